@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessApprovalSpec defines the desired state of AccessApproval.
+//
+// The approval only applies to the approved generation of the AccessRequest, and while applicationRef and the target of
+// the AccessRequest match the approval, so that changing an approved AccessRequest, e.g. removing its expiry, does not
+// grant access that was not approved.
+type AccessApprovalSpec struct {
+	// AccessRequest refers to the approved AccessRequest.
+	//
+	// +kubebuilder:validation:Required
+	AccessRequest AccessRequestReference `json:"accessRequest"`
+
+	// Application is the name of the calling application that is approved. Must match `applicationRef` of the
+	// AccessRequest.
+	//
+	// +kubebuilder:validation:Required
+	Application string `json:"application"`
+
+	// SecurityConfigRef is the name of the SecurityConfig in the namespace of the AccessApproval that access is
+	// approved to. Must match the target of the AccessRequest.
+	//
+	// +kubebuilder:validation:Required
+	SecurityConfigRef string `json:"securityConfigRef"`
+
+	// ExpiresAt is the point in time after which the approval no longer grants access.
+	// If both the AccessRequest and the AccessApproval set an expiry, the earliest one applies.
+	//
+	// +kubebuilder:validation:Optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// AccessRequestReference refers to an AccessRequest by name and namespace.
+type AccessRequestReference struct {
+	// Name is the name of the AccessRequest.
+	//
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the AccessRequest.
+	//
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+
+	// Generation is the metadata.generation of the AccessRequest that is approved. Any later change to the spec of the
+	// AccessRequest requires a new approval.
+	//
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	Generation int64 `json:"generation"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Request Namespace",type=string,JSONPath=`.spec.accessRequest.namespace`
+// +kubebuilder:printcolumn:name="Request",type=string,JSONPath=`.spec.accessRequest.name`
+// +kubebuilder:printcolumn:name="Application",type=string,JSONPath=`.spec.application`
+
+// AccessApproval is the Schema for the accessapprovals API. It is created in the namespace of the target
+// SecurityConfig by the team owning the target application to approve an AccessRequest.
+type AccessApproval struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of AccessApproval
+	// +required
+	Spec AccessApprovalSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// AccessApprovalList contains a list of AccessApproval
+type AccessApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []AccessApproval `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AccessApproval{}, &AccessApprovalList{})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessRequestSpec defines the desired state of AccessRequest.
+type AccessRequestSpec struct {
+	// ApplicationRef is the name of the Skiperator application in the namespace of the AccessRequest that requests
+	// to exchange tokens where the target application is the intended audience.
+	//
+	// +kubebuilder:validation:Required
+	ApplicationRef string `json:"applicationRef"`
+
+	// Target refers to the SecurityConfig of the application that access is requested to.
+	//
+	// +kubebuilder:validation:Required
+	Target SecurityConfigReference `json:"target"`
+
+	// Reason describes why access is needed.
+	//
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+
+	// ExpiresAt is the point in time after which access is no longer needed.
+	// If unset, access does not expire unless the AccessApproval sets an expiry.
+	//
+	// +kubebuilder:validation:Optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// SecurityConfigReference refers to a SecurityConfig by name and namespace.
+type SecurityConfigReference struct {
+	// Name is the name of the SecurityConfig.
+	//
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the SecurityConfig.
+	//
+	// +kubebuilder:validation:Required
+	Namespace string `json:"namespace"`
+}
+
+// AccessRequestStatus defines the observed state of AccessRequest.
+type AccessRequestStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	Phase              AccessRequestPhase `json:"phase,omitempty"`
+	Message            string             `json:"message,omitempty"`
+
+	// ApprovedBy is the namespace and name of the AccessApproval that approved the request.
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// ExpiresAt is the point in time when the approved access expires.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+type AccessRequestPhase string
+
+// ConditionTypeApproved is the condition type reporting whether an AccessRequest is approved.
+const ConditionTypeApproved = "Approved"
+
+const (
+	AccessRequestPhasePending  AccessRequestPhase = "Pending"
+	AccessRequestPhaseApproved AccessRequestPhase = "Approved"
+	AccessRequestPhaseExpired  AccessRequestPhase = "Expired"
+	AccessRequestPhaseInvalid  AccessRequestPhase = "Invalid"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Application",type=string,JSONPath=`.spec.applicationRef`
+// +kubebuilder:printcolumn:name="Target Namespace",type=string,JSONPath=`.spec.target.namespace`
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target.name`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+
+// AccessRequest is the Schema for the accessrequests API. It is created by the team owning the calling application
+// to request TokenX access to an application owned by another team.
+type AccessRequest struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// spec defines the desired state of AccessRequest
+	// +required
+	Spec AccessRequestSpec `json:"spec"`
+
+	// status defines the observed state of AccessRequest
+	// +optional
+	Status AccessRequestStatus `json:"status,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// AccessRequestList contains a list of AccessRequest
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+	Items           []AccessRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AccessRequest{}, &AccessRequestList{})
+}
+
+func (s *AccessRequestStatus) SetPhase(phase AccessRequestPhase, msg string) {
+	s.Phase = phase
+	s.Message = msg
+}
+
+func SetConditionApproved(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionTrue
+	cond.Reason = "Approved"
+	cond.Message = msg
+}
+
+func SetConditionApprovalPending(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = "ApprovalPending"
+	cond.Message = msg
+}
+
+func SetConditionAccessExpired(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = "Expired"
+	cond.Message = msg
+}
+
+func SetConditionInvalidRequest(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = "InvalidRequest"
+	cond.Message = msg
+}
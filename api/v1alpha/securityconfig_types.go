@@ -45,6 +45,48 @@ type TokenXSpec struct {
 	//
 	// +kubebuilder:validation:Required
 	Enabled bool `json:"enabled"`
+
+	// AccessPolicy contains TokenX access policy rules that are added to the accessPolicy of the Application referred
+	// to by `applicationRef`. Rules can be time-bound with `expiresAt`, and are removed from the Jwker once they expire.
+	//
+	// +kubebuilder:validation:Optional
+	AccessPolicy *AccessPolicy `json:"accessPolicy,omitempty"`
+}
+
+// AccessPolicy defines additional TokenX access policy rules for the application.
+//
+// +kubebuilder:object:generate=true
+type AccessPolicy struct {
+	// Inbound lists the applications that may exchange tokens where this application is the intended audience.
+	//
+	// +kubebuilder:validation:Optional
+	Inbound []AccessPolicyRule `json:"inbound,omitempty"`
+
+	// Outbound lists the applications that this application may exchange tokens for.
+	//
+	// +kubebuilder:validation:Optional
+	Outbound []AccessPolicyRule `json:"outbound,omitempty"`
+}
+
+// AccessPolicyRule refers to an application that is allowed to exchange tokens to or from this application.
+//
+// +kubebuilder:object:generate=true
+type AccessPolicyRule struct {
+	// Application is the name of the application.
+	//
+	// +kubebuilder:validation:Required
+	Application string `json:"application"`
+
+	// Namespace is the namespace of the application. If unset, the namespace of the SecurityConfig is used.
+	//
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// ExpiresAt is the point in time after which the rule no longer grants access.
+	// If unset, the rule does not expire.
+	//
+	// +kubebuilder:validation:Optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
 }
 
 // SecurityConfigStatus defines the observed state of SecurityConfig.
@@ -54,15 +96,70 @@ type SecurityConfigStatus struct {
 	Phase              Phase              `json:"phase,omitempty"`
 	Message            string             `json:"message,omitempty"`
 	Ready              bool               `json:"ready"`
+
+	// AccessGrants lists the time-bound access policy rules that currently grant access.
+	AccessGrants []AccessGrantStatus `json:"accessGrants,omitempty"`
+
+	// PhaseHistory lists the most recent phase transitions, newest first.
+	//
+	// +kubebuilder:validation:MaxItems=10
+	PhaseHistory []PhaseTransition `json:"phaseHistory,omitempty"`
+
+	// GeneratedResources lists the kinds and names of the resources generated from the SecurityConfig. Names longer
+	// than the Kubernetes limits are truncated and suffixed with a hash, so they cannot be derived from the spec.
+	GeneratedResources []GeneratedResource `json:"generatedResources,omitempty"`
+}
+
+// GeneratedResource is a resource generated from the SecurityConfig.
+type GeneratedResource struct {
+	// Kind is the kind of the resource, e.g. Jwker.
+	Kind string `json:"kind"`
+	// Name is the name of the resource.
+	Name string `json:"name"`
+}
+
+// PhaseTransition describes a transition of the SecurityConfig to a phase.
+type PhaseTransition struct {
+	// Phase is the phase the SecurityConfig transitioned to.
+	Phase Phase `json:"phase"`
+	// Reason is the reason of the transition, the same as the reason of the SecurityConfig condition.
+	Reason string `json:"reason"`
+	// Message is a human readable message describing the transition.
+	Message string `json:"message,omitempty"`
+	// TransitionTime is the point in time when the transition happened.
+	TransitionTime metav1.Time `json:"transitionTime"`
+}
+
+// AccessGrantStatus describes an active time-bound access policy rule.
+type AccessGrantStatus struct {
+	// Direction is either inbound or outbound.
+	Direction string `json:"direction"`
+	// Application is the name of the application access is granted to or from.
+	Application string `json:"application"`
+	// Namespace is the namespace of the application.
+	Namespace string `json:"namespace"`
+	// ExpiresAt is the point in time when the rule expires.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+	// ExpiringSoon is true when the rule expires within the configured expiry warning period.
+	ExpiringSoon bool `json:"expiringSoon,omitempty"`
 }
 
 type Phase string
 
+// ConditionTypeAccessPolicyConsistent is the condition type reporting whether every TokenX access policy rule of the
+// application has a matching rule on the other side.
+const ConditionTypeAccessPolicyConsistent = "AccessPolicyConsistent"
+
+// PhaseHistoryLimit is the number of phase transitions kept in the status.
+const PhaseHistoryLimit = 10
+
 const (
 	PhasePending Phase = "Pending"
 	PhaseReady   Phase = "Ready"
 	PhaseFailed  Phase = "Failed"
 	PhaseInvalid Phase = "Invalid"
+	// PhaseOrphaned is the phase of a SecurityConfig whose Application does not exist.
+	PhaseOrphaned Phase = "Orphaned"
 )
 
 // +kubebuilder:object:root=true
@@ -108,6 +205,39 @@ func (s *SecurityConfig) InitializeStatus() {
 	s.Status.Phase = PhasePending
 }
 
+// RecordPhaseTransition adds the current phase to the phase history if it differs from the previous phase, keeping
+// the most recent PhaseHistoryLimit transitions.
+func (s *SecurityConfigStatus) RecordPhaseTransition(previousPhase Phase, reason string, now metav1.Time) {
+	if s.Phase == previousPhase {
+		return
+	}
+	transition := PhaseTransition{Phase: s.Phase, Reason: reason, Message: s.Message, TransitionTime: now}
+	s.PhaseHistory = append([]PhaseTransition{transition}, s.PhaseHistory...)
+	if len(s.PhaseHistory) > PhaseHistoryLimit {
+		s.PhaseHistory = s.PhaseHistory[:PhaseHistoryLimit]
+	}
+}
+
+// HasBeenReady reports whether the phase history includes a transition to the Ready phase. Transitions older than the
+// most recent PhaseHistoryLimit are not included.
+func (s *SecurityConfigStatus) HasBeenReady() bool {
+	for _, transition := range s.PhaseHistory {
+		if transition.Phase == PhaseReady {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPhaseTransitionTime returns when the SecurityConfig transitioned to its current phase, or nil if the transition
+// is not in the phase history.
+func (s *SecurityConfigStatus) GetPhaseTransitionTime() *metav1.Time {
+	if len(s.PhaseHistory) == 0 || s.PhaseHistory[0].Phase != s.Phase {
+		return nil
+	}
+	return &s.PhaseHistory[0].TransitionTime
+}
+
 func (s *SecurityConfigStatus) SetPhaseInvalid(msg string) {
 	s.Phase = PhaseInvalid
 	s.Ready = false
@@ -120,6 +250,18 @@ func SetConditionInvalid(cond *metav1.Condition, msg string) {
 	cond.Message = msg
 }
 
+func (s *SecurityConfigStatus) SetPhaseOrphaned(msg string) {
+	s.Phase = PhaseOrphaned
+	s.Ready = false
+	s.Message = msg
+}
+
+func SetConditionOrphaned(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = "ApplicationNotFound"
+	cond.Message = msg
+}
+
 func (s *SecurityConfigStatus) SetPhasePending(msg string) {
 	s.Phase = PhasePending
 	s.Ready = false
@@ -155,3 +297,15 @@ func SetConditionReady(cond *metav1.Condition, msg string) {
 	cond.Reason = "ReconciliationSuccess"
 	cond.Message = msg
 }
+
+func SetConditionAccessPolicyConsistent(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionTrue
+	cond.Reason = "CounterpartRulesFound"
+	cond.Message = msg
+}
+
+func SetConditionAccessPolicyInconsistent(cond *metav1.Condition, msg string) {
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = "OneSidedRules"
+	cond.Message = msg
+}
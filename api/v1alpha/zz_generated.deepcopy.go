@@ -25,6 +25,299 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessApproval) DeepCopyInto(out *AccessApproval) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessApproval.
+func (in *AccessApproval) DeepCopy() *AccessApproval {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessApproval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessApproval) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessApprovalList) DeepCopyInto(out *AccessApprovalList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccessApproval, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessApprovalList.
+func (in *AccessApprovalList) DeepCopy() *AccessApprovalList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessApprovalList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessApprovalList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessApprovalSpec) DeepCopyInto(out *AccessApprovalSpec) {
+	*out = *in
+	out.AccessRequest = in.AccessRequest
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessApprovalSpec.
+func (in *AccessApprovalSpec) DeepCopy() *AccessApprovalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessApprovalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessGrantStatus) DeepCopyInto(out *AccessGrantStatus) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessGrantStatus.
+func (in *AccessGrantStatus) DeepCopy() *AccessGrantStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessGrantStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessPolicy) DeepCopyInto(out *AccessPolicy) {
+	*out = *in
+	if in.Inbound != nil {
+		in, out := &in.Inbound, &out.Inbound
+		*out = make([]AccessPolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Outbound != nil {
+		in, out := &in.Outbound, &out.Outbound
+		*out = make([]AccessPolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessPolicy.
+func (in *AccessPolicy) DeepCopy() *AccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessPolicyRule) DeepCopyInto(out *AccessPolicyRule) {
+	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessPolicyRule.
+func (in *AccessPolicyRule) DeepCopy() *AccessPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequest) DeepCopyInto(out *AccessRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequest.
+func (in *AccessRequest) DeepCopy() *AccessRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestList) DeepCopyInto(out *AccessRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccessRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestList.
+func (in *AccessRequestList) DeepCopy() *AccessRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestReference) DeepCopyInto(out *AccessRequestReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestReference.
+func (in *AccessRequestReference) DeepCopy() *AccessRequestReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestSpec) DeepCopyInto(out *AccessRequestSpec) {
+	*out = *in
+	out.Target = in.Target
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestSpec.
+func (in *AccessRequestSpec) DeepCopy() *AccessRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestStatus) DeepCopyInto(out *AccessRequestStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestStatus.
+func (in *AccessRequestStatus) DeepCopy() *AccessRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GeneratedResource) DeepCopyInto(out *GeneratedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeneratedResource.
+func (in *GeneratedResource) DeepCopy() *GeneratedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(GeneratedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PhaseTransition) DeepCopyInto(out *PhaseTransition) {
+	*out = *in
+	in.TransitionTime.DeepCopyInto(&out.TransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseTransition.
+func (in *PhaseTransition) DeepCopy() *PhaseTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PhaseTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityConfig) DeepCopyInto(out *SecurityConfig) {
 	*out = *in
@@ -84,13 +377,28 @@ func (in *SecurityConfigList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityConfigReference) DeepCopyInto(out *SecurityConfigReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityConfigReference.
+func (in *SecurityConfigReference) DeepCopy() *SecurityConfigReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityConfigReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityConfigSpec) DeepCopyInto(out *SecurityConfigSpec) {
 	*out = *in
 	if in.Tokenx != nil {
 		in, out := &in.Tokenx, &out.Tokenx
 		*out = new(TokenXSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -114,6 +422,25 @@ func (in *SecurityConfigStatus) DeepCopyInto(out *SecurityConfigStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AccessGrants != nil {
+		in, out := &in.AccessGrants, &out.AccessGrants
+		*out = make([]AccessGrantStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PhaseHistory != nil {
+		in, out := &in.PhaseHistory, &out.PhaseHistory
+		*out = make([]PhaseTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GeneratedResources != nil {
+		in, out := &in.GeneratedResources, &out.GeneratedResources
+		*out = make([]GeneratedResource, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityConfigStatus.
@@ -129,6 +456,11 @@ func (in *SecurityConfigStatus) DeepCopy() *SecurityConfigStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenXSpec) DeepCopyInto(out *TokenXSpec) {
 	*out = *in
+	if in.AccessPolicy != nil {
+		in, out := &in.AccessPolicy, &out.AccessPolicy
+		*out = new(AccessPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenXSpec.
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/kartverket/accesserator/internal/cli"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// kubectl-accesserator is a kubectl plugin running the accesserator subcommands, e.g. kubectl accesserator status.
+func main() {
+	os.Exit(cli.RunPlugin(ctrl.SetupSignalHandler(), os.Args[1:], os.Stdout, os.Stderr))
+}
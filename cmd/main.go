@@ -17,12 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/kartverket/accesserator/internal/cli"
+	"github.com/kartverket/accesserator/pkg/accessgraph"
+	"github.com/kartverket/accesserator/pkg/audit"
 	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/fieldindex"
+	accesseratormetrics "github.com/kartverket/accesserator/pkg/metrics"
+	"github.com/kartverket/accesserator/pkg/tracing"
 	"github.com/kartverket/skiperator/api/v1alpha1"
 	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 
@@ -36,6 +44,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -51,6 +60,9 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// auditCloseTimeout is how long the audit entries that are still buffered may take to be written on shutdown.
+const auditCloseTimeout = 10 * time.Second
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -62,7 +74,12 @@ func init() {
 
 // nolint:gocyclo
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(ctrl.SetupSignalHandler(), os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
+	var enableAccessGraph bool
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
@@ -71,9 +88,12 @@ func main() {
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	var isDeployment bool
+	var maxConcurrentReconciles int
 	flag.BoolVar(&isDeployment, "deployment", false, "Whether the application is running in deployment mode.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
+	flag.BoolVar(&enableAccessGraph, "enable-access-graph", false,
+		"If set, the access graph is served on "+accessgraph.Path+" by the metrics server, with the same authn/authz as the metrics.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
@@ -87,6 +107,8 @@ func main() {
 		"The directory that contains the metrics server certificate.")
 	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of SecurityConfigs that are reconciled concurrently.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	opts := zap.Options{
@@ -178,8 +200,10 @@ func main() {
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "ea93bf51.kartverket.no",
+		// Spans are started around every API call, and are no-ops unless tracing is enabled.
+		NewClient:        tracing.NewClient,
+		LeaderElection:   enableLeaderElection,
+		LeaderElectionID: "ea93bf51.kartverket.no",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -201,36 +225,110 @@ func main() {
 		setupLog.Error(configLoadErr, "unable to load config")
 		os.Exit(1)
 	}
+	if err := audit.Setup(); err != nil {
+		setupLog.Error(err, "unable to set up audit log")
+		os.Exit(1)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	eventBroadcaster, err := controller.NewEventBroadcaster(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to set up event broadcaster")
+		os.Exit(1)
+	}
+	if err := eventBroadcaster.StartRecordingToSinkWithContext(ctx); err != nil {
+		setupLog.Error(err, "unable to start event broadcaster")
+		os.Exit(1)
+	}
+	// Deferred calls do not run on os.Exit, so the event broadcaster and the audit log are shut down explicitly to
+	// flush the recorded events and audit entries both when the manager stops and when the setup below fails.
+	shutdown := func() {
+		eventBroadcaster.Shutdown()
+		auditCtx, cancelAudit := context.WithTimeout(context.Background(), auditCloseTimeout)
+		defer cancelAudit()
+		if err := audit.Close(auditCtx); err != nil {
+			setupLog.Error(err, "unable to close audit log")
+		}
+	}
+	exitAfterShutdown := func() {
+		shutdown()
+		os.Exit(1)
+	}
+
+	if err := fieldindex.Setup(ctx, mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		exitAfterShutdown()
+	}
 
 	if err := (&controller.SecurityConfigReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                eventBroadcaster.NewRecorder(mgr.GetScheme(), controller.ReportingControllerPrefix+"securityconfig-controller"),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SecurityConfig")
+		exitAfterShutdown()
+	}
+	if err := (&controller.AccessRequestReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("securityconfig-controller"),
+		Recorder: eventBroadcaster.NewRecorder(mgr.GetScheme(), controller.ReportingControllerPrefix+"accessrequest-controller"),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "SecurityConfig")
-		os.Exit(1)
+		setupLog.Error(err, "unable to create controller", "controller", "AccessRequest")
+		exitAfterShutdown()
+	}
+	if err := (&controller.TokenxIngressReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: eventBroadcaster.NewRecorder(mgr.GetScheme(), controller.ReportingControllerPrefix+"tokenxingress-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TokenxIngress")
+		exitAfterShutdown()
 	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err := webhookv1.SetupPodWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
-			os.Exit(1)
+			exitAfterShutdown()
+		}
+	}
+	if enableAccessGraph {
+		if metricsAddr == "0" {
+			setupLog.Error(nil, "the access graph is served by the metrics server, which is disabled")
+			exitAfterShutdown()
 		}
+		if err := mgr.AddMetricsServerExtraHandler(accessgraph.Path, accessgraph.Handler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to set up access graph endpoint")
+			exitAfterShutdown()
+		}
+	}
+	if err := ctrlmetrics.Registry.Register(&accesseratormetrics.Collector{Client: mgr.GetClient()}); err != nil {
+		setupLog.Error(err, "unable to register metrics collector")
+		exitAfterShutdown()
 	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+		exitAfterShutdown()
 	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+		exitAfterShutdown()
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		exitAfterShutdown()
+	}
+	shutdown()
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to flush traces")
 	}
 }
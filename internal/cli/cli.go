@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// command is a subcommand of the accesserator binary.
+type command struct {
+	description string
+	run         func(ctx context.Context, args []string, stdout io.Writer) error
+}
+
+var (
+	scheme   = runtime.NewScheme()
+	commands = map[string]command{
+		"graph": {
+			description: "Export the token exchange access graph as DOT, Mermaid or JSON.",
+			run:         runGraph,
+		},
+		"doctor": {
+			description: "Check every precondition for Texas to be injected into an application and suggest fixes.",
+			run:         runDoctor,
+		},
+		"explain": {
+			description: "Explain whether a TokenX token exchange between two applications would succeed.",
+			run:         runExplain,
+		},
+		"lint": {
+			description: "Check a directory of SecurityConfig and Application manifests for misconfigurations.",
+			run:         runLint,
+		},
+		"migrate": {
+			description: "Convert NAIS Application manifests to a Skiperator Application and a SecurityConfig.",
+			run:         runMigrate,
+		},
+		"render": {
+			description: "Render the Jwker, NetworkPolicy and pod mutation produced for SecurityConfig manifests, without a cluster.",
+			run:         runRender,
+		},
+		"status": {
+			description: "Show a SecurityConfig and the resources it depends on as a tree with their health.",
+			run:         runStatus,
+		},
+	}
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(accesseratorv1alpha.AddToScheme(scheme))
+	utilruntime.Must(naisiov1.AddToScheme(scheme))
+}
+
+// IsCommand reports whether name is a subcommand handled by Run.
+func IsCommand(name string) bool {
+	_, exists := commands[name]
+	return exists || name == "help"
+}
+
+// Run executes the subcommand given by args[0] and returns the exit code of the process.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	return run(ctx, "accesserator", args, stdout, stderr)
+}
+
+// RunPlugin executes the subcommand given by args[0] as the kubectl-accesserator kubectl plugin, and returns the exit
+// code of the process.
+func RunPlugin(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	return run(ctx, "kubectl accesserator", args, stdout, stderr)
+}
+
+func run(ctx context.Context, program string, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(stderr, program)
+		return 0
+	}
+	cmd, exists := commands[args[0]]
+	if !exists {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		printUsage(stderr, program)
+		return 2
+	}
+	if err := cmd.run(ctx, args[1:], stdout); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer, program string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintf(w, "Usage: %s <command> [flags]\n", program)
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].description)
+	}
+	if program == "accesserator" {
+		_, _ = fmt.Fprintln(w, "\nRun without a command to start the controller manager.")
+	}
+}
+
+// kubeFlags holds the flags used to connect to a cluster.
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+}
+
+func (k *kubeFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&k.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&k.context, "context", "", "The kubeconfig context to use.")
+}
+
+func (k *kubeFlags) newClient() (client.Client, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if k.kubeconfig != "" {
+		loadingRules.ExplicitPath = k.kubeconfig
+	}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: k.context},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
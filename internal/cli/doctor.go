@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kartverket/accesserator/pkg/doctor"
+)
+
+func runDoctor(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator doctor [flags] <[namespace/]app>")
+		fs.PrintDefaults()
+	}
+	var kube kubeFlags
+	kube.bind(fs)
+	namespace := fs.String("namespace", "default", "Namespace of an application given without a namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one application, got %d", fs.NArg())
+	}
+
+	k8sClient, err := kube.newClient()
+	if err != nil {
+		return err
+	}
+	report, err := doctor.Diagnose(ctx, k8sClient, parseWorkload(fs.Arg(0), *namespace))
+	if err != nil {
+		return err
+	}
+	if err := report.Print(stdout); err != nil {
+		return err
+	}
+	if !report.Healthy {
+		return fmt.Errorf("found problems with application %s", report.Workload)
+	}
+	return nil
+}
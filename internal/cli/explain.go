@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/simulator"
+)
+
+func runExplain(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator explain [flags] <[namespace/]from-app> <[namespace/]to-app>")
+		fs.PrintDefaults()
+	}
+	var kube kubeFlags
+	kube.bind(fs)
+	namespace := fs.String("namespace", "default", "Namespace of applications given without a namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly two applications, got %d", fs.NArg())
+	}
+
+	k8sClient, err := kube.newClient()
+	if err != nil {
+		return err
+	}
+	result, err := simulator.Simulate(
+		ctx,
+		k8sClient,
+		parseWorkload(fs.Arg(0), *namespace),
+		parseWorkload(fs.Arg(1), *namespace),
+	)
+	if err != nil {
+		return err
+	}
+	if err := result.Print(stdout); err != nil {
+		return err
+	}
+	if !result.Allowed {
+		return fmt.Errorf("token exchange from %s to %s would fail", result.From, result.To)
+	}
+	return nil
+}
+
+// parseWorkload parses an application given as either name or namespace/name.
+func parseWorkload(s, defaultNamespace string) accesspolicy.Workload {
+	if namespace, name, found := strings.Cut(s, "/"); found {
+		return accesspolicy.Workload{Name: name, Namespace: namespace}
+	}
+	return accesspolicy.Workload{Name: s, Namespace: defaultNamespace}
+}
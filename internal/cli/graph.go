@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"io"
+
+	"github.com/kartverket/accesserator/pkg/accessgraph"
+)
+
+func runGraph(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	var kube kubeFlags
+	kube.bind(fs)
+	format := fs.String("format", string(accessgraph.FormatDOT), "Output format: dot, mermaid or json.")
+	namespace := fs.String("namespace", "", "Only include edges to or from applications in this namespace.")
+	app := fs.String("app", "", "Only include edges to or from applications with this name.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	outputFormat, err := accessgraph.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	k8sClient, err := kube.newClient()
+	if err != nil {
+		return err
+	}
+	graph, err := accessgraph.Collect(ctx, k8sClient)
+	if err != nil {
+		return err
+	}
+	return graph.Filter(*namespace, *app).Write(stdout, outputFormat)
+}
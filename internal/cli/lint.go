@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kartverket/accesserator/pkg/lint"
+)
+
+func runLint(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator lint [flags] <directory>")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", string(lint.FormatText), "Output format: text, json or sarif.")
+	namespace := fs.String("namespace", "default", "Namespace of objects given without a namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one directory, got %d", fs.NArg())
+	}
+
+	outputFormat, err := lint.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	objects, err := lint.Load(fs.Arg(0), *namespace)
+	if err != nil {
+		return err
+	}
+	findings := lint.Lint(objects)
+	if err := lint.Write(stdout, findings, outputFormat); err != nil {
+		return err
+	}
+	if lint.HasErrors(findings) {
+		return fmt.Errorf("found errors in the manifests in %s", fs.Arg(0))
+	}
+	return nil
+}
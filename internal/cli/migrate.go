@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kartverket/accesserator/pkg/migrate"
+	naisiov1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+)
+
+func runMigrate(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator migrate [flags] -f <nais-manifest> [-f <nais-manifest>...]")
+		fs.PrintDefaults()
+	}
+	var files fileFlags
+	fs.Var(&files, "f", "A YAML or JSON manifest with nais.io/v1alpha1 Applications. Can be repeated, - reads stdin.")
+	namespace := fs.String("namespace", "default", "Namespace of applications given without a namespace.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 || fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one manifest given with -f")
+	}
+
+	var applications []*naisiov1alpha1.Application
+	for _, file := range files {
+		fileApplications, err := decodeNaisFile(file, *namespace)
+		if err != nil {
+			return err
+		}
+		applications = append(applications, fileApplications...)
+	}
+	if len(applications) == 0 {
+		return fmt.Errorf("the manifests contain no nais.io/v1alpha1 Application")
+	}
+	for _, application := range applications {
+		if err := migrate.Convert(application).Print(stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeNaisFile(file, namespace string) ([]*naisiov1alpha1.Application, error) {
+	if file == "-" {
+		return migrate.Decode(os.Stdin, namespace)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	applications, err := migrate.Decode(f, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return applications, nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/render"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fileFlags is a flag that can be given several times.
+type fileFlags []string
+
+func (f *fileFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func runRender(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator render [flags] -f <manifest> [-f <manifest>...]")
+		_, _ = fmt.Fprintln(fs.Output(), "\nThe operator config is read from the ACCESSERATOR_* environment variables like for the manager.")
+		fs.PrintDefaults()
+	}
+	var files fileFlags
+	fs.Var(&files, "f", "A YAML or JSON manifest with Applications, SecurityConfigs, AccessRequests or pods. Can be repeated, - reads stdin.")
+	namespace := fs.String("namespace", "default", "Namespace of objects given without a namespace.")
+	clusterName := fs.String("cluster-name", "", "Overrides ACCESSERATOR_CLUSTER_NAME.")
+	tokenxNamespace := fs.String("tokenx-namespace", "", "Overrides ACCESSERATOR_TOKENX_NAMESPACE.")
+	texasImageTag := fs.String("texas-image-tag", "", "Overrides ACCESSERATOR_TEXAS_IMAGE_TAG.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(files) == 0 || fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one manifest given with -f")
+	}
+
+	for name, value := range map[string]string{
+		"ACCESSERATOR_CLUSTER_NAME":     *clusterName,
+		"ACCESSERATOR_TOKENX_NAMESPACE": *tokenxNamespace,
+		"ACCESSERATOR_TEXAS_IMAGE_TAG":  *texasImageTag,
+	} {
+		if value != "" {
+			if err := os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	if err := config.Load(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var objects []client.Object
+	for _, file := range files {
+		fileObjects, err := decodeFile(file, *namespace)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+	}
+
+	results, err := render.Render(ctx, scheme, objects)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := result.Print(stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeFile(file, namespace string) ([]client.Object, error) {
+	if file == "-" {
+		return render.Decode(os.Stdin, scheme, namespace)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	objects, err := render.Decode(f, scheme, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return objects, nil
+}
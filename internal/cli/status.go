@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kartverket/accesserator/pkg/status"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func runStatus(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(fs.Output(), "Usage: accesserator status [flags] <[namespace/]securityconfig>")
+		fs.PrintDefaults()
+	}
+	var kube kubeFlags
+	kube.bind(fs)
+	namespace := fs.String("namespace", "default", "Namespace of a SecurityConfig given without a namespace.")
+	noColor := fs.Bool("no-color", os.Getenv("NO_COLOR") != "", "Disable colored output. Defaults to true if $NO_COLOR is set.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one SecurityConfig, got %d", fs.NArg())
+	}
+
+	k8sClient, err := kube.newClient()
+	if err != nil {
+		return err
+	}
+	workload := parseWorkload(fs.Arg(0), *namespace)
+	tree, err := status.Collect(ctx, k8sClient, types.NamespacedName{Name: workload.Name, Namespace: workload.Namespace})
+	if err != nil {
+		return err
+	}
+	if err := tree.Print(stdout, !*noColor); err != nil {
+		return err
+	}
+	if health := tree.OverallHealth(); health != status.HealthHealthy {
+		return fmt.Errorf("SecurityConfig %s is %s", tree.Name, health)
+	}
+	return nil
+}
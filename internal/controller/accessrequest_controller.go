@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/eventhandler"
+	"github.com/kartverket/accesserator/pkg/accessrequest"
+	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AccessRequestReconciler reconciles the status of AccessRequest objects. The inbound rules of approved
+// AccessRequests are added to the Jwker of the target by the SecurityConfigReconciler.
+type AccessRequestReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AccessRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(
+			&accesseratorv1alpha.AccessRequest{},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&accesseratorv1alpha.AccessApproval{}, eventhandler.HandleAccessApprovalEventForAccessRequest()).
+		Watches(
+			&accesseratorv1alpha.SecurityConfig{},
+			eventhandler.HandleSecurityConfigEventForAccessRequest(r.Client),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&v1alpha1.Application{}, eventhandler.HandleSkiperatorApplicationEventForAccessRequest(r.Client)).
+		Named("accessrequest").
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=accessrequests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=accessrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=accessapprovals,verbs=get;list;watch
+
+func (r *AccessRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rlog := log.GetLogger(ctx)
+	accessRequest := new(accesseratorv1alpha.AccessRequest)
+	rlog.Info("Reconciling AccessRequest", "name", req.NamespacedName)
+
+	if err := r.Get(ctx, req.NamespacedName, accessRequest); err != nil {
+		if apierrors.IsNotFound(err) {
+			rlog.Debug("AccessRequest not found. Probably a delete.", "name", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		rlog.Error(err, "failed to get AccessRequest", "name", req.NamespacedName)
+		return reconcile.Result{}, err
+	}
+	if !accessRequest.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	original := accessRequest.DeepCopy()
+
+	invalidMessage, err := r.validate(ctx, accessRequest)
+	if err != nil {
+		rlog.Error(err, "failed to validate AccessRequest", "name", req.NamespacedName)
+		return reconcile.Result{}, err
+	}
+
+	var approvalList accesseratorv1alpha.AccessApprovalList
+	if err := r.List(ctx, &approvalList, client.InNamespace(accessRequest.Spec.Target.Namespace)); err != nil {
+		rlog.Error(err, "failed to list AccessApproval resources", "namespace", accessRequest.Spec.Target.Namespace)
+		return reconcile.Result{}, err
+	}
+
+	result := r.setStatus(accessRequest, invalidMessage, accessrequest.GetApproval(*accessRequest, approvalList.Items), time.Now())
+
+	if !equality.Semantic.DeepEqual(original.Status, accessRequest.Status) {
+		if err := r.updateStatusWithRetriesOnConflict(ctx, *accessRequest); err != nil {
+			rlog.Error(err, "failed to update AccessRequest status", "name", req.NamespacedName)
+			return reconcile.Result{}, err
+		}
+	}
+	// The event is only recorded once the phase is persisted, so a failed update does not report a phase the
+	// AccessRequest never had.
+	r.recordPhaseEvent(accessRequest, original.Status.Phase)
+	return result, nil
+}
+
+// validate returns a message describing why the AccessRequest can never be approved, or an empty string if it is
+// valid.
+func (r *AccessRequestReconciler) validate(ctx context.Context, accessRequest *accesseratorv1alpha.AccessRequest) (string, error) {
+	var application v1alpha1.Application
+	applicationKey := types.NamespacedName{Name: accessRequest.Spec.ApplicationRef, Namespace: accessRequest.Namespace}
+	if err := r.Get(ctx, applicationKey, &application); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("Application %s does not exist.", applicationKey), nil
+		}
+		return "", err
+	}
+
+	var securityConfig accesseratorv1alpha.SecurityConfig
+	securityConfigKey := types.NamespacedName{Name: accessRequest.Spec.Target.Name, Namespace: accessRequest.Spec.Target.Namespace}
+	if err := r.Get(ctx, securityConfigKey, &securityConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("Target SecurityConfig %s does not exist.", securityConfigKey), nil
+		}
+		return "", err
+	}
+	if securityConfig.Spec.Tokenx == nil || !securityConfig.Spec.Tokenx.Enabled {
+		return fmt.Sprintf("Target SecurityConfig %s does not enable TokenX.", securityConfigKey), nil
+	}
+	return "", nil
+}
+
+// setStatus sets the phase and conditions of the AccessRequest, and returns when it must be reconciled again for
+// the approved access to expire.
+func (r *AccessRequestReconciler) setStatus(
+	accessRequest *accesseratorv1alpha.AccessRequest,
+	invalidMessage string,
+	approval *accesseratorv1alpha.AccessApproval,
+	now time.Time,
+) ctrl.Result {
+	status := &accessRequest.Status
+	status.ObservedGeneration = accessRequest.GetGeneration()
+	status.ApprovedBy = ""
+	status.ExpiresAt = accessRequest.Spec.ExpiresAt
+	condition := metav1.Condition{
+		Type:               accesseratorv1alpha.ConditionTypeApproved,
+		ObservedGeneration: accessRequest.GetGeneration(),
+	}
+	if approval != nil {
+		status.ApprovedBy = accessrequest.GetApprovalName(*approval)
+		status.ExpiresAt = accessrequest.GetExpiresAt(*accessRequest, *approval)
+	}
+
+	result := ctrl.Result{}
+	switch {
+	case invalidMessage != "":
+		status.SetPhase(accesseratorv1alpha.AccessRequestPhaseInvalid, invalidMessage)
+		accesseratorv1alpha.SetConditionInvalidRequest(&condition, invalidMessage)
+
+	case accessrequest.IsExpired(status.ExpiresAt, now):
+		msg := fmt.Sprintf("Access expired at %s.", status.ExpiresAt.Format(time.RFC3339))
+		status.SetPhase(accesseratorv1alpha.AccessRequestPhaseExpired, msg)
+		accesseratorv1alpha.SetConditionAccessExpired(&condition, msg)
+
+	case approval == nil:
+		msg := fmt.Sprintf(
+			"Waiting for an AccessApproval in namespace %s approving generation %d of the request for access to SecurityConfig %s.",
+			accessRequest.Spec.Target.Namespace,
+			accessRequest.GetGeneration(),
+			accessRequest.Spec.Target.Name,
+		)
+		status.SetPhase(accesseratorv1alpha.AccessRequestPhasePending, msg)
+		accesseratorv1alpha.SetConditionApprovalPending(&condition, msg)
+		if status.ExpiresAt != nil {
+			result.RequeueAfter = status.ExpiresAt.Sub(now)
+		}
+
+	default:
+		msg := fmt.Sprintf("Access approved by AccessApproval %s.", status.ApprovedBy)
+		status.SetPhase(accesseratorv1alpha.AccessRequestPhaseApproved, msg)
+		accesseratorv1alpha.SetConditionApproved(&condition, msg)
+		if status.ExpiresAt != nil {
+			result.RequeueAfter = status.ExpiresAt.Sub(now)
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, condition)
+	return result
+}
+
+// recordPhaseEvent emits an event when the AccessRequest changes phase.
+func (r *AccessRequestReconciler) recordPhaseEvent(
+	accessRequest *accesseratorv1alpha.AccessRequest,
+	previousPhase accesseratorv1alpha.AccessRequestPhase,
+) {
+	if accessRequest.Status.Phase == previousPhase {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if accessRequest.Status.Phase == accesseratorv1alpha.AccessRequestPhaseInvalid ||
+		accessRequest.Status.Phase == accesseratorv1alpha.AccessRequestPhaseExpired {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Eventf(
+		accessRequest,
+		nil,
+		eventType,
+		fmt.Sprintf("AccessRequest%s", accessRequest.Status.Phase),
+		EventActionUpdateStatus,
+		"%s",
+		accessRequest.Status.Message,
+	)
+}
+
+func (r *AccessRequestReconciler) updateStatusWithRetriesOnConflict(
+	ctx context.Context,
+	accessRequest accesseratorv1alpha.AccessRequest,
+) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &accesseratorv1alpha.AccessRequest{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(&accessRequest), latest); err != nil {
+			return err
+		}
+		latest.Status = accessRequest.Status
+		return r.Status().Update(ctx, latest)
+	})
+}
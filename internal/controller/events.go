@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/reconciliation"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/events"
+)
+
+// ReportingControllerPrefix prefixes the name of the controllers reporting events.
+const ReportingControllerPrefix = "accesserator.kartverket.no/"
+
+// Reasons of the events emitted by the controllers.
+const (
+	EventReasonCreated                  = "Created"
+	EventReasonUpdated                  = "Updated"
+	EventReasonDeleted                  = "Deleted"
+	EventReasonReconcileFailed          = "ReconcileFailed"
+	EventReasonStatusUpdateFailed       = "StatusUpdateFailed"
+	EventReasonAccessGrantExpired       = "AccessGrantExpired"
+	EventReasonAccessGrantExpiringSoon  = "AccessGrantExpiringSoon"
+	EventReasonNotManagedByAccesserator = "NotManagedByAccesserator"
+)
+
+// Actions of the events emitted by the controllers, describing what the controller did.
+const (
+	EventActionCreate       = "Create"
+	EventActionUpdate       = "Update"
+	EventActionDelete       = "Delete"
+	EventActionReconcile    = "Reconcile"
+	EventActionUpdateStatus = "UpdateStatus"
+	EventActionSkipUpdate   = "SkipUpdate"
+)
+
+// NewEventBroadcaster returns a broadcaster writing events.k8s.io/v1 events to the cluster. Repeated events are
+// aggregated into event series by the broadcaster.
+func NewEventBroadcaster(config *rest.Config) (events.EventBroadcaster, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset for events: %w", err)
+	}
+	return events.NewBroadcaster(&events.EventSinkImpl{Interface: clientset.EventsV1()}), nil
+}
+
+// recordOutcome emits an event on the regarding object if reconciling a descendant changed it.
+func recordOutcome(
+	recorder events.EventRecorder,
+	regarding *accesseratorv1alpha.SecurityConfig,
+	resource reconciliation.ControllerResource,
+	outcome reconciliation.Outcome,
+) {
+	switch outcome.Operation {
+	case reconciliation.OperationCreated:
+		recorder.Eventf(
+			regarding, outcome.Object, corev1.EventTypeNormal, EventReasonCreated, EventActionCreate,
+			"Created %s %s.", resource.GetResourceKind(), resource.GetResourceName(),
+		)
+	case reconciliation.OperationUpdated:
+		recorder.Eventf(
+			regarding, outcome.Object, corev1.EventTypeNormal, EventReasonUpdated, EventActionUpdate,
+			"Updated %s %s: changed %s.", resource.GetResourceKind(), resource.GetResourceName(), getChangesSummary(outcome.Changes),
+		)
+	case reconciliation.OperationDeleted:
+		recorder.Eventf(
+			regarding, outcome.Object, corev1.EventTypeNormal, EventReasonDeleted, EventActionDelete,
+			"Deleted %s %s as it is no longer desired.", resource.GetResourceKind(), resource.GetResourceName(),
+		)
+	}
+}
+
+func getChangesSummary(changes []string) string {
+	if len(changes) == 0 {
+		return "metadata"
+	}
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/reconciliation"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("recordOutcome", func() {
+	securityConfig := &accesseratorv1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-securityconfig", Namespace: "my-namespace"},
+	}
+	jwker := &naisiov1.Jwker{ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "my-namespace"}}
+	resource := fakeControllerResource{kind: "Jwker", name: "my-app"}
+
+	It("should emit an event when a resource is created", func() {
+		recorder := events.NewFakeRecorder(1)
+		recordOutcome(recorder, securityConfig, resource, reconciliation.Outcome{
+			Operation: reconciliation.OperationCreated,
+			Object:    jwker,
+		})
+		Expect(recorder.Events).To(Receive(Equal("Normal Created Created Jwker my-app.")))
+	})
+
+	It("should emit an event with the changed fields when a resource is updated", func() {
+		recorder := events.NewFakeRecorder(1)
+		recordOutcome(recorder, securityConfig, resource, reconciliation.Outcome{
+			Operation: reconciliation.OperationUpdated,
+			Object:    jwker,
+			Changes:   []string{"spec.accessPolicy", "spec.secretName"},
+		})
+		Expect(recorder.Events).To(Receive(Equal(
+			"Normal Updated Updated Jwker my-app: changed spec.accessPolicy, spec.secretName.",
+		)))
+	})
+
+	It("should emit an event when a resource is deleted", func() {
+		recorder := events.NewFakeRecorder(1)
+		recordOutcome(recorder, securityConfig, resource, reconciliation.Outcome{
+			Operation: reconciliation.OperationDeleted,
+			Object:    jwker,
+		})
+		Expect(recorder.Events).To(Receive(Equal("Normal Deleted Deleted Jwker my-app as it is no longer desired.")))
+	})
+
+	It("should not emit an event when a resource is unchanged", func() {
+		recorder := events.NewFakeRecorder(1)
+		recordOutcome(recorder, securityConfig, resource, reconciliation.Outcome{Operation: reconciliation.OperationNone})
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("doReconcile", func() {
+	It("should emit a single warning event per failed resource", func() {
+		recorder := events.NewFakeRecorder(10)
+		reconciler := &SecurityConfigReconciler{Recorder: recorder}
+		scope := &state.Scope{SecurityConfig: accesseratorv1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-securityconfig", Namespace: "my-namespace"},
+		}}
+
+		_, err := reconciler.doReconcile(ctx, []reconciliation.ControllerResource{
+			fakeControllerResource{kind: "Jwker", name: "my-app", err: errors.New("boom")},
+		}, scope)
+
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.Events).To(Receive(Equal("Warning ReconcileFailed Failed to reconcile Jwker my-app: boom")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should make the SecurityConfig invalid instead of requeueing when a resource is rejected", func() {
+		reconciler := &SecurityConfigReconciler{Recorder: events.NewFakeRecorder(10)}
+		scope := &state.Scope{SecurityConfig: accesseratorv1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-securityconfig", Namespace: "my-namespace"},
+		}}
+		invalid := apierrors.NewInvalid(schema.GroupKind{Group: "nais.io", Kind: "Jwker"}, "my-app", nil)
+
+		_, err := reconciler.doReconcile(ctx, []reconciliation.ControllerResource{
+			fakeControllerResource{kind: "Jwker", name: "my-app", err: invalid},
+			fakeControllerResource{kind: "NetworkPolicy", name: "my-app"},
+		}, scope)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scope.InvalidConfig).To(BeTrue())
+		Expect(*scope.ValidationErrorMessage).To(ContainSubstring(invalid.Error()))
+	})
+})
+
+type fakeControllerResource struct {
+	kind, name string
+	err        error
+}
+
+func (f fakeControllerResource) Reconcile(
+	context.Context,
+	client.Client,
+	*runtime.Scheme,
+) (ctrl.Result, reconciliation.Outcome, error) {
+	return ctrl.Result{}, reconciliation.Outcome{Operation: reconciliation.OperationNone}, f.err
+}
+
+func (f fakeControllerResource) GetResourceKind() string           { return f.kind }
+func (f fakeControllerResource) GetResourceName() string           { return f.name }
+func (f fakeControllerResource) IsResourceNil() bool               { return false }
+func (f fakeControllerResource) GetDesiredResource() client.Object { return nil }
@@ -2,68 +2,144 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
 	"github.com/kartverket/accesserator/internal/eventhandler"
 	"github.com/kartverket/accesserator/internal/resolver"
 	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/capability/tokenx"
 	"github.com/kartverket/accesserator/pkg/config"
 	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/accesserator/pkg/metrics"
+	"github.com/kartverket/accesserator/pkg/naming"
 	"github.com/kartverket/accesserator/pkg/reconciliation"
-	"github.com/kartverket/accesserator/pkg/resourcegenerators/tokenx/egress"
-	"github.com/kartverket/accesserator/pkg/resourcegenerators/tokenx/jwker"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/externalegress"
+	"github.com/kartverket/accesserator/pkg/tracing"
 	"github.com/kartverket/accesserator/pkg/utilities"
 	"github.com/kartverket/skiperator/api/v1alpha1"
 	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8sErrors "k8s.io/apimachinery/pkg/util/errors"
-	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-const jwkerSynchronizationStateReady = "RolloutComplete"
+const jwkerSynchronizationStateReady = utilities.JwkerSynchronizationStateReady
 
 // SecurityConfigReconciler reconciles a SecurityConfig object
 type SecurityConfigReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
-	Recorder record.EventRecorder
+	Recorder events.EventRecorder
+	// MaxConcurrentReconciles is the number of SecurityConfigs reconciled concurrently. Defaults to 1.
+	MaxConcurrentReconciles int
+}
+
+// accessRequestValidated passes updates of AccessRequests whose phase or observed generation changed, since the inbound
+// rules of an approved AccessRequest are only added once the AccessRequestReconciler has validated it.
+var accessRequestValidated = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldRequest, oldOk := e.ObjectOld.(*accesseratorv1alpha.AccessRequest)
+		newRequest, newOk := e.ObjectNew.(*accesseratorv1alpha.AccessRequest)
+		return oldOk && newOk && (oldRequest.Status.Phase != newRequest.Status.Phase ||
+			oldRequest.Status.ObservedGeneration != newRequest.Status.ObservedGeneration)
+	},
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecurityConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(
 			&accesseratorv1alpha.SecurityConfig{},
 			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		).
-		Owns(&naisiov1.Jwker{}).
-		Owns(&networkv1.NetworkPolicy{}).
+		Owns(&naisiov1.Jwker{})
+	// Only the resources of the selected egress backend are watched, so the CRDs of the other backends are not
+	// required.
+	switch config.Get().EgressBackend {
+	case config.EgressBackendCilium:
+		b = b.Owns(newUnstructured(cilium.CiliumNetworkPolicyGVK))
+	case config.EgressBackendIstio:
+		// The istio backend is enforced by the AuthorizationPolicy on Tokendings, which is reconciled by the
+		// TokenxIngressReconciler.
+	default:
+		b = b.Owns(&networkv1.NetworkPolicy{})
+	}
+	// ServiceEntries are only generated when external egress endpoints are configured, so the Istio CRDs are not
+	// required otherwise.
+	if len(config.Get().EgressEndpoints) > 0 {
+		b = b.Owns(newUnstructured(externalegress.ServiceEntryGVK))
+	}
+	return b.
 		Watches(&v1alpha1.Application{}, eventhandler.HandleSkiperatorApplicationEvent(r.Client)).
+		Watches(
+			&accesseratorv1alpha.SecurityConfig{},
+			eventhandler.HandleSecurityConfigEvent(r.Client),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&accesseratorv1alpha.AccessRequest{},
+			eventhandler.HandleAccessRequestEvent(),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, accessRequestValidated)),
+		).
+		Watches(&accesseratorv1alpha.AccessApproval{}, eventhandler.HandleAccessApprovalEvent()).
 		Named("securityconfig").
+		WithOptions(controller.Options{MaxConcurrentReconciles: max(r.MaxConcurrentReconciles, 1)}).
 		Complete(r)
 }
 
 // +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=securityconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=securityconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=securityconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=accessrequests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=accessapprovals,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=skiperator.kartverket.no,resources=applications,verbs=get;list;watch
 // +kubebuilder:rbac:groups=nais.io,resources=jwkers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=serviceentries;sidecars,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies,verbs=get;list;watch;create;update;patch;delete
 
 func (r *SecurityConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"SecurityConfigReconciler.Reconcile",
+		attribute.String("accesserator.securityconfig.name", req.Name),
+		attribute.String("k8s.namespace.name", req.Namespace),
+	)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *SecurityConfigReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	rlog := log.GetLogger(ctx)
 	securityConfig := new(accesseratorv1alpha.SecurityConfig)
 	rlog.Info("Reconciling SecurityConfig", "name", req.NamespacedName)
@@ -77,17 +153,14 @@ func (r *SecurityConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return reconcile.Result{}, err
 	}
 
-	r.Recorder.Eventf(
-		securityConfig,
-		"Normal",
-		"ReconcileStarted",
-		fmt.Sprintf("SecurityConfig with name %s started.", req.String()),
-	)
-
-	rlog.Debug("SecurityConfig found", "name", req.NamespacedName)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.SecurityConfigAttributes(*securityConfig)...)
+	ctx = log.WithSecurityConfig(ctx, securityConfig)
+	rlog = log.GetLogger(ctx)
+	rlog.Debug("SecurityConfig found")
 
-	securityConfig.InitializeStatus()
+	// The original status is copied before it is initialized, so phase transitions can be detected.
 	deepCopiedSecurityConfig := securityConfig.DeepCopy()
+	securityConfig.InitializeStatus()
 
 	if !securityConfig.DeletionTimestamp.IsZero() {
 		rlog.Info("SecurityConfig is marked for deletion.", "name", req.NamespacedName)
@@ -97,8 +170,8 @@ func (r *SecurityConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	scope, err := resolver.ResolveSecurityConfig(ctx, r.Client, *securityConfig)
 	if err != nil {
 		rlog.Error(err, "failed to resolve SecurityConfig", "name", req.NamespacedName)
-		securityConfig.Status.Phase = accesseratorv1alpha.PhaseFailed
-		securityConfig.Status.Message = err.Error()
+		securityConfig.Status.SetPhaseFailed(err.Error())
+		securityConfig.Status.RecordPhaseTransition(deepCopiedSecurityConfig.Status.Phase, "ResolveFailed", metav1.Now())
 		updateStatusOnResolveFailedErr := r.updateStatusWithRetriesOnConflict(ctx, *securityConfig)
 		if updateStatusOnResolveFailedErr != nil {
 			return ctrl.Result{}, updateStatusOnResolveFailedErr
@@ -106,102 +179,289 @@ func (r *SecurityConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return reconcile.Result{}, err
 	}
 
-	jwkerObjectMeta := metav1.ObjectMeta{
-		Name:      utilities.GetJwkerName(securityConfig.Spec.ApplicationRef),
-		Namespace: securityConfig.Namespace,
+	if scope.Orphaned {
+		return r.reconcileOrphaned(ctx, scope, deepCopiedSecurityConfig)
 	}
 
-	tokenxEgressObjectMeta := metav1.ObjectMeta{
-		Name:      utilities.GetTokenxEgressName(securityConfig.Name, config.Get().TokenxName),
-		Namespace: securityConfig.Namespace,
+	if scope.InvalidConfig {
+		// An invalid SecurityConfig stays invalid until it, its Application or the other SecurityConfigs in the
+		// namespace change, and the watches enqueue it again then. The descendants are left as they are, so a running
+		// application keeps working until the SecurityConfig is fixed.
+		rlog.Info("SecurityConfig is invalid", "reason", *scope.ValidationErrorMessage)
+		defer func() {
+			r.updateStatus(ctx, scope, deepCopiedSecurityConfig, nil)
+		}()
+		if _, err := r.doReconcile(ctx, tokenx.GetStaleJwkerResources(scope), scope); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	controllerResources := getControllerResources(scope)
+	defer func() {
+		r.updateStatus(ctx, scope, deepCopiedSecurityConfig, controllerResources)
+	}()
+
+	result, err := r.doReconcile(ctx, controllerResources, scope)
+	if err != nil {
+		return result, err
+	}
+	// Time-bound access policy rules must be dropped from the Jwker when they expire, so reconcile again at the next
+	// expiry or expiry warning.
+	return utilities.LowestNonZeroResult(result, getAccessGrantResult(scope, time.Now())), nil
+}
+
+// getControllerResources returns the descendants of the SecurityConfig of every registered capability, including the
+// ones that are not desired, so they are deleted.
+func getControllerResources(scope *state.Scope) []reconciliation.ControllerResource {
+	var controllerResources []reconciliation.ControllerResource
+	for _, c := range capability.All() {
+		controllerResources = append(controllerResources, c.DesiredResources(scope)...)
+	}
+	for _, capabilityName := range config.Get().EgressEndpoints.Capabilities() {
+		controllerResources = append(controllerResources, getExternalEgressResources(scope.SecurityConfig, scope, capabilityName)...)
 	}
+	return controllerResources
+}
 
-	controllerResources := []reconciliation.ControllerResource{
-		ControllerResourceAdapter[*naisiov1.Jwker]{
-			reconciliation.ReconcilerAdapter[*naisiov1.Jwker]{
-				Func: reconciliation.ResourceReconciler[*naisiov1.Jwker]{
-					ResourceKind:    "Jwker",
-					ResourceName:    jwkerObjectMeta.Name,
-					DesiredResource: utilities.Ptr(jwker.GetDesired(jwkerObjectMeta, *scope)),
-					Scope:           scope,
-					ShouldUpdate: func(current, desired *naisiov1.Jwker) bool {
-						return !equality.Semantic.DeepEqual(current.Spec, desired.Spec)
-					},
-					UpdateFields: func(current, desired *naisiov1.Jwker) {
-						current.Spec = desired.Spec
-					},
+// reconcileOrphaned keeps the descendants of a SecurityConfig whose Application does not exist for the grace period,
+// so an Application that is recreated keeps its OAuth client, and deletes them afterwards. The SecurityConfig is
+// reconciled again when the Application is created, since the Application watch enqueues it.
+func (r *SecurityConfigReconciler) reconcileOrphaned(
+	ctx context.Context,
+	scope *state.Scope,
+	original *accesseratorv1alpha.SecurityConfig,
+) (ctrl.Result, error) {
+	rlog := log.GetLogger(ctx)
+	now := time.Now()
+	cleanupTime := getOrphanCleanupTime(original, now)
+	if cleanupTime == nil || cleanupTime.After(now) {
+		rlog.Info("Application of SecurityConfig not found. Keeping its descendants", "applicationRef", scope.SecurityConfig.Spec.ApplicationRef)
+		defer func() {
+			r.updateStatus(ctx, scope, original, nil)
+		}()
+		// The Jwkers named after a previous applicationRef are deleted anyway, since the grace period is only meant
+		// for the descendants of the current Application.
+		if _, err := r.doReconcile(ctx, tokenx.GetStaleJwkerResources(scope), scope); err != nil {
+			return ctrl.Result{}, err
+		}
+		if cleanupTime == nil {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{RequeueAfter: cleanupTime.Sub(now)}, nil
+	}
+
+	rlog.Info("Application of SecurityConfig not found. Deleting its descendants", "applicationRef", scope.SecurityConfig.Spec.ApplicationRef)
+	// The capabilities of an orphaned SecurityConfig are not resolved, so none of its descendants are desired.
+	controllerResources := getControllerResources(scope)
+	defer func() {
+		r.updateStatus(ctx, scope, original, controllerResources)
+	}()
+	return r.doReconcile(ctx, controllerResources, scope)
+}
+
+// getOrphanCleanupTime returns when the descendants of an orphaned SecurityConfig are deleted, counting the grace
+// period from when it became Orphaned. It returns nil if the cleanup of orphaned descendants is disabled.
+func getOrphanCleanupTime(original *accesseratorv1alpha.SecurityConfig, now time.Time) *time.Time {
+	if !config.Get().OrphanCleanupEnabled {
+		return nil
+	}
+	orphanedSince := now
+	if original.Status.Phase == accesseratorv1alpha.PhaseOrphaned {
+		if transitionTime := original.Status.GetPhaseTransitionTime(); transitionTime != nil {
+			orphanedSince = transitionTime.Time
+		}
+	}
+	return utilities.Ptr(orphanedSince.Add(config.Get().OrphanGracePeriod))
+}
+
+// getOrphanedMessage explains what happens to the descendants of an orphaned SecurityConfig.
+func getOrphanedMessage(securityConfig, original *accesseratorv1alpha.SecurityConfig, now time.Time) string {
+	notFound := fmt.Sprintf("Application %s of SecurityConfig was not found.", securityConfig.Spec.ApplicationRef)
+	cleanupTime := getOrphanCleanupTime(original, now)
+	switch {
+	case cleanupTime == nil:
+		return fmt.Sprintf("%s Its descendants are kept, since the cleanup of orphaned descendants is disabled.", notFound)
+	case cleanupTime.After(now):
+		return fmt.Sprintf(
+			"%s Its descendants are deleted at %s unless the Application is created again.",
+			notFound,
+			cleanupTime.UTC().Format(time.RFC3339),
+		)
+	default:
+		return fmt.Sprintf("%s Its descendants are deleted, since the grace period has passed.", notFound)
+	}
+}
+
+// getExternalEgressResources returns the resources allowing the application to reach the external endpoints
+// configured for the capability.
+func getExternalEgressResources(
+	securityConfig accesseratorv1alpha.SecurityConfig,
+	scope *state.Scope,
+	capability string,
+) []reconciliation.ControllerResource {
+	objectMeta := metav1.ObjectMeta{
+		Name:      naming.GetExternalEgressName(securityConfig.Name, capability),
+		Namespace: securityConfig.Namespace,
+	}
+	return []reconciliation.ControllerResource{
+		reconciliation.NewUnstructuredResource(
+			"ServiceEntry",
+			objectMeta.Name,
+			externalegress.GetDesiredServiceEntry(objectMeta, scope, capability),
+			scope,
+			externalegress.ServiceEntryGVK,
+		),
+		reconciliation.NewUnstructuredResource(
+			"CiliumNetworkPolicy",
+			objectMeta.Name,
+			externalegress.GetDesiredCiliumNetworkPolicy(objectMeta, scope, capability),
+			scope,
+			cilium.CiliumNetworkPolicyGVK,
+		),
+		reconciliation.ReconcilerAdapter[*networkv1.NetworkPolicy]{
+			Func: reconciliation.ResourceReconciler[*networkv1.NetworkPolicy]{
+				ResourceKind:    "NetworkPolicy",
+				ResourceName:    objectMeta.Name,
+				DesiredResource: utilities.Ptr(externalegress.GetDesiredNetworkPolicy(objectMeta, scope, capability)),
+				Scope:           scope,
+				ShouldUpdate: func(current, desired *networkv1.NetworkPolicy) bool {
+					return !equality.Semantic.DeepEqual(current.Spec, desired.Spec)
 				},
-			},
-		},
-		ControllerResourceAdapter[*networkv1.NetworkPolicy]{
-			reconciliation.ReconcilerAdapter[*networkv1.NetworkPolicy]{
-				Func: reconciliation.ResourceReconciler[*networkv1.NetworkPolicy]{
-					ResourceKind:    "NetworkPolicy",
-					ResourceName:    tokenxEgressObjectMeta.Name,
-					DesiredResource: utilities.Ptr(egress.GetDesired(tokenxEgressObjectMeta, *scope)),
-					Scope:           scope,
-					ShouldUpdate: func(current, desired *networkv1.NetworkPolicy) bool {
-						return !equality.Semantic.DeepEqual(current.Spec, desired.Spec)
-					},
-					UpdateFields: func(current, desired *networkv1.NetworkPolicy) {
-						current.Spec = desired.Spec
-					},
+				UpdateFields: func(current, desired *networkv1.NetworkPolicy) {
+					current.Spec = desired.Spec
 				},
 			},
 		},
 	}
+}
 
-	defer func() {
-		r.updateStatus(ctx, scope, deepCopiedSecurityConfig, controllerResources)
-	}()
-
-	return r.doReconcile(ctx, controllerResources, scope)
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
 }
 
+// doReconcile reconciles the descendants concurrently, with at most DescendantReconcileWorkers at a time. The
+// descendants do not depend on each other, since each is generated from the scope alone. Transient errors are returned
+// so the SecurityConfig is requeued with backoff, while permanent errors make the SecurityConfig Invalid.
 func (r *SecurityConfigReconciler) doReconcile(
 	ctx context.Context,
 	controllerResources []reconciliation.ControllerResource,
 	scope *state.Scope,
 ) (ctrl.Result, error) {
+	results := make([]ctrl.Result, len(controllerResources))
+	errs := make([]error, len(controllerResources))
+	var group errgroup.Group
+	group.SetLimit(max(config.Get().DescendantReconcileWorkers, 1))
+	for i, rf := range controllerResources {
+		group.Go(func() error {
+			results[i], errs[i] = r.reconcileDescendant(ctx, rf, scope)
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sortDescendants(scope, controllerResources)
+
 	result := ctrl.Result{}
-	var errs []error
-	for _, rf := range controllerResources {
-		reconcileResult, err := rf.Reconcile(ctx, r.Client, r.Scheme)
-		if err != nil {
-			r.Recorder.Eventf(
-				&scope.SecurityConfig,
-				"Warning",
-				fmt.Sprintf("%sReconcileFailed", rf.GetResourceKind()),
-				fmt.Sprintf(
-					"%s with name %s failed during reconciliation.",
-					rf.GetResourceKind(),
-					rf.GetResourceName(),
-				),
-			)
-			errs = append(errs, err)
-		} else {
-			r.Recorder.Eventf(
-				&scope.SecurityConfig,
-				"Normal",
-				fmt.Sprintf("%sReconciledSuccessfully", rf.GetResourceKind()),
-				fmt.Sprintf("%s with name %s reconciled successfully.", rf.GetResourceKind(), rf.GetResourceName()),
-			)
-		}
-		if len(errs) > 0 {
-			continue
+	var transientErrs, permanentErrs []error
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			result = utilities.LowestNonZeroResult(result, results[i])
+		case reconciliation.IsPermanentError(err):
+			permanentErrs = append(permanentErrs, err)
+		default:
+			transientErrs = append(transientErrs, err)
 		}
-		result = utilities.LowestNonZeroResult(result, reconcileResult)
 	}
 
-	if len(errs) > 0 {
-		r.Recorder.Eventf(&scope.SecurityConfig, "Error", "ReconcileFailed", "SecurityConfig failed during reconciliation")
-		r.Recorder.Eventf(&scope.SecurityConfig, "Error", "ReconcileFailed", "SecurityConfig failed during reconciliation")
-		return ctrl.Result{}, k8sErrors.NewAggregate(errs)
+	if len(permanentErrs) > 0 {
+		scope.InvalidConfig = true
+		scope.ValidationErrorMessage = utilities.Ptr(fmt.Sprintf(
+			"Descendants of SecurityConfig were rejected: %s",
+			k8sErrors.NewAggregate(permanentErrs).Error(),
+		))
+	}
+	if len(transientErrs) > 0 {
+		return ctrl.Result{}, k8sErrors.NewAggregate(transientErrs)
 	}
-	r.Recorder.Eventf(&scope.SecurityConfig, "Normal", "ReconcileSuccess", "SecurityConfig reconciled successfully")
 	return result, nil
 }
 
+// sortDescendants orders the descendants of the scope like the controller resources, since the concurrent
+// reconciliation records them in the order they finish. This keeps the conditions of the status stable.
+func sortDescendants(scope *state.Scope, controllerResources []reconciliation.ControllerResource) {
+	order := make(map[string]int, len(controllerResources))
+	for i, rf := range controllerResources {
+		order[state.GetID(rf.GetResourceKind(), rf.GetResourceName())] = i
+	}
+	sort.SliceStable(scope.Descendants, func(i, j int) bool {
+		return order[scope.Descendants[i].ID] < order[scope.Descendants[j].ID]
+	})
+}
+
+// reconcileDescendant reconciles a single descendant within DescendantReconcileTimeout, and emits an event for the
+// outcome.
+func (r *SecurityConfigReconciler) reconcileDescendant(
+	ctx context.Context,
+	rf reconciliation.ControllerResource,
+	scope *state.Scope,
+) (ctrl.Result, error) {
+	if timeout := config.Get().DescendantReconcileTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	reconcileResult, outcome, err := rf.Reconcile(ctx, r.Client, r.Scheme)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", config.Get().DescendantReconcileTimeout, err)
+		}
+		metrics.RecordDescendantReconcileError(rf.GetResourceKind())
+		r.Recorder.Eventf(
+			&scope.SecurityConfig,
+			nil,
+			corev1.EventTypeWarning,
+			EventReasonReconcileFailed,
+			EventActionReconcile,
+			"Failed to reconcile %s %s: %s",
+			rf.GetResourceKind(),
+			rf.GetResourceName(),
+			err.Error(),
+		)
+		return ctrl.Result{}, err
+	}
+	recordOutcome(r.Recorder, &scope.SecurityConfig, rf, outcome)
+	return reconcileResult, nil
+}
+
+// recordPhaseTransition emits an event when the phase of the SecurityConfig has changed.
+func (r *SecurityConfigReconciler) recordPhaseTransition(
+	securityConfig *accesseratorv1alpha.SecurityConfig,
+	previousPhase accesseratorv1alpha.Phase,
+) {
+	if securityConfig.Status.Phase == previousPhase {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if securityConfig.Status.Phase == accesseratorv1alpha.PhaseFailed ||
+		securityConfig.Status.Phase == accesseratorv1alpha.PhaseInvalid ||
+		securityConfig.Status.Phase == accesseratorv1alpha.PhaseOrphaned {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Eventf(
+		securityConfig,
+		nil,
+		eventType,
+		fmt.Sprintf("SecurityConfig%s", securityConfig.Status.Phase),
+		EventActionUpdateStatus,
+		"%s",
+		securityConfig.Status.Message,
+	)
+}
+
 func (r *SecurityConfigReconciler) updateStatusWithRetriesOnConflict(
 	ctx context.Context,
 	securityConfig accesseratorv1alpha.SecurityConfig,
@@ -223,13 +483,22 @@ func (r *SecurityConfigReconciler) updateStatus(
 	controllerResources []reconciliation.ControllerResource,
 ) {
 	securityConfig := scope.SecurityConfig
+	ctx, span := tracing.Start(ctx, "SecurityConfigReconciler.updateStatus", tracing.SecurityConfigAttributes(securityConfig)...)
+	defer span.End()
 	rLog := log.GetLogger(ctx)
-	rLog.Debug(fmt.Sprintf("Updating SecurityConfig status for %s/%s", securityConfig.Namespace, securityConfig.Name))
+	rLog.Debug("Updating SecurityConfig status")
 
+	now := time.Now()
 	securityConfig.Status.ObservedGeneration = securityConfig.GetGeneration()
 	statusCondition := metav1.Condition{
 		Type:               state.GetID(strings.TrimPrefix(securityConfig.Kind, "*"), securityConfig.Name),
-		LastTransitionTime: metav1.Now(),
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	// The capabilities of a SecurityConfig that failed validation or is orphaned are not resolved, so there is no
+	// status of the capabilities either.
+	var capabilityStatus mergedCapabilityStatus
+	if len(controllerResources) > 0 && !scope.Orphaned {
+		capabilityStatus = getCapabilityStatus(ctx, r.Client, scope, now)
 	}
 
 	switch {
@@ -237,6 +506,11 @@ func (r *SecurityConfigReconciler) updateStatus(
 		securityConfig.Status.SetPhaseInvalid(*scope.ValidationErrorMessage)
 		accesseratorv1alpha.SetConditionInvalid(&statusCondition, *scope.ValidationErrorMessage)
 
+	case scope.Orphaned:
+		msg := getOrphanedMessage(&securityConfig, original, now)
+		securityConfig.Status.SetPhaseOrphaned(msg)
+		accesseratorv1alpha.SetConditionOrphaned(&statusCondition, msg)
+
 	case len(scope.Descendants) != reconciliation.CountReconciledResources(controllerResources):
 		securityConfig.Status.SetPhasePending("SecurityConfig pending due to missing Descendants.")
 		accesseratorv1alpha.SetConditionPending(&statusCondition, "Descendants of SecurityConfig are not reconciled yet.")
@@ -245,32 +519,29 @@ func (r *SecurityConfigReconciler) updateStatus(
 		securityConfig.Status.SetPhaseFailed("SecurityConfig reconciliation failed.")
 		accesseratorv1alpha.SetConditionFailed(&statusCondition, "Descendants of SecurityConfig failed during reconciliation.")
 
-	case scope.TokenXConfig.Enabled:
-		jwkerResource, getJwkerErr := scope.GetJwker(ctx, r.Client)
-		if getJwkerErr != nil {
-			rLog.Error(
-				getJwkerErr,
-				fmt.Sprintf(
-					"Failed to get Jwker resource with name %s when updating SecurityConfig status",
-					utilities.GetJwkerName(securityConfig.Spec.ApplicationRef),
-				),
+	case capabilityStatus.Pending != nil:
+		for _, err := range capabilityStatus.Errors {
+			rLog.Error(err, "Failed to get capability status when updating SecurityConfig status")
+			r.Recorder.Eventf(
+				&securityConfig,
+				nil,
+				corev1.EventTypeWarning,
+				EventReasonStatusUpdateFailed,
+				EventActionUpdateStatus,
+				"%s",
+				err.Error(),
 			)
-			r.Recorder.Eventf(&securityConfig, "Error", "StatusUpdateFailed", "Failed to get Jwker resource with name %s.", utilities.GetJwkerName(securityConfig.Spec.ApplicationRef))
-		}
-		if jwkerResource.Status.SynchronizationState != jwkerSynchronizationStateReady {
-			securityConfig.Status.SetPhasePending("SecurityConfig pending due to missing TokenX secret.")
-			statusMsg := fmt.Sprintf("Jwker resource with name %s has not finished registering an OAuth client", jwkerResource.Name)
-			accesseratorv1alpha.SetConditionPending(&statusCondition, statusMsg)
-		} else {
-			securityConfig.Status.SetPhaseReady("SecurityConfig ready.")
-			accesseratorv1alpha.SetConditionReady(&statusCondition, "Descendants of SecurityConfig reconciled successfully.")
 		}
+		securityConfig.Status.SetPhasePending(capabilityStatus.Pending.PhaseMessage)
+		accesseratorv1alpha.SetConditionPending(&statusCondition, capabilityStatus.Pending.ConditionMessage)
 
 	default:
 		securityConfig.Status.SetPhaseReady("SecurityConfig ready.")
 		accesseratorv1alpha.SetConditionReady(&statusCondition, "Descendants of SecurityConfig reconciled successfully.")
 	}
 
+	securityConfig.Status.RecordPhaseTransition(original.Status.Phase, statusCondition.Reason, metav1.NewTime(now))
+
 	conditions := make([]metav1.Condition, 0, len(scope.Descendants)+len(controllerResources))
 	descendantIDs := map[string]bool{}
 
@@ -278,7 +549,7 @@ func (r *SecurityConfigReconciler) updateStatus(
 		descendantIDs[d.ID] = true
 		cond := metav1.Condition{
 			Type:               d.ID,
-			LastTransitionTime: metav1.Now(),
+			LastTransitionTime: metav1.NewTime(now),
 		}
 		switch {
 		case d.ErrorMessage != nil:
@@ -309,28 +580,192 @@ func (r *SecurityConfigReconciler) updateStatus(
 						rf.GetResourceName(),
 						rf.GetResourceKind(),
 					),
-					LastTransitionTime: metav1.Now(),
+					LastTransitionTime: metav1.NewTime(now),
 				})
 			}
 		}
 	}
 
-	securityConfig.Status.Conditions = append([]metav1.Condition{statusCondition}, conditions...)
+	conditions = append(conditions, capabilityStatus.Conditions...)
+
+	securityConfig.Status.Conditions = preserveTransitionTimes(
+		append([]metav1.Condition{statusCondition}, conditions...),
+		original.Status.Conditions,
+	)
+
+	securityConfig.Status.AccessGrants = getAccessGrantStatuses(scope, now)
+	// Without controller resources, e.g. for an invalid SecurityConfig, the descendants are left as they are, and so
+	// are the generated resources in the status.
+	if controllerResources != nil {
+		securityConfig.Status.GeneratedResources = getGeneratedResources(controllerResources)
+	}
 
 	if !equality.Semantic.DeepEqual(original.Status, securityConfig.Status) {
-		rLog.Debug(fmt.Sprintf("Updating SecurityConfig status with name %s/%s", securityConfig.Namespace, securityConfig.Name))
+		rLog.Debug("Status of SecurityConfig changed. Updating it")
 		if updateStatusWithRetriesErr := r.updateStatusWithRetriesOnConflict(ctx, securityConfig); updateStatusWithRetriesErr != nil {
-			rLog.Error(
-				updateStatusWithRetriesErr,
-				fmt.Sprintf(
-					"Failed to update SecurityConfig status with name %s/%s",
-					securityConfig.Namespace,
-					securityConfig.Name,
-				),
+			rLog.Error(updateStatusWithRetriesErr, "Failed to update SecurityConfig status")
+			r.Recorder.Eventf(
+				&securityConfig,
+				nil,
+				corev1.EventTypeWarning,
+				EventReasonStatusUpdateFailed,
+				EventActionUpdateStatus,
+				"Status update of SecurityConfig failed.",
 			)
-			r.Recorder.Eventf(&securityConfig, "Error", "StatusUpdateFailed", "Status update of SecurityConfig failed.")
 		} else {
-			r.Recorder.Eventf(&securityConfig, "Normal", "StatusUpdateSuccess", "Status of SecurityConfig updated successfully.")
+			r.recordAccessGrantEvents(&securityConfig, original.Status.AccessGrants, scope, now)
+			r.recordPhaseTransition(&securityConfig, original.Status.Phase)
+			// The time to ready is measured from creation, so it is only observed the first time the SecurityConfig
+			// becomes Ready, and not when it recovers from a later phase.
+			if original.Status.Phase != accesseratorv1alpha.PhaseReady &&
+				securityConfig.Status.Phase == accesseratorv1alpha.PhaseReady &&
+				!original.Status.HasBeenReady() {
+				metrics.RecordSecurityConfigReady(securityConfig.CreationTimestamp.Time, now)
+			}
+		}
+	}
+}
+
+// getGeneratedResources returns the kinds and names of the desired controller resources.
+func getGeneratedResources(controllerResources []reconciliation.ControllerResource) []accesseratorv1alpha.GeneratedResource {
+	var generatedResources []accesseratorv1alpha.GeneratedResource
+	for _, rf := range controllerResources {
+		if !rf.IsResourceNil() {
+			generatedResources = append(generatedResources, accesseratorv1alpha.GeneratedResource{
+				Kind: rf.GetResourceKind(),
+				Name: rf.GetResourceName(),
+			})
+		}
+	}
+	return generatedResources
+}
+
+// preserveTransitionTimes keeps the LastTransitionTime of the previous conditions whose status has not changed, so the
+// time reflects the actual transition rather than the last status update.
+func preserveTransitionTimes(conditions, previous []metav1.Condition) []metav1.Condition {
+	for i := range conditions {
+		previousCondition := meta.FindStatusCondition(previous, conditions[i].Type)
+		if previousCondition != nil && previousCondition.Status == conditions[i].Status {
+			conditions[i].LastTransitionTime = previousCondition.LastTransitionTime
+		}
+	}
+	return conditions
+}
+
+// mergedCapabilityStatus is the status of all the capabilities enabled by a SecurityConfig.
+type mergedCapabilityStatus struct {
+	// Pending is the reason of the first capability that is not ready yet, if any.
+	Pending    *capability.Pending
+	Conditions []metav1.Condition
+	// Errors are only reported when a capability is pending, since they are expected while descendants are missing.
+	Errors []error
+}
+
+func getCapabilityStatus(
+	ctx context.Context,
+	k8sClient client.Client,
+	scope *state.Scope,
+	now time.Time,
+) mergedCapabilityStatus {
+	var merged mergedCapabilityStatus
+	for _, c := range capability.Enabled(scope.SecurityConfig) {
+		status, err := c.Status(ctx, k8sClient, scope, now)
+		if err != nil {
+			merged.Errors = append(merged.Errors, fmt.Errorf("failed to get status of capability %s: %w", c.Name(), err))
+		}
+		if merged.Pending == nil {
+			merged.Pending = status.Pending
+		}
+		merged.Conditions = append(merged.Conditions, status.Conditions...)
+	}
+	return merged
+}
+
+func getAccessGrantResult(scope *state.Scope, now time.Time) ctrl.Result {
+	warning := config.Get().AccessGrantExpiryWarning
+	var next time.Time
+	for _, grant := range scope.TokenXConfig.AccessGrants {
+		for _, at := range []time.Time{grant.ExpiresAt.Add(-warning), grant.ExpiresAt} {
+			if at.After(now) && (next.IsZero() || at.Before(next)) {
+				next = at
+			}
+		}
+	}
+	if next.IsZero() {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(now)}
+}
+
+func getAccessGrantStatuses(scope *state.Scope, now time.Time) []accesseratorv1alpha.AccessGrantStatus {
+	var statuses []accesseratorv1alpha.AccessGrantStatus
+	for _, grant := range scope.TokenXConfig.AccessGrants {
+		if grant.Expired(now) {
+			continue
+		}
+		// Only the expiry is stored, since a remaining lifetime would change the status on every reconciliation. The
+		// status command of the CLI computes the remaining lifetime when the status is read.
+		statuses = append(statuses, accesseratorv1alpha.AccessGrantStatus{
+			Direction:    string(grant.Direction),
+			Application:  grant.Peer.Name,
+			Namespace:    grant.Peer.Namespace,
+			ExpiresAt:    metav1.NewTime(grant.ExpiresAt),
+			ExpiringSoon: grant.ExpiresAt.Sub(now) <= config.Get().AccessGrantExpiryWarning,
+		})
+	}
+	return statuses
+}
+
+// recordAccessGrantEvents emits an event when an access grant starts expiring soon, and when it has expired. The
+// previous status is used to only emit the events once per grant, so it must only be called once the new status is
+// persisted.
+func (r *SecurityConfigReconciler) recordAccessGrantEvents(
+	securityConfig *accesseratorv1alpha.SecurityConfig,
+	previous []accesseratorv1alpha.AccessGrantStatus,
+	scope *state.Scope,
+	now time.Time,
+) {
+	getPrevious := func(grant accesspolicy.Grant) *accesseratorv1alpha.AccessGrantStatus {
+		for i, status := range previous {
+			if status.Direction == string(grant.Direction) &&
+				status.Application == grant.Peer.Name &&
+				status.Namespace == grant.Peer.Namespace &&
+				status.ExpiresAt.Time.Equal(grant.ExpiresAt) {
+				return &previous[i]
+			}
+		}
+		return nil
+	}
+
+	for _, grant := range scope.TokenXConfig.AccessGrants {
+		previousStatus := getPrevious(grant)
+		switch {
+		case grant.Expired(now):
+			if previousStatus != nil {
+				r.Recorder.Eventf(
+					securityConfig,
+					nil,
+					corev1.EventTypeWarning,
+					EventReasonAccessGrantExpired,
+					EventActionUpdate,
+					"Access policy %s expired at %s and has been removed.",
+					grant,
+					grant.ExpiresAt.Format(time.RFC3339),
+				)
+			}
+		case grant.ExpiresAt.Sub(now) <= config.Get().AccessGrantExpiryWarning:
+			if previousStatus == nil || !previousStatus.ExpiringSoon {
+				r.Recorder.Eventf(
+					securityConfig,
+					nil,
+					corev1.EventTypeWarning,
+					EventReasonAccessGrantExpiringSoon,
+					EventActionReconcile,
+					"Access policy %s expires at %s.",
+					grant,
+					grant.ExpiresAt.Format(time.RFC3339),
+				)
+			}
 		}
 	}
 }
@@ -2,8 +2,9 @@ package controller
 
 import (
 	"context"
-
+	"fmt"
 	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/naming"
 	"github.com/kartverket/accesserator/pkg/utilities"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -11,9 +12,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
 
 	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
 	"github.com/kartverket/skiperator/api/v1alpha1"
@@ -47,6 +49,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      skiperatorAppName,
 						Namespace: namespaceName,
+						Labels:    map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue},
 					},
 					Spec: v1alpha1.ApplicationSpec{
 						AccessPolicy: &podtypes.AccessPolicy{},
@@ -88,14 +91,14 @@ var _ = Describe("SecurityConfig Controller", func() {
 
 			By("Cleanup any created Jwker resource")
 			jwker := &naisiov1.Jwker{}
-			jwkerKey := types.NamespacedName{Name: utilities.GetJwkerName(skiperatorAppName), Namespace: namespaceName}
+			jwkerKey := types.NamespacedName{Name: naming.GetJwkerName(skiperatorAppName), Namespace: namespaceName}
 			if err := k8sClient.Get(ctx, jwkerKey, jwker); err == nil {
 				Expect(k8sClient.Delete(ctx, jwker)).To(Succeed())
 			}
 
 			By("Cleanup any created Netpol resource")
 			netpol := &v1.NetworkPolicy{}
-			netpolKey := types.NamespacedName{Name: utilities.GetTokenxEgressName(securityConfigName, config.Get().TokenxName), Namespace: namespaceName}
+			netpolKey := types.NamespacedName{Name: naming.GetTokenxEgressName(securityConfigName, config.Get().TokenxName), Namespace: namespaceName}
 			if err := k8sClient.Get(ctx, netpolKey, netpol); err == nil {
 				Expect(k8sClient.Delete(ctx, netpol)).To(Succeed())
 			}
@@ -112,7 +115,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 		It("should create a Jwker resource and a NetworkPolicy when TokenX is enabled", func() {
 			By("Reconciling the SecurityConfig with TokenX enabled")
 
-			fakeRecorder := record.NewFakeRecorder(100)
+			fakeRecorder := events.NewFakeRecorder(100)
 			controllerReconciler := getSecurityConfigReconciler(fakeRecorder)
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -123,7 +126,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 			By("Verifying that a NetworkPolicy resource was created")
 			netpol := &v1.NetworkPolicy{}
 			netpolKey := types.NamespacedName{
-				Name:      utilities.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
+				Name:      naming.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
 				Namespace: namespaceName,
 			}
 			Eventually(func() error {
@@ -133,7 +136,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 			By("Verifying that a Jwker resource was created")
 			jwker := &naisiov1.Jwker{}
 			jwkerKey := types.NamespacedName{
-				Name:      utilities.GetJwkerName(skiperatorAppName),
+				Name:      naming.GetJwkerName(skiperatorAppName),
 				Namespace: namespaceName,
 			}
 			Eventually(func() error {
@@ -168,14 +171,22 @@ var _ = Describe("SecurityConfig Controller", func() {
 				return sc.Status.Phase, nil
 			}).Should(Equal(accesseratorv1alpha.PhaseReady))
 
-			By("Verifying events were emitted")
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconcileStarted")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconciledSuccessfully")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconcileSuccess")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("StatusUpdateSuccess")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("ReconcileFailed")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("StatusUpdateFailed")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("ReconcileFailed")))
+			By("Verifying that the phase transitions were recorded in the phase history")
+			sc := &accesseratorv1alpha.SecurityConfig{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, sc)).To(Succeed())
+			Expect(sc.Status.PhaseHistory).To(HaveLen(2))
+			Expect(sc.Status.PhaseHistory[0].Phase).To(Equal(accesseratorv1alpha.PhaseReady))
+			Expect(sc.Status.PhaseHistory[0].Reason).To(Equal("ReconciliationSuccess"))
+			Expect(sc.Status.PhaseHistory[1].Phase).To(Equal(accesseratorv1alpha.PhasePending))
+
+			By("Verifying events were emitted for the transitions only")
+			Eventually(fakeRecorder.Events).Should(Receive(Equal(
+				fmt.Sprintf("Normal Created Created Jwker %s.", naming.GetJwkerName(skiperatorAppName)),
+			)))
+			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("Normal Created Created NetworkPolicy")))
+			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("Normal SecurityConfigPending")))
+			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("Normal SecurityConfigReady")))
+			Consistently(fakeRecorder.Events).ShouldNot(Receive())
 		})
 
 		It("should NOT create a Jwker resource nor a NetworkPolicy resource when TokenX is disabled", func() {
@@ -188,7 +199,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 
 			By("Reconciling the SecurityConfig with TokenX disabled")
 
-			fakeRecorder := record.NewFakeRecorder(100)
+			fakeRecorder := events.NewFakeRecorder(100)
 			controllerReconciler := getSecurityConfigReconciler(fakeRecorder)
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -200,7 +211,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 			jwker := &naisiov1.Jwker{}
 			Consistently(func() bool {
 				err := k8sClient.Get(ctx, types.NamespacedName{
-					Name:      utilities.GetJwkerName(skiperatorAppName),
+					Name:      naming.GetJwkerName(skiperatorAppName),
 					Namespace: namespaceName,
 				}, jwker)
 				return errors.IsNotFound(err)
@@ -210,26 +221,21 @@ var _ = Describe("SecurityConfig Controller", func() {
 			netpol := &v1.NetworkPolicy{}
 			Consistently(func() bool {
 				err := k8sClient.Get(ctx, types.NamespacedName{
-					Name:      utilities.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
+					Name:      naming.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
 					Namespace: namespaceName,
 				}, netpol)
 				return errors.IsNotFound(err)
 			}).Should(BeTrue())
 
-			By("Verifying events were emitted")
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconcileStarted")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconciledSuccessfully")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("ReconcileSuccess")))
-			Eventually(fakeRecorder.Events).Should(Receive(ContainSubstring("StatusUpdateSuccess")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("ReconcileFailed")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("StatusUpdateFailed")))
-			Eventually(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring("ReconcileFailed")))
+			By("Verifying that no events were emitted for resources that were not changed")
+			Consistently(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring(EventReasonCreated)))
+			Consistently(fakeRecorder.Events).ShouldNot(Receive(ContainSubstring(EventReasonReconcileFailed)))
 		})
 
 		It("should recreate owned resources when they are deleted", func() {
 			By("Reconciling the SecurityConfig to create owned resources")
 
-			fakeRecorder := record.NewFakeRecorder(100)
+			fakeRecorder := events.NewFakeRecorder(100)
 			controllerReconciler := getSecurityConfigReconciler(fakeRecorder)
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -240,7 +246,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 			By("Deleting the owned NetworkPolicy resource")
 			netpol := &v1.NetworkPolicy{}
 			netpolKey := types.NamespacedName{
-				Name:      utilities.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
+				Name:      naming.GetTokenxEgressName(securityConfigName, config.Get().TokenxName),
 				Namespace: namespaceName,
 			}
 			Expect(k8sClient.Get(ctx, netpolKey, netpol)).To(Succeed())
@@ -260,7 +266,7 @@ var _ = Describe("SecurityConfig Controller", func() {
 			By("Deleting the owned Jwker resource")
 			jwker := &naisiov1.Jwker{}
 			jwkerKey := types.NamespacedName{
-				Name:      utilities.GetJwkerName(skiperatorAppName),
+				Name:      naming.GetJwkerName(skiperatorAppName),
 				Namespace: namespaceName,
 			}
 			Expect(k8sClient.Get(ctx, jwkerKey, jwker)).To(Succeed())
@@ -281,11 +287,11 @@ var _ = Describe("SecurityConfig Controller", func() {
 })
 
 func getSecurityConfigReconciler(
-	eventRecorder record.EventRecorder,
+	eventRecorder events.EventRecorder,
 ) *SecurityConfigReconciler {
 	return &SecurityConfigReconciler{
-		Client:   gvkInjectingClient{k8sClient},
-		Scheme:   gvkInjectingClient{k8sClient}.Scheme(),
+		Client:   gvkInjectingClient{indexedClient},
+		Scheme:   gvkInjectingClient{indexedClient}.Scheme(),
 		Recorder: eventRecorder,
 	}
 }
@@ -305,3 +311,95 @@ func (c gvkInjectingClient) Get(ctx context.Context, key client.ObjectKey, obj c
 	}
 	return nil
 }
+
+var _ = Describe("SecurityConfig status", func() {
+	earlier := metav1.NewTime(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC))
+
+	It("should preserve the transition time of conditions whose status has not changed", func() {
+		previous := []metav1.Condition{
+			{Type: "Jwker-app", Status: metav1.ConditionTrue, LastTransitionTime: earlier},
+			{Type: "NetworkPolicy-app", Status: metav1.ConditionFalse, LastTransitionTime: earlier},
+		}
+		conditions := preserveTransitionTimes([]metav1.Condition{
+			{Type: "Jwker-app", Status: metav1.ConditionTrue, Message: "changed", LastTransitionTime: now},
+			{Type: "NetworkPolicy-app", Status: metav1.ConditionTrue, LastTransitionTime: now},
+			{Type: "ServiceEntry-app", Status: metav1.ConditionTrue, LastTransitionTime: now},
+		}, previous)
+
+		Expect(conditions[0].LastTransitionTime).To(Equal(earlier))
+		Expect(conditions[1].LastTransitionTime).To(Equal(now))
+		Expect(conditions[2].LastTransitionTime).To(Equal(now))
+	})
+
+	It("should only record phase transitions when the phase changes", func() {
+		status := accesseratorv1alpha.SecurityConfigStatus{}
+		status.SetPhaseReady("SecurityConfig ready.")
+		status.RecordPhaseTransition(accesseratorv1alpha.PhaseReady, "ReconciliationSuccess", now)
+		Expect(status.PhaseHistory).To(BeEmpty())
+
+		status.RecordPhaseTransition(accesseratorv1alpha.PhasePending, "ReconciliationSuccess", now)
+		Expect(status.PhaseHistory).To(Equal([]accesseratorv1alpha.PhaseTransition{{
+			Phase:          accesseratorv1alpha.PhaseReady,
+			Reason:         "ReconciliationSuccess",
+			Message:        "SecurityConfig ready.",
+			TransitionTime: now,
+		}}))
+	})
+
+	It("should keep a bounded phase history with the newest transition first", func() {
+		status := accesseratorv1alpha.SecurityConfigStatus{}
+		for i := 0; i < accesseratorv1alpha.PhaseHistoryLimit+5; i++ {
+			status.SetPhaseFailed(fmt.Sprintf("failure %d", i))
+			status.RecordPhaseTransition(accesseratorv1alpha.PhaseReady, "ReconciliationFailed", now)
+		}
+
+		Expect(status.PhaseHistory).To(HaveLen(accesseratorv1alpha.PhaseHistoryLimit))
+		Expect(status.PhaseHistory[0].Message).To(Equal(fmt.Sprintf("failure %d", accesseratorv1alpha.PhaseHistoryLimit+4)))
+	})
+
+	It("should only report having been ready once a Ready phase is recorded", func() {
+		status := accesseratorv1alpha.SecurityConfigStatus{}
+		status.SetPhaseFailed("failure")
+		status.RecordPhaseTransition(accesseratorv1alpha.PhasePending, "ReconciliationFailed", now)
+		Expect(status.HasBeenReady()).To(BeFalse())
+
+		status.SetPhaseReady("SecurityConfig ready.")
+		status.RecordPhaseTransition(accesseratorv1alpha.PhaseFailed, "ReconciliationSuccess", now)
+		status.SetPhaseFailed("failure")
+		status.RecordPhaseTransition(accesseratorv1alpha.PhaseReady, "ReconciliationFailed", now)
+		Expect(status.HasBeenReady()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Orphaned SecurityConfig", func() {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	getOrphaned := func(orphanedSince time.Time) *accesseratorv1alpha.SecurityConfig {
+		securityConfig := &accesseratorv1alpha.SecurityConfig{Spec: accesseratorv1alpha.SecurityConfigSpec{ApplicationRef: "app"}}
+		securityConfig.Status.SetPhaseOrphaned("Application app of SecurityConfig was not found.")
+		securityConfig.Status.RecordPhaseTransition(accesseratorv1alpha.PhaseReady, "ApplicationNotFound", metav1.NewTime(orphanedSince))
+		return securityConfig
+	}
+
+	It("should count the grace period from when the SecurityConfig became Orphaned", func() {
+		orphanedSince := now.Add(-time.Minute)
+		Expect(*getOrphanCleanupTime(getOrphaned(orphanedSince), now)).To(Equal(orphanedSince.Add(config.Get().OrphanGracePeriod)))
+	})
+
+	It("should start the grace period when the SecurityConfig becomes Orphaned", func() {
+		Expect(*getOrphanCleanupTime(&accesseratorv1alpha.SecurityConfig{}, now)).To(Equal(now.Add(config.Get().OrphanGracePeriod)))
+	})
+
+	It("should tell when the descendants are deleted", func() {
+		securityConfig := getOrphaned(now.Add(-time.Minute))
+		Expect(getOrphanedMessage(securityConfig, securityConfig, now)).To(Equal(fmt.Sprintf(
+			"Application app of SecurityConfig was not found. Its descendants are deleted at %s unless the Application is created again.",
+			now.Add(-time.Minute).Add(config.Get().OrphanGracePeriod).Format(time.RFC3339),
+		)))
+
+		securityConfig = getOrphaned(now.Add(-config.Get().OrphanGracePeriod))
+		Expect(getOrphanedMessage(securityConfig, securityConfig, now)).To(Equal(
+			"Application app of SecurityConfig was not found. Its descendants are deleted, since the grace period has passed.",
+		))
+	})
+})
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/fieldindex"
 	"github.com/kartverket/skiperator/api/v1alpha1"
 	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	. "github.com/onsi/ginkgo/v2"
@@ -16,6 +17,7 @@ import (
 
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -34,6 +36,8 @@ var (
 	testEnv   *envtest.Environment
 	cfg       *rest.Config
 	k8sClient client.Client
+	// indexedClient serves the lookups by field index from a cache, like the client of the manager does.
+	indexedClient client.Client
 )
 
 func TestControllers(t *testing.T) {
@@ -66,6 +70,8 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	err = os.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "a-random-tag")
 	Expect(err).NotTo(HaveOccurred())
+	err = os.Setenv("ACCESSERATOR_ORPHAN_CLEANUP_ENABLED", "true")
+	Expect(err).NotTo(HaveOccurred())
 	err = config.Load()
 	Expect(err).NotTo(HaveOccurred())
 
@@ -93,6 +99,16 @@ var _ = BeforeSuite(func() {
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
+
+	indexedCache, err := cache.New(cfg, cache.Options{Scheme: scheme.Scheme})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(fieldindex.Setup(ctx, indexedCache)).To(Succeed())
+	go func() {
+		defer GinkgoRecover()
+		Expect(indexedCache.Start(ctx)).To(Succeed())
+	}()
+	Expect(indexedCache.WaitForCacheSync(ctx)).To(BeTrue())
+	indexedClient = fieldIndexClient{Client: k8sClient, cache: indexedCache}
 })
 
 var _ = AfterSuite(func() {
@@ -102,6 +118,21 @@ var _ = AfterSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 })
 
+// fieldIndexClient lists from the cache when the list is selected by field, since the API server cannot select by
+// the field indexes of the cache, and reads everything else directly so the tests do not have to wait for the cache.
+type fieldIndexClient struct {
+	client.Client
+	cache cache.Cache
+}
+
+func (c fieldIndexClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := (&client.ListOptions{}).ApplyOptions(opts)
+	if listOptions.FieldSelector != nil {
+		return c.cache.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
 // getFirstFoundEnvTestBinaryDir locates the first binary in the specified path.
 // ENVTEST-based tests depend on specific binaries, usually located in paths set by
 // controller-runtime. When running tests directly (e.g., via an IDE) without using
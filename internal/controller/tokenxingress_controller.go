@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+
+	accesseratorv1alpha "github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/eventhandler"
+	"github.com/kartverket/accesserator/pkg/audit"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/tokenx/ingress"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TokenxIngressReconciler reconciles the resources in the Tokendings namespace allowing ingress from the applications
+// of all SecurityConfigs with TokenX enabled: a NetworkPolicy, and an Istio AuthorizationPolicy with the istio egress
+// backend. The resources are in another namespace than the SecurityConfigs and cannot be owned by them, so they are
+// only updated or deleted when they are labeled as managed by Accesserator.
+type TokenxIngressReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
+}
+
+// tokenxIngressResource is a resource in the Tokendings namespace reconciled by the TokenxIngressReconciler.
+type tokenxIngressResource struct {
+	kind string
+	// current is an empty object of the kind of the resource, which the current resource is read into.
+	current client.Object
+	// desired is nil if the resource is not desired.
+	desired client.Object
+	// updateSpec copies the spec of desired to current, and returns false if it is already equal.
+	updateSpec func(current, desired client.Object) bool
+	// reasons for the audit entries of creating, updating and deleting the resource.
+	createReason string
+	updateReason string
+	deleteReason string
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TokenxIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isTokenxIngress := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == config.Get().TokenxNamespace && obj.GetName() == config.Get().TokenxIngressName
+	})
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&networkv1.NetworkPolicy{}, builder.WithPredicates(isTokenxIngress)).
+		Watches(
+			&accesseratorv1alpha.SecurityConfig{},
+			eventhandler.HandleSecurityConfigEventForTokenxIngress(),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		)
+	// The AuthorizationPolicy is only watched with the istio egress backend, so the Istio CRDs are not required
+	// otherwise.
+	if config.Get().EgressBackend == config.EgressBackendIstio {
+		b = b.Watches(
+			newUnstructured(ingress.AuthorizationPolicyGVK),
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(isTokenxIngress),
+		)
+	}
+	return b.
+		Named("tokenxingress").
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=accesserator.kartverket.no,resources=securityconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=security.istio.io,resources=authorizationpolicies,verbs=get;list;watch;create;update;patch;delete
+
+func (r *TokenxIngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rlog := log.GetLogger(ctx)
+	rlog.Info("Reconciling Tokendings ingress")
+
+	cfg := config.Get()
+	var securityConfigs []accesseratorv1alpha.SecurityConfig
+	if cfg.TokenxIngressEnabled || cfg.EgressBackend == config.EgressBackendIstio {
+		var securityConfigList accesseratorv1alpha.SecurityConfigList
+		if err := r.List(ctx, &securityConfigList); err != nil {
+			rlog.Error(err, "failed to list SecurityConfig resources")
+			return reconcile.Result{}, err
+		}
+		securityConfigs = securityConfigList.Items
+	}
+	objectMeta := metav1.ObjectMeta{Name: req.Name, Namespace: req.Namespace}
+
+	networkPolicy := tokenxIngressResource{
+		kind:    "NetworkPolicy",
+		current: &networkv1.NetworkPolicy{},
+		updateSpec: func(current, desired client.Object) bool {
+			currentNetworkPolicy := current.(*networkv1.NetworkPolicy)
+			desiredNetworkPolicy := desired.(*networkv1.NetworkPolicy)
+			if equality.Semantic.DeepEqual(currentNetworkPolicy.Spec, desiredNetworkPolicy.Spec) {
+				return false
+			}
+			currentNetworkPolicy.Spec = desiredNetworkPolicy.Spec
+			return true
+		},
+		createReason: "Allow ingress to Tokendings from applications with TokenX enabled",
+		updateReason: "Applications with TokenX enabled changed",
+		deleteReason: "No SecurityConfig with TokenX enabled, or Tokendings ingress disabled",
+	}
+	if cfg.TokenxIngressEnabled {
+		if desired := ingress.GetDesired(objectMeta, securityConfigs); desired != nil {
+			networkPolicy.desired = desired
+		}
+	}
+
+	authorizationPolicy := tokenxIngressResource{
+		kind:    "AuthorizationPolicy",
+		current: newUnstructured(ingress.AuthorizationPolicyGVK),
+		updateSpec: func(current, desired client.Object) bool {
+			currentAuthorizationPolicy := current.(*unstructured.Unstructured)
+			desiredAuthorizationPolicy := desired.(*unstructured.Unstructured)
+			if equality.Semantic.DeepEqual(currentAuthorizationPolicy.Object["spec"], desiredAuthorizationPolicy.Object["spec"]) {
+				return false
+			}
+			currentAuthorizationPolicy.Object["spec"] = desiredAuthorizationPolicy.Object["spec"]
+			return true
+		},
+		createReason: "Allow applications with TokenX enabled to reach Tokendings in the Istio mesh",
+		updateReason: "Applications with TokenX enabled changed",
+		deleteReason: "No SecurityConfig with TokenX enabled, or egress backend is not istio",
+	}
+	if desired := ingress.GetDesiredAuthorizationPolicy(objectMeta, securityConfigs); desired != nil {
+		authorizationPolicy.desired = desired
+	}
+
+	for _, resource := range []tokenxIngressResource{networkPolicy, authorizationPolicy} {
+		if err := r.reconcileResource(ctx, req.NamespacedName, resource); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+func (r *TokenxIngressReconciler) reconcileResource(
+	ctx context.Context,
+	name types.NamespacedName,
+	resource tokenxIngressResource,
+) error {
+	ctx = log.WithDescendant(ctx, resource.kind, name.Name)
+	rlog := log.GetLogger(ctx)
+
+	current := resource.current
+	if err := r.Get(ctx, name, current); err != nil {
+		// A missing CRD means there is nothing to delete, e.g. an AuthorizationPolicy without Istio installed.
+		if !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			rlog.Error(err, "failed to get "+resource.kind, "name", name)
+			return err
+		}
+		current = nil
+	}
+	desired := resource.desired
+
+	switch {
+	case current != nil && !utilities.IsManagedByAccesserator(current):
+		// Never touch a resource created by someone else, even if it has the configured name.
+		if desired != nil {
+			rlog.Warning(resource.kind + " is not managed by Accesserator and will not be updated")
+			r.Recorder.Eventf(
+				current,
+				nil,
+				corev1.EventTypeWarning,
+				EventReasonNotManagedByAccesserator,
+				EventActionSkipUpdate,
+				"%s %s is not labeled %s=%s and will not be updated by Accesserator.",
+				resource.kind,
+				name,
+				utilities.ManagedByLabelKey,
+				utilities.ManagedByLabelValue,
+			)
+		}
+		return nil
+
+	case desired == nil && current == nil:
+		return nil
+
+	case desired == nil:
+		rlog.Info("Deleting " + resource.kind + " as it's no longer desired")
+		if err := r.Delete(ctx, current); err != nil && !apierrors.IsNotFound(err) {
+			rlog.Error(err, "failed to delete "+resource.kind, "name", name)
+			return err
+		}
+		audit.Record(ctx, audit.Entry{
+			Action: audit.ActionDelete,
+			Actor:  audit.ActorAccesserator,
+			Object: audit.GetObjectRef(current, r.Scheme),
+			Reason: resource.deleteReason,
+			Before: audit.GetSpec(current),
+		})
+		return nil
+
+	case current == nil:
+		rlog.Info("Creating " + resource.kind)
+		if err := r.Create(ctx, desired); err != nil {
+			rlog.Error(err, "failed to create "+resource.kind, "name", name)
+			return err
+		}
+		audit.Record(ctx, audit.Entry{
+			Action: audit.ActionCreate,
+			Actor:  audit.ActorAccesserator,
+			Object: audit.GetObjectRef(desired, r.Scheme),
+			Reason: resource.createReason,
+			After:  audit.GetSpec(desired),
+		})
+		return nil
+	}
+
+	before := current.DeepCopyObject().(client.Object)
+	if !resource.updateSpec(current, desired) {
+		return nil
+	}
+	rlog.Info("Updating " + resource.kind)
+	if err := r.Patch(ctx, current, client.MergeFrom(before)); err != nil {
+		rlog.Error(err, "failed to patch "+resource.kind, "name", name)
+		return err
+	}
+	audit.Record(ctx, audit.Entry{
+		Action: audit.ActionUpdate,
+		Actor:  audit.ActorAccesserator,
+		Object: audit.GetObjectRef(current, r.Scheme),
+		Reason: resource.updateReason,
+		Before: audit.GetSpec(before),
+		After:  audit.GetSpec(current),
+	})
+	return nil
+}
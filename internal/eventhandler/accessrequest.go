@@ -0,0 +1,122 @@
+package eventhandler
+
+import (
+	"context"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/fieldindex"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HandleAccessRequestEvent enqueues the SecurityConfig targeted by an AccessRequest.
+func HandleAccessRequestEvent() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		accessRequest, ok := obj.(*v1alpha.AccessRequest)
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{
+			NamespacedName: types.NamespacedName{
+				Namespace: accessRequest.Spec.Target.Namespace,
+				Name:      accessRequest.Spec.Target.Name,
+			},
+		}}
+	})
+}
+
+// HandleAccessApprovalEvent enqueues the SecurityConfig that an AccessApproval approves access to.
+func HandleAccessApprovalEvent() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		accessApproval, ok := obj.(*v1alpha.AccessApproval)
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{
+			NamespacedName: types.NamespacedName{
+				Namespace: accessApproval.Namespace,
+				Name:      accessApproval.Spec.SecurityConfigRef,
+			},
+		}}
+	})
+}
+
+// HandleAccessApprovalEventForAccessRequest enqueues the AccessRequest referenced by an AccessApproval.
+func HandleAccessApprovalEventForAccessRequest() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		accessApproval, ok := obj.(*v1alpha.AccessApproval)
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{
+			NamespacedName: types.NamespacedName{
+				Namespace: accessApproval.Spec.AccessRequest.Namespace,
+				Name:      accessApproval.Spec.AccessRequest.Name,
+			},
+		}}
+	})
+}
+
+// HandleSecurityConfigEventForAccessRequest enqueues the AccessRequests targeting a SecurityConfig.
+func HandleSecurityConfigEventForAccessRequest(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		securityConfig, ok := obj.(*v1alpha.SecurityConfig)
+		if !ok {
+			return nil
+		}
+
+		var accessRequestList v1alpha.AccessRequestList
+		if err := c.List(
+			ctx,
+			&accessRequestList,
+			client.MatchingFields{fieldindex.AccessRequestTarget: fieldindex.Key(securityConfig.Namespace, securityConfig.Name)},
+		); err != nil {
+			return nil
+		}
+
+		reqs := make([]reconcile.Request, 0, len(accessRequestList.Items))
+		for _, accessRequest := range accessRequestList.Items {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: accessRequest.GetNamespace(),
+					Name:      accessRequest.GetName(),
+				},
+			})
+		}
+		return reqs
+	})
+}
+
+// HandleSkiperatorApplicationEventForAccessRequest enqueues the AccessRequests made on behalf of an Application.
+func HandleSkiperatorApplicationEventForAccessRequest(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		skiperatorApp, ok := obj.(*v1alpha1.Application)
+		if !ok {
+			return nil
+		}
+
+		var accessRequestList v1alpha.AccessRequestList
+		if err := c.List(
+			ctx,
+			&accessRequestList,
+			client.InNamespace(skiperatorApp.Namespace),
+			client.MatchingFields{fieldindex.AccessRequestApplicationRef: skiperatorApp.Name},
+		); err != nil {
+			return nil
+		}
+
+		reqs := make([]reconcile.Request, 0, len(accessRequestList.Items))
+		for _, accessRequest := range accessRequestList.Items {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: accessRequest.GetNamespace(),
+					Name:      accessRequest.GetName(),
+				},
+			})
+		}
+		return reqs
+	})
+}
@@ -0,0 +1,81 @@
+package eventhandler
+
+import (
+	"context"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/resolver"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HandleSecurityConfigEvent enqueues the SecurityConfigs of the counterparts of the application referenced by a
+// SecurityConfig, since enabling or disabling TokenX changes whether their access policy rules are one-sided. The other
+// SecurityConfigs referencing the same application are enqueued too, since they are invalid while they conflict.
+func HandleSecurityConfigEvent(c client.Client) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		securityConfig, ok := obj.(*v1alpha.SecurityConfig)
+		if !ok {
+			return nil
+		}
+		return append(
+			getConflictingRequests(ctx, c, securityConfig),
+			getCounterpartRequests(
+				ctx,
+				c,
+				accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace},
+			)...,
+		)
+	})
+}
+
+func getConflictingRequests(ctx context.Context, c client.Client, securityConfig *v1alpha.SecurityConfig) []reconcile.Request {
+	securityConfigs, err := resolver.GetSecurityConfigs(
+		ctx,
+		c,
+		accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace},
+	)
+	if err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, other := range securityConfigs {
+		if other.Name != securityConfig.Name {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: other.GetNamespace(),
+					Name:      other.GetName(),
+				},
+			})
+		}
+	}
+	return reqs
+}
+
+func getCounterpartRequests(ctx context.Context, c client.Client, workload accesspolicy.Workload) []reconcile.Request {
+	counterparts, err := resolver.GetCounterparts(ctx, c, workload)
+	if err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, counterpart := range counterparts {
+		securityConfigs, err := resolver.GetSecurityConfigs(ctx, c, counterpart)
+		if err != nil {
+			return nil
+		}
+		for _, securityConfig := range securityConfigs {
+			reqs = append(reqs, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: securityConfig.GetNamespace(),
+					Name:      securityConfig.GetName(),
+				},
+			})
+		}
+	}
+	return reqs
+}
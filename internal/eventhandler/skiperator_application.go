@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
 	"github.com/kartverket/skiperator/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,6 +34,12 @@ func HandleSkiperatorApplicationEvent(c client.Client) handler.EventHandler {
 			})
 		}
 
+		// The access policy of this Application decides whether the rules of its counterparts are one-sided.
+		reqs = append(
+			reqs,
+			getCounterpartRequests(ctx, c, accesspolicy.Workload{Name: skiperatorApp.Name, Namespace: skiperatorApp.Namespace})...,
+		)
+
 		return reqs
 	})
 }
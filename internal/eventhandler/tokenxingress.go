@@ -0,0 +1,24 @@
+package eventhandler
+
+import (
+	"context"
+
+	"github.com/kartverket/accesserator/pkg/config"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// HandleSecurityConfigEventForTokenxIngress enqueues the NetworkPolicy and AuthorizationPolicy allowing ingress to
+// Tokendings, which aggregate all SecurityConfigs with TokenX enabled.
+func HandleSecurityConfigEventForTokenxIngress() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{
+			NamespacedName: types.NamespacedName{
+				Namespace: config.Get().TokenxNamespace,
+				Name:      config.Get().TokenxIngressName,
+			},
+		}}
+	})
+}
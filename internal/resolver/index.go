@@ -0,0 +1,222 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/accessrequest"
+	"github.com/kartverket/accesserator/pkg/fieldindex"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The lookups in this file use the field indexes of the fieldindex package, so the client must be backed by a cache
+// with those indexes, like the client of the manager.
+
+// GetAccessPolicyIndexFor builds an index of the access policies of the application of the SecurityConfig and of the
+// applications its access policy refers to, which is all the index needs to tell whether its rules are one-sided.
+// The SecurityConfig is used as it is rather than as it is in the cache, which may not have its latest changes yet.
+func GetAccessPolicyIndexFor(
+	ctx context.Context,
+	k8sClient client.Client,
+	securityConfig v1alpha.SecurityConfig,
+) (*accesspolicy.Index, error) {
+	l := newIndexLoader(k8sClient, &securityConfig)
+	rules, err := l.load(ctx, accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace})
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if _, err := l.load(ctx, rule.Peer); err != nil {
+			return nil, err
+		}
+	}
+	return l.getIndex(), nil
+}
+
+// GetCounterparts returns the workloads that either are referenced by the access policy of w, or that reference w in
+// their own access policy, including the rules of approved AccessRequests.
+func GetCounterparts(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) ([]accesspolicy.Workload, error) {
+	l := newIndexLoader(k8sClient, nil)
+	if _, err := l.load(ctx, w); err != nil {
+		return nil, err
+	}
+	referencing, err := getReferencingWorkloads(ctx, k8sClient, w)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range referencing {
+		if _, err := l.load(ctx, other); err != nil {
+			return nil, err
+		}
+	}
+	return l.getIndex().GetCounterparts(w), nil
+}
+
+// GetSecurityConfigs returns the SecurityConfigs referencing the application of the workload.
+func GetSecurityConfigs(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) ([]v1alpha.SecurityConfig, error) {
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(
+		ctx,
+		&securityConfigList,
+		client.InNamespace(w.Namespace),
+		client.MatchingFields{fieldindex.SecurityConfigApplicationRef: w.Name},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources for application %s: %w", w, err)
+	}
+	return securityConfigList.Items, nil
+}
+
+// getReferencingWorkloads returns the workloads that may refer to w in their access policy: the ones whose Application
+// or SecurityConfig has a rule for w, and the targets of the AccessRequests of w. Whether an AccessRequest is approved
+// is left to the index.
+func getReferencingWorkloads(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) ([]accesspolicy.Workload, error) {
+	key := fieldindex.Key(w.Namespace, w.Name)
+	var workloads []accesspolicy.Workload
+
+	var applicationList v1alpha1.ApplicationList
+	if err := k8sClient.List(ctx, &applicationList, client.MatchingFields{fieldindex.ApplicationPeers: key}); err != nil {
+		return nil, fmt.Errorf("failed to list Application resources referencing %s: %w", w, err)
+	}
+	for _, application := range applicationList.Items {
+		workloads = append(workloads, accesspolicy.Workload{Name: application.Name, Namespace: application.Namespace})
+	}
+
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList, client.MatchingFields{fieldindex.SecurityConfigPeers: key}); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources referencing %s: %w", w, err)
+	}
+	for _, securityConfig := range securityConfigList.Items {
+		workloads = append(workloads, accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace})
+	}
+
+	var accessRequestList v1alpha.AccessRequestList
+	if err := k8sClient.List(
+		ctx,
+		&accessRequestList,
+		client.InNamespace(w.Namespace),
+		client.MatchingFields{fieldindex.AccessRequestApplicationRef: w.Name},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list AccessRequest resources of %s: %w", w, err)
+	}
+	for _, accessRequest := range accessRequestList.Items {
+		var target v1alpha.SecurityConfig
+		if err := k8sClient.Get(ctx, types.NamespacedName{
+			Name:      accessRequest.Spec.Target.Name,
+			Namespace: accessRequest.Spec.Target.Namespace,
+		}, &target); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to fetch SecurityConfig resource named %s: %w", accessRequest.Spec.Target.Name, err)
+		}
+		workloads = append(workloads, accesspolicy.Workload{Name: target.Spec.ApplicationRef, Namespace: target.Namespace})
+	}
+	return workloads, nil
+}
+
+// indexLoader collects the Applications and SecurityConfigs of the workloads an index is built from.
+type indexLoader struct {
+	k8sClient client.Client
+	// securityConfig replaces the cached copy of the same SecurityConfig if set.
+	securityConfig  *v1alpha.SecurityConfig
+	applications    []v1alpha1.Application
+	securityConfigs []v1alpha.SecurityConfig
+	rules           map[accesspolicy.Workload][]accesspolicy.Rule
+}
+
+func newIndexLoader(k8sClient client.Client, securityConfig *v1alpha.SecurityConfig) *indexLoader {
+	return &indexLoader{
+		k8sClient:      k8sClient,
+		securityConfig: securityConfig,
+		rules:          map[accesspolicy.Workload][]accesspolicy.Rule{},
+	}
+}
+
+// load adds the Application and the SecurityConfigs of the workload once, and returns the access policy rules of the
+// workload.
+func (l *indexLoader) load(ctx context.Context, w accesspolicy.Workload) ([]accesspolicy.Rule, error) {
+	if rules, loaded := l.rules[w]; loaded {
+		return rules, nil
+	}
+
+	var rules []accesspolicy.Rule
+	var application v1alpha1.Application
+	if err := l.k8sClient.Get(ctx, types.NamespacedName{Name: w.Name, Namespace: w.Namespace}, &application); err == nil {
+		l.applications = append(l.applications, application)
+		rules = accesspolicy.GetRules(w, application.Spec.AccessPolicy)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to fetch Application resource named %s: %w", w.Name, err)
+	}
+
+	securityConfigs, err := GetSecurityConfigs(ctx, l.k8sClient, w)
+	if err != nil {
+		return nil, err
+	}
+	if l.securityConfig != nil && l.securityConfig.Namespace == w.Namespace && l.securityConfig.Spec.ApplicationRef == w.Name {
+		securityConfigs = l.withSecurityConfig(securityConfigs)
+	}
+	for _, securityConfig := range securityConfigs {
+		if securityConfig.Spec.Tokenx == nil || !securityConfig.Spec.Tokenx.Enabled {
+			continue
+		}
+		withRequests, err := withApprovedAccessRequests(ctx, l.k8sClient, securityConfig)
+		if err != nil {
+			return nil, err
+		}
+		l.securityConfigs = append(l.securityConfigs, withRequests)
+		rules = append(rules, accesspolicy.GetSecurityConfigRules(w, withRequests.Spec.Tokenx.AccessPolicy)...)
+	}
+
+	l.rules[w] = rules
+	return rules, nil
+}
+
+// withSecurityConfig replaces the cached copy of the SecurityConfig of the loader, or adds it if it is not cached yet.
+func (l *indexLoader) withSecurityConfig(securityConfigs []v1alpha.SecurityConfig) []v1alpha.SecurityConfig {
+	replaced := make([]v1alpha.SecurityConfig, 0, len(securityConfigs)+1)
+	for _, securityConfig := range securityConfigs {
+		if securityConfig.Name != l.securityConfig.Name {
+			replaced = append(replaced, securityConfig)
+		}
+	}
+	return append(replaced, *l.securityConfig)
+}
+
+func (l *indexLoader) getIndex() *accesspolicy.Index {
+	return accesspolicy.NewIndex(l.applications, l.securityConfigs)
+}
+
+// withApprovedAccessRequests returns a copy of the SecurityConfig whose TokenX access policy includes the inbound rules
+// of the approved AccessRequests targeting it.
+func withApprovedAccessRequests(
+	ctx context.Context,
+	k8sClient client.Client,
+	securityConfig v1alpha.SecurityConfig,
+) (v1alpha.SecurityConfig, error) {
+	var accessRequestList v1alpha.AccessRequestList
+	if err := k8sClient.List(
+		ctx,
+		&accessRequestList,
+		client.MatchingFields{fieldindex.AccessRequestTarget: fieldindex.Key(securityConfig.Namespace, securityConfig.Name)},
+	); err != nil {
+		return securityConfig, fmt.Errorf("failed to list AccessRequest resources targeting %s: %w", securityConfig.Name, err)
+	}
+	if len(accessRequestList.Items) == 0 {
+		return securityConfig, nil
+	}
+	var accessApprovalList v1alpha.AccessApprovalList
+	if err := k8sClient.List(
+		ctx,
+		&accessApprovalList,
+		client.InNamespace(securityConfig.Namespace),
+		client.MatchingFields{fieldindex.AccessApprovalSecurityConfigRef: securityConfig.Name},
+	); err != nil {
+		return securityConfig, fmt.Errorf("failed to list AccessApproval resources of %s: %w", securityConfig.Name, err)
+	}
+	return accessrequest.WithApprovedAccessRequests(securityConfig, accessRequestList.Items, accessApprovalList.Items), nil
+}
@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getWorkloadApplication(name, namespace string, rules ...podtypes.InternalRule) *v1alpha1.Application {
+	return &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1alpha1.ApplicationSpec{AccessPolicy: &podtypes.AccessPolicy{
+			Outbound: podtypes.OutboundPolicy{Rules: rules},
+		}},
+	}
+}
+
+func getWorkloadSecurityConfig(name, namespace string, inbound ...v1alpha.AccessPolicyRule) *v1alpha.SecurityConfig {
+	return &v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: name,
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: true, AccessPolicy: &v1alpha.AccessPolicy{Inbound: inbound}},
+		},
+	}
+}
+
+func getApprovedAccessRequest() (*v1alpha.AccessRequest, *v1alpha.AccessApproval) {
+	accessRequest := &v1alpha.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "caller-ns", Generation: 1},
+		Spec: v1alpha.AccessRequestSpec{
+			ApplicationRef: "caller",
+			Target:         v1alpha.SecurityConfigReference{Name: "target", Namespace: "target-ns"},
+		},
+		Status: v1alpha.AccessRequestStatus{ObservedGeneration: 1, Phase: v1alpha.AccessRequestPhaseApproved},
+	}
+	accessApproval := &v1alpha.AccessApproval{
+		ObjectMeta: metav1.ObjectMeta{Name: "approval", Namespace: "target-ns"},
+		Spec: v1alpha.AccessApprovalSpec{
+			AccessRequest:     v1alpha.AccessRequestReference{Name: "request", Namespace: "caller-ns", Generation: 1},
+			Application:       "caller",
+			SecurityConfigRef: "target",
+		},
+	}
+	return accessRequest, accessApproval
+}
+
+func TestGetAccessPolicyIndexFor(t *testing.T) {
+	caller := accesspolicy.Workload{Name: "caller", Namespace: "caller-ns"}
+	callerPolicy := &podtypes.AccessPolicy{Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{
+		{Application: "target", Namespace: "target-ns"},
+	}}}
+	objects := []client.Object{
+		getWorkloadApplication("caller", "caller-ns", callerPolicy.Outbound.Rules...),
+		getWorkloadSecurityConfig("caller", "caller-ns"),
+		getWorkloadApplication("target", "target-ns"),
+		getWorkloadSecurityConfig("target", "target-ns"),
+		getWorkloadApplication("unrelated", "other-ns"),
+	}
+
+	t.Run("only includes the related applications", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), objects...)
+		index, err := GetAccessPolicyIndexFor(context.Background(), k8sClient, *getWorkloadSecurityConfig("caller", "caller-ns"))
+		require.NoError(t, err)
+		assert.Equal(t, []accesspolicy.Workload{caller, {Name: "target", Namespace: "target-ns"}}, index.Workloads())
+		assert.Len(t, index.GetOneSidedRules(caller, callerPolicy), 1)
+	})
+
+	t.Run("includes the approved AccessRequests of the peers", func(t *testing.T) {
+		accessRequest, accessApproval := getApprovedAccessRequest()
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), append(objects, accessRequest, accessApproval)...)
+		index, err := GetAccessPolicyIndexFor(context.Background(), k8sClient, *getWorkloadSecurityConfig("caller", "caller-ns"))
+		require.NoError(t, err)
+		assert.Empty(t, index.GetOneSidedRules(caller, callerPolicy))
+	})
+
+	t.Run("uses the SecurityConfig as it is given", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), objects...)
+		updated := getWorkloadSecurityConfig("caller", "caller-ns", v1alpha.AccessPolicyRule{Application: "new"})
+		index, err := GetAccessPolicyIndexFor(context.Background(), k8sClient, *updated)
+		require.NoError(t, err)
+		assert.Equal(t, updated.Spec.Tokenx.AccessPolicy, index.GetSecurityConfigAccessPolicy(caller))
+	})
+}
+
+func TestGetCounterparts(t *testing.T) {
+	target := accesspolicy.Workload{Name: "target", Namespace: "target-ns"}
+
+	t.Run("finds the applications referring to the workload", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(
+			getScheme(t),
+			getWorkloadApplication("caller", "caller-ns", podtypes.InternalRule{Application: "target", Namespace: "target-ns"}),
+			getWorkloadApplication("target", "target-ns"),
+			getWorkloadApplication("other", "target-ns"),
+			getWorkloadSecurityConfig("other", "target-ns", v1alpha.AccessPolicyRule{Application: "target"}),
+			getWorkloadApplication("unrelated", "other-ns"),
+		)
+		counterparts, err := GetCounterparts(context.Background(), k8sClient, target)
+		require.NoError(t, err)
+		assert.Equal(t, []accesspolicy.Workload{{Name: "caller", Namespace: "caller-ns"}, {Name: "other", Namespace: "target-ns"}}, counterparts)
+	})
+
+	t.Run("finds the applications with approved AccessRequests", func(t *testing.T) {
+		accessRequest, accessApproval := getApprovedAccessRequest()
+		k8sClient := utilities.GetMockKubernetesClient(
+			getScheme(t),
+			getWorkloadApplication("caller", "caller-ns"),
+			getWorkloadApplication("target", "target-ns"),
+			getWorkloadSecurityConfig("target", "target-ns"),
+			accessRequest,
+			accessApproval,
+		)
+		caller := accesspolicy.Workload{Name: "caller", Namespace: "caller-ns"}
+		counterparts, err := GetCounterparts(context.Background(), k8sClient, target)
+		require.NoError(t, err)
+		assert.Equal(t, []accesspolicy.Workload{caller}, counterparts)
+
+		counterparts, err = GetCounterparts(context.Background(), k8sClient, caller)
+		require.NoError(t, err)
+		assert.Equal(t, []accesspolicy.Workload{target}, counterparts)
+	})
+}
@@ -6,47 +6,116 @@ import (
 
 	"github.com/kartverket/accesserator/api/v1alpha"
 	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/accessrequest"
+	"github.com/kartverket/accesserator/pkg/capability"
+	// Importing tokenx also registers the built-in capabilities, so every user of the resolver has them.
+	"github.com/kartverket/accesserator/pkg/capability/tokenx"
+	"github.com/kartverket/accesserator/pkg/tracing"
+	"github.com/kartverket/accesserator/pkg/utilities"
 	"github.com/kartverket/skiperator/api/v1alpha1"
-	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ResolveSecurityConfig validates the SecurityConfig and resolves the state of its enabled capabilities. A SecurityConfig
+// whose Application does not exist is resolved to a scope with Orphaned set, and one that fails validation to a scope
+// with InvalidConfig set, both without the state of its capabilities. An error is only returned when the SecurityConfig
+// could not be resolved.
 func ResolveSecurityConfig(ctx context.Context, k8sClient client.Client, securityConfig v1alpha.SecurityConfig) (*state.Scope, error) {
-	tokenXEnabled := securityConfig.Spec.Tokenx != nil && securityConfig.Spec.Tokenx.Enabled
-	if !tokenXEnabled {
-		return &state.Scope{
-			SecurityConfig: securityConfig,
-			TokenXConfig: state.TokenXConfig{
-				Enabled: tokenXEnabled,
-			},
-		}, nil
-	}
-
-	var skiperatorApplication v1alpha1.Application
-	if exists := k8sClient.Get(ctx, types.NamespacedName{
+	return ResolveSecurityConfigWithIndex(ctx, k8sClient, securityConfig, nil)
+}
+
+// ResolveSecurityConfigWithIndex resolves a SecurityConfig using an already built access policy index, which must
+// include the SecurityConfig. If accessPolicyIndex is nil, an index of the applications related to the SecurityConfig
+// is built with GetAccessPolicyIndexFor.
+func ResolveSecurityConfigWithIndex(
+	ctx context.Context,
+	k8sClient client.Client,
+	securityConfig v1alpha.SecurityConfig,
+	accessPolicyIndex *accesspolicy.Index,
+) (*state.Scope, error) {
+	ctx, span := tracing.Start(ctx, "ResolveSecurityConfig", tracing.SecurityConfigAttributes(securityConfig)...)
+	scope, err := resolveSecurityConfig(ctx, k8sClient, securityConfig, accessPolicyIndex)
+	tracing.End(span, err)
+	return scope, err
+}
+
+func resolveSecurityConfig(
+	ctx context.Context,
+	k8sClient client.Client,
+	securityConfig v1alpha.SecurityConfig,
+	accessPolicyIndex *accesspolicy.Index,
+) (*state.Scope, error) {
+	scope := &state.Scope{SecurityConfig: securityConfig}
+	// The Jwkers named after a previous applicationRef are deleted in every state of the SecurityConfig, so they are
+	// resolved before the Application is.
+	staleJwkerNames, err := tokenx.GetStaleJwkerNames(ctx, k8sClient, securityConfig)
+	if err != nil {
+		return nil, err
+	}
+	scope.TokenXConfig.StaleJwkerNames = staleJwkerNames
+
+	var application v1alpha1.Application
+	if err := k8sClient.Get(ctx, types.NamespacedName{
 		Name:      securityConfig.Spec.ApplicationRef,
 		Namespace: securityConfig.Namespace,
-	}, &skiperatorApplication); exists != nil {
-		return nil, fmt.Errorf(
-			"failed to fetch Application resource named %s: %w",
-			securityConfig.Spec.ApplicationRef,
-			exists,
-		)
+	}, &application); err != nil {
+		if apierrors.IsNotFound(err) {
+			scope.Orphaned = true
+			return scope, nil
+		}
+		return nil, fmt.Errorf("failed to fetch Application resource named %s: %w", securityConfig.Spec.ApplicationRef, err)
+	}
+
+	validationErrs, err := validateSecurityConfig(ctx, k8sClient, securityConfig, application)
+	if err != nil {
+		return nil, err
+	}
+	if len(validationErrs) > 0 {
+		scope.InvalidConfig = true
+		scope.ValidationErrorMessage = utilities.Ptr(validationErrs.ToAggregate().Error())
+		return scope, nil
 	}
 
-	var skiperatorAccessPolicy *podtypes.AccessPolicy
-	if skiperatorApplication.Spec.AccessPolicy != nil {
-		skiperatorAccessPolicy = skiperatorApplication.Spec.AccessPolicy
-	} else {
-		skiperatorAccessPolicy = nil
+	for _, c := range capability.Enabled(securityConfig) {
+		if accessPolicyIndex == nil {
+			index, err := GetAccessPolicyIndexFor(ctx, k8sClient, securityConfig)
+			if err != nil {
+				return nil, err
+			}
+			accessPolicyIndex = index
+		}
+		if err := c.Resolve(ctx, k8sClient, scope, accessPolicyIndex); err != nil {
+			return nil, err
+		}
 	}
+	return scope, nil
+}
 
-	return &state.Scope{
-		SecurityConfig: securityConfig,
-		TokenXConfig: state.TokenXConfig{
-			Enabled:      tokenXEnabled,
-			AccessPolicy: skiperatorAccessPolicy,
-		},
-	}, nil
+// GetAccessPolicyIndex builds an index of the access policies of all Applications and SecurityConfigs in the cluster.
+// It lists every object in the cluster, so it is meant for resolving all SecurityConfigs at once, e.g. for the access
+// graph, and works with clients without the field indexes.
+func GetAccessPolicyIndex(ctx context.Context, k8sClient client.Client) (*accesspolicy.Index, error) {
+	var applicationList v1alpha1.ApplicationList
+	if err := k8sClient.List(ctx, &applicationList); err != nil {
+		return nil, fmt.Errorf("failed to list Application resources: %w", err)
+	}
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources: %w", err)
+	}
+	accessRequests, accessApprovals, err := accessrequest.List(ctx, k8sClient, "")
+	if err != nil {
+		return nil, err
+	}
+	securityConfigs := make([]v1alpha.SecurityConfig, 0, len(securityConfigList.Items))
+	for _, securityConfig := range securityConfigList.Items {
+		securityConfigs = append(
+			securityConfigs,
+			accessrequest.WithApprovedAccessRequests(securityConfig, accessRequests, accessApprovals),
+		)
+	}
+	return accesspolicy.NewIndex(applicationList.Items, securityConfigs), nil
 }
@@ -0,0 +1,106 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// validateSecurityConfig returns the problems with the SecurityConfig that will not go away without changing it, its
+// Application or the other SecurityConfigs in its namespace. The error is only returned when the validation itself
+// fails, e.g. because the API server is unavailable.
+func validateSecurityConfig(
+	ctx context.Context,
+	k8sClient client.Client,
+	securityConfig v1alpha.SecurityConfig,
+	application v1alpha1.Application,
+) (field.ErrorList, error) {
+	specPath := field.NewPath("spec")
+	allErrs := validateAccessPolicy(securityConfig, specPath.Child("tokenx", "accessPolicy"))
+
+	applicationRefPath := specPath.Child("applicationRef")
+	// The applicationRef is the name of the Jwker, which is not truncated like the other generated names since it is part
+	// of the TokenX client ID, and it ends up in labels.
+	for _, msg := range validation.IsDNS1123Label(securityConfig.Spec.ApplicationRef) {
+		allErrs = append(allErrs, field.Invalid(applicationRefPath, securityConfig.Spec.ApplicationRef, msg))
+	}
+	if application.Labels[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue {
+		allErrs = append(allErrs, field.Invalid(
+			applicationRefPath,
+			securityConfig.Spec.ApplicationRef,
+			fmt.Sprintf(
+				"Application is not labelled %s=%s, so its pods are not mutated",
+				utilities.SecurityEnabledLabelName,
+				utilities.SecurityEnabledLabelValue,
+			),
+		))
+	}
+
+	// The webhook rejects the pods of an application referenced by more than one SecurityConfig, so all of them are
+	// invalid until only one is left.
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList, client.InNamespace(securityConfig.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources: %w", err)
+	}
+	var others []v1alpha.SecurityConfig
+	for _, other := range securityConfigList.Items {
+		if other.Name == securityConfig.Name {
+			continue
+		}
+		if other.Spec.ApplicationRef == securityConfig.Spec.ApplicationRef {
+			allErrs = append(allErrs, field.Invalid(
+				applicationRefPath,
+				securityConfig.Spec.ApplicationRef,
+				fmt.Sprintf("Application is also referenced by SecurityConfig %s", other.Name),
+			))
+			continue
+		}
+		others = append(others, other)
+	}
+
+	// Generated resources with the same name would be taken over by whichever SecurityConfig is reconciled last.
+	for _, collision := range naming.GetCollisions(securityConfig, others) {
+		value := securityConfig.Name
+		if collision.Path.String() == applicationRefPath.String() {
+			value = securityConfig.Spec.ApplicationRef
+		}
+		allErrs = append(allErrs, field.Invalid(
+			collision.Path,
+			value,
+			fmt.Sprintf("generated %s %s is also generated from SecurityConfig %s", collision.Kind, collision.Name, collision.SecurityConfig),
+		))
+	}
+	return allErrs, nil
+}
+
+// validateAccessPolicy checks that the access policy rules of the SecurityConfig refer to valid application and
+// namespace names.
+func validateAccessPolicy(securityConfig v1alpha.SecurityConfig, path *field.Path) field.ErrorList {
+	if securityConfig.Spec.Tokenx == nil || securityConfig.Spec.Tokenx.AccessPolicy == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	validateRules := func(rules []v1alpha.AccessPolicyRule, path *field.Path) {
+		for i, rule := range rules {
+			rulePath := path.Index(i)
+			for _, msg := range validation.IsDNS1123Label(rule.Application) {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("application"), rule.Application, msg))
+			}
+			if rule.Namespace != "" {
+				for _, msg := range validation.IsDNS1123Label(rule.Namespace) {
+					allErrs = append(allErrs, field.Invalid(rulePath.Child("namespace"), rule.Namespace, msg))
+				}
+			}
+		}
+	}
+	validateRules(securityConfig.Spec.Tokenx.AccessPolicy.Inbound, path.Child("inbound"))
+	validateRules(securityConfig.Spec.Tokenx.AccessPolicy.Outbound, path.Child("outbound"))
+	return allErrs
+}
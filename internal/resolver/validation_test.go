@@ -0,0 +1,141 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func getApplication(labels map[string]string) *v1alpha1.Application {
+	return &v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns", Labels: labels}}
+}
+
+func getSecurityConfig(name string, rules ...v1alpha.AccessPolicyRule) *v1alpha.SecurityConfig {
+	return &v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: "app",
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: true, AccessPolicy: &v1alpha.AccessPolicy{Inbound: rules}},
+		},
+	}
+}
+
+func TestValidateSecurityConfig(t *testing.T) {
+	labelled := getApplication(map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue})
+	longApplicationRef := getSecurityConfig("sc")
+	longApplicationRef.Spec.ApplicationRef = strings.Repeat("a", 64)
+
+	tests := []struct {
+		name           string
+		securityConfig *v1alpha.SecurityConfig
+		application    *v1alpha1.Application
+		objects        []client.Object
+		want           []string
+	}{
+		{
+			name:           "valid",
+			securityConfig: getSecurityConfig("sc", v1alpha.AccessPolicyRule{Application: "caller", Namespace: "other"}),
+			application:    labelled,
+		},
+		{
+			name:           "missing security label",
+			securityConfig: getSecurityConfig("sc"),
+			application:    getApplication(nil),
+			want: []string{
+				`spec.applicationRef: Invalid value: "app": Application is not labelled skiperator/security=enabled, so its pods are not mutated`,
+			},
+		},
+		{
+			name:           "conflicting SecurityConfig",
+			securityConfig: getSecurityConfig("sc"),
+			application:    labelled,
+			objects:        []client.Object{getSecurityConfig("other")},
+			want:           []string{`spec.applicationRef: Invalid value: "app": Application is also referenced by SecurityConfig other`},
+		},
+		{
+			name:           "applicationRef too long for a Jwker name",
+			securityConfig: longApplicationRef,
+			application:    labelled,
+			want:           []string{"spec.applicationRef: Invalid value: \"" + strings.Repeat("a", 64) + "\": must be no more than 63 characters"},
+		},
+		{
+			name: "invalid access policy references",
+			securityConfig: getSecurityConfig(
+				"sc",
+				v1alpha.AccessPolicyRule{Application: ""},
+				v1alpha.AccessPolicyRule{Application: "caller", Namespace: "Other_Namespace"},
+			),
+			application: labelled,
+			want: []string{
+				"spec.tokenx.accessPolicy.inbound[0].application",
+				"spec.tokenx.accessPolicy.inbound[1].namespace",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := utilities.GetMockKubernetesClient(getScheme(t), append(tt.objects, tt.securityConfig)...)
+			errs, err := validateSecurityConfig(context.Background(), k8sClient, *tt.securityConfig, *tt.application)
+			require.NoError(t, err)
+			require.Len(t, errs, len(tt.want))
+			for i, want := range tt.want {
+				assert.Contains(t, errs[i].Error(), want)
+			}
+		})
+	}
+}
+
+func TestResolveSecurityConfig(t *testing.T) {
+	securityConfig := getSecurityConfig("sc")
+
+	t.Run("orphaned when the Application does not exist", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), securityConfig)
+		scope, err := ResolveSecurityConfig(context.Background(), k8sClient, *securityConfig)
+		require.NoError(t, err)
+		assert.True(t, scope.Orphaned)
+		assert.False(t, scope.InvalidConfig)
+		assert.False(t, scope.TokenXConfig.Enabled)
+	})
+
+	t.Run("finds the Jwkers named after a previous applicationRef when orphaned", func(t *testing.T) {
+		securityConfig := securityConfig.DeepCopy()
+		securityConfig.UID = "uid"
+		staleJwker := &naisiov1.Jwker{ObjectMeta: metav1.ObjectMeta{Name: "old-app", Namespace: "ns"}}
+		require.NoError(t, controllerutil.SetControllerReference(securityConfig, staleJwker, getScheme(t)))
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), securityConfig, staleJwker)
+		scope, err := ResolveSecurityConfig(context.Background(), k8sClient, *securityConfig)
+		require.NoError(t, err)
+		assert.True(t, scope.Orphaned)
+		assert.Equal(t, []string{"old-app"}, scope.TokenXConfig.StaleJwkerNames)
+	})
+
+	t.Run("invalid when the validation fails", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), securityConfig, getApplication(nil))
+		scope, err := ResolveSecurityConfig(context.Background(), k8sClient, *securityConfig)
+		require.NoError(t, err)
+		assert.True(t, scope.InvalidConfig)
+		assert.Contains(t, *scope.ValidationErrorMessage, "Application is not labelled skiperator/security=enabled")
+		assert.False(t, scope.TokenXConfig.Enabled)
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 
 	"github.com/kartverket/accesserator/api/v1alpha"
-	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/naming"
 	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -19,13 +21,27 @@ type Scope struct {
 	Descendants            []Descendant[client.Object]
 	InvalidConfig          bool
 	ValidationErrorMessage *string
+	// Orphaned is set when the Application referenced by the SecurityConfig does not exist. The capabilities of an
+	// orphaned SecurityConfig are not resolved.
+	Orphaned bool
+
+	// descendantsMu guards Descendants, since the descendants of a SecurityConfig are reconciled concurrently.
+	descendantsMu sync.Mutex
 }
 
 type TokenXConfig struct {
-	Enabled      bool
-	AccessPolicy *podtypes.AccessPolicy
+	Enabled       bool
+	AccessPolicy  *podtypes.AccessPolicy
+	OneSidedRules []accesspolicy.Rule
+	// AccessGrants are the time-bound access policy rules of the SecurityConfig, including expired ones.
+	AccessGrants []accesspolicy.Grant
+	// StaleJwkerNames are the Jwkers controlled by the SecurityConfig that are named after a previous applicationRef.
+	StaleJwkerNames []string
 }
 
+// CapabilityTokenX is the name of the TokenX capability, e.g. in the egress endpoint catalog.
+const CapabilityTokenX = "tokenx"
+
 type Descendant[T client.Object] struct {
 	ID             string
 	Object         T
@@ -45,6 +61,7 @@ func (s *Scope) GetErrors() []string {
 	return errs
 }
 
+// ReplaceDescendant records the result of reconciling a descendant. It is safe for concurrent use.
 func (s *Scope) ReplaceDescendant(
 	obj client.Object,
 	errorMessage *string,
@@ -52,6 +69,8 @@ func (s *Scope) ReplaceDescendant(
 	resourceKind, resourceName string,
 ) {
 	if s != nil {
+		s.descendantsMu.Lock()
+		defer s.descendantsMu.Unlock()
 		for i, d := range s.Descendants {
 			if reflect.TypeOf(d) == reflect.TypeOf(obj) && d.ID == obj.GetName() {
 				s.Descendants[i] = Descendant[client.Object]{
@@ -84,7 +103,7 @@ func (s *Scope) GetJwker(ctx context.Context, k8sClient client.Client) (*nais_io
 	if k8sClient == nil {
 		return nil, fmt.Errorf("k8sClient not configured")
 	}
-	jwkerName := utilities.GetJwkerName(s.SecurityConfig.Spec.ApplicationRef)
+	jwkerName := naming.GetJwkerName(s.SecurityConfig.Spec.ApplicationRef)
 	if err := k8sClient.Get(ctx, types.NamespacedName{
 		Name:      jwkerName,
 		Namespace: s.SecurityConfig.Namespace,
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/kartverket/accesserator/pkg/capability"
+)
+
+const (
+	mutatingWebhookName   = "mutating"
+	validatingWebhookName = "validating"
+
+	skipReasonSecurityNotEnabled = "security_not_enabled"
+
+	rejectionReasonError                   = "error"
+	rejectionReasonInvalidObject           = "invalid_object"
+	rejectionReasonApplicationNotFound     = "application_not_found"
+	rejectionReasonSecurityConfigNotFound  = "securityconfig_not_found"
+	rejectionReasonMultipleSecurityConfigs = "multiple_securityconfigs"
+	rejectionReasonNoCapabilityEnabled     = "no_capability_enabled"
+)
+
+func reject(reason string, err error) error {
+	return capability.Reject(reason, err)
+}
+
+// getRejectionReason returns the reason of a rejection made by the webhook or by a capability, used as a metric label.
+func getRejectionReason(err error) string {
+	var rejection *capability.RejectionError
+	if errors.As(err, &rejection) {
+		return rejection.Reason
+	}
+	return rejectionReasonError
+}
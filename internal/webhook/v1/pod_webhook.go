@@ -3,40 +3,44 @@ package v1
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"strings"
+	"time"
 
 	"github.com/kartverket/accesserator/api/v1alpha"
-	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/audit"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/capability/tokenx"
+	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/accesserator/pkg/metrics"
+	"github.com/kartverket/accesserator/pkg/tracing"
 	"github.com/kartverket/accesserator/pkg/utilities"
 	"github.com/kartverket/skiperator/api/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 const (
-	SkiperatorApplicationRefLabel = "application.skiperator.no/app-name"
-	SecurityEnabledLabelName      = "skiperator/security"
-	SecurityEnabledLabelValue     = "enabled"
+	TexasInitContainerName = tokenx.TexasInitContainerName
+	TexasPortName          = tokenx.TexasPortName
 
-	TexasInitContainerName = "texas"
-	TexasPortName          = "http"
-
-	MaskinportenEnabledEnvVarName = "MASKINPORTEN_ENABLED"
-	AzureEnabledEnvVarName        = "AZURE_ENABLED"
-	IdportenEnabledEnvVarName     = "IDPORTEN_ENABLED"
-	TokenXEnabledEnvVarName       = "TOKEN_X_ENABLED"
+	MaskinportenEnabledEnvVarName = tokenx.MaskinportenEnabledEnvVarName
+	AzureEnabledEnvVarName        = tokenx.AzureEnabledEnvVarName
+	IdportenEnabledEnvVarName     = tokenx.IdportenEnabledEnvVarName
+	TokenXEnabledEnvVarName       = tokenx.TokenXEnabledEnvVarName
 )
 
-// nolint:unused
-// log is for logging in this package.
-var podlog = logf.Log.WithName("pod-webhook")
+// getLogger returns the logger of the admission request in the context.
+func getLogger(ctx context.Context) log.Logger {
+	return log.GetLogger(ctx).WithName("pod-webhook")
+}
 
 // SetupPodWebhookWithManager registers the webhook for Pod in the manager.
 func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
@@ -61,39 +65,56 @@ var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
 
 // Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Pod.
 func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	start := time.Now()
+	ctx = log.WithAdmission(ctx)
+	outcome, reason, err := d.defaultPod(ctx, obj)
+	metrics.RecordPodAdmission(mutatingWebhookName, outcome, reason, time.Since(start))
+	recordRejection(ctx, obj, err)
+	return err
+}
+
+// defaultPod lets the capabilities enabled for the pod mutate it, and returns the outcome and reason of the admission
+// for the metrics.
+func (d *PodCustomDefaulter) defaultPod(ctx context.Context, obj runtime.Object) (string, string, error) {
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
-		return fmt.Errorf("expected an Pod object but got %T", obj)
+		return metrics.AdmissionOutcomeRejected, rejectionReasonInvalidObject, fmt.Errorf("expected an Pod object but got %T", obj)
 	}
 
-	podlog.Info("Defaulting for Pod")
+	rlog := getLogger(ctx)
+	rlog.Info("Defaulting for Pod")
 
 	securityConfigForPod, err := getSecurityConfigForPod(ctx, d.Client, pod)
 	if err != nil {
-		return err
+		return metrics.AdmissionOutcomeRejected, getRejectionReason(err), err
 	}
 	if !securityConfigForPod.SecurityEnabled {
-		return nil
+		return metrics.AdmissionOutcomeSkipped, skipReasonSecurityNotEnabled, nil
 	}
 
-	if securityConfigForPod.SecurityConfig.Spec.Tokenx != nil && securityConfigForPod.SecurityConfig.Spec.Tokenx.Enabled {
-		// TokenX is enabled for this Application
-		// We inject an init container with texas in the pod
-		podlog.Info("Tokenx is enabled, injecting texas init container")
-		pod.Spec.InitContainers = append(pod.Spec.InitContainers, securityConfigForPod.TexasContainer)
-
-		podlog.Info("Injecting texas url")
-		for i := range pod.Spec.Containers {
-			if pod.Spec.Containers[i].Name == securityConfigForPod.AppName {
-				pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, corev1.EnvVar{
-					Name:  config.Get().TexasUrlEnvVarName,
-					Value: getTexasUrlEnvVarValue(),
-				})
-			}
+	securityConfig := securityConfigForPod.SecurityConfig
+	var capabilities []string
+	for _, c := range capability.Enabled(*securityConfig) {
+		rlog.Info("Mutating pod for capability", "capability", c.Name())
+		if err := c.PodMutation(ctx, pod, *securityConfig, securityConfigForPod.AppName); err != nil {
+			return metrics.AdmissionOutcomeRejected, getRejectionReason(err), err
 		}
+		capabilities = append(capabilities, c.Name())
 	}
-
-	return nil
+	// The injection is only recorded once every capability has mutated the pod, so the entry reflects the mutation
+	// that is returned.
+	audit.Record(ctx, audit.Entry{
+		Action: audit.ActionRequestInjection,
+		Actor:  audit.GetRequester(ctx),
+		Object: audit.GetObjectRef(pod, clientgoscheme.Scheme),
+		Reason: fmt.Sprintf(
+			"Capabilities %s enabled by SecurityConfig %s/%s",
+			strings.Join(capabilities, ", "),
+			securityConfig.Namespace,
+			securityConfig.Name,
+		),
+	})
+	return metrics.AdmissionOutcomeInjected, "", nil
 }
 
 // +kubebuilder:webhook:path=/validate--v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=vpod-v1.kb.io,admissionReviewVersions=v1
@@ -117,54 +138,90 @@ func (v *PodCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runti
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Pod.
-func (v *PodCustomValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (v *PodCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
 		return nil, fmt.Errorf("expected a Pod object but got %T", obj)
 	}
-	podlog.Info("Validation for Pod upon deletion", "name", pod.GetName())
+	rlog := getLogger(log.WithAdmission(ctx))
+	rlog.Info("Validation for Pod upon deletion", "name", pod.GetName())
 
 	// Nothing to do
 
 	return nil, nil
 }
 
+// recordRejection records a rejected pod in the audit log.
+func recordRejection(ctx context.Context, obj runtime.Object, err error) {
+	pod, ok := obj.(*corev1.Pod)
+	if err == nil || !ok {
+		return
+	}
+	audit.Record(ctx, audit.Entry{
+		Action: audit.ActionRejectPod,
+		Actor:  audit.GetRequester(ctx),
+		Object: audit.GetObjectRef(pod, clientgoscheme.Scheme),
+		Reason: err.Error(),
+	})
+}
+
 type PodSecurityConfiguration struct {
 	SecurityConfig  *v1alpha.SecurityConfig
 	AppName         string
 	SecurityEnabled bool
-	TexasContainer  corev1.Container
 }
 
 // getSecurityConfigForPod extracts the SecurityConfig for a given pod and determines if security is enabled.
 // Returns PodSecurityConfiguration with SecurityEnabled=false if security is not enabled or not applicable.
 // Returns an error if validation fails (e.g., missing SecurityConfig when security label is present).
 func getSecurityConfigForPod(ctx context.Context, crudClient client.Client, pod *corev1.Pod) (*PodSecurityConfiguration, error) {
+	ctx, span := tracing.Start(
+		ctx,
+		"getSecurityConfigForPod",
+		attribute.String("k8s.namespace.name", pod.Namespace),
+		attribute.String("accesserator.application", pod.Labels[utilities.SkiperatorApplicationRefLabel]),
+	)
+	securityConfigForPod, err := resolveSecurityConfigForPod(ctx, crudClient, pod)
+	if err == nil && securityConfigForPod.SecurityConfig != nil {
+		span.SetAttributes(tracing.SecurityConfigAttributes(*securityConfigForPod.SecurityConfig)...)
+	}
+	tracing.End(span, err)
+	return securityConfigForPod, err
+}
+
+func resolveSecurityConfigForPod(ctx context.Context, crudClient client.Client, pod *corev1.Pod) (*PodSecurityConfiguration, error) {
 	if pod.Labels == nil {
 		return &PodSecurityConfiguration{SecurityEnabled: false}, nil
 	}
-	appName, appNameExists := pod.Labels[SkiperatorApplicationRefLabel]
+	appName, appNameExists := pod.Labels[utilities.SkiperatorApplicationRefLabel]
 	if !appNameExists {
 		return &PodSecurityConfiguration{SecurityEnabled: false}, nil
 	}
 
 	if crudClient == nil {
-		return nil, fmt.Errorf("webhook client is not configured")
+		return nil, reject(rejectionReasonError, fmt.Errorf("webhook client is not configured"))
 	}
 
+	rlog := getLogger(ctx).WithValues(log.ApplicationKey, appName)
 	var skiperatorApplication v1alpha1.Application
-	podlog.Info("Fetching Application resource", "name", appName)
+	rlog.Info("Fetching Application resource")
 	if err := crudClient.Get(ctx, types.NamespacedName{
 		Name:      appName,
 		Namespace: pod.Namespace,
 	}, &skiperatorApplication); err != nil {
 		if apierrors.IsNotFound(err) {
-			return nil, fmt.Errorf("no Application found with the name %s/%s: %w", pod.Namespace, appName, err)
+			return nil, reject(
+				rejectionReasonApplicationNotFound,
+				fmt.Errorf("no Application found with the name %s/%s: %w", pod.Namespace, appName, err),
+			)
 		}
-		return nil, fmt.Errorf("failed to fetch Application resource named %s/%s: %w", pod.Namespace, appName, err)
+		return nil, reject(
+			rejectionReasonError,
+			fmt.Errorf("failed to fetch Application resource named %s/%s: %w", pod.Namespace, appName, err),
+		)
 	}
 
-	if skiperatorApplication.Labels[SecurityEnabledLabelName] != SecurityEnabledLabelValue {
+	if skiperatorApplication.Labels[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue {
 		return &PodSecurityConfiguration{
 			AppName:         appName,
 			SecurityEnabled: false,
@@ -172,9 +229,9 @@ func getSecurityConfigForPod(ctx context.Context, crudClient client.Client, pod
 	}
 
 	var securityConfigList v1alpha.SecurityConfigList
-	podlog.Info("Fetching SecurityConfig resources")
+	rlog.Info("Fetching SecurityConfig resources")
 	if err := crudClient.List(ctx, &securityConfigList, client.InNamespace(pod.Namespace)); err != nil {
-		return nil, fmt.Errorf("failed to fetch SecurityConfig resources: %w", err)
+		return nil, reject(rejectionReasonError, fmt.Errorf("failed to fetch SecurityConfig resources: %w", err))
 	}
 
 	var securityConfigForApplication []v1alpha.SecurityConfig
@@ -187,193 +244,78 @@ func getSecurityConfigForPod(ctx context.Context, crudClient client.Client, pod
 	if len(securityConfigForApplication) < 1 {
 		msg := fmt.Sprintf(
 			"the application is labelled with %s=%s but no SecurityConfig resource was found for Application",
-			SecurityEnabledLabelName,
-			SecurityEnabledLabelValue,
+			utilities.SecurityEnabledLabelName,
+			utilities.SecurityEnabledLabelValue,
 		)
-		podlog.Info(msg, "name", appName)
-		return nil, fmt.Errorf("%s", msg)
+		rlog.Warning(msg)
+		return nil, reject(rejectionReasonSecurityConfigNotFound, fmt.Errorf("%s", msg))
 	}
 
 	if len(securityConfigForApplication) > 1 {
 		msg := "multiple SecurityConfig resources found for Application"
-		podlog.Info(msg, "name", appName)
-		return nil, fmt.Errorf("%s", msg)
+		rlog.Warning(msg)
+		return nil, reject(rejectionReasonMultipleSecurityConfigs, fmt.Errorf("%s", msg))
 	}
 
 	securityConfig := &securityConfigForApplication[0]
 
 	if securityConfig == nil {
 		msg := "SecurityConfig resource for Application was nil"
-		podlog.Info(msg, "name", appName)
+		rlog.Warning(msg)
 		return nil, fmt.Errorf("%s", msg)
 	}
 
-	texasContainer, err := getTexasContainer(*securityConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to construct Texas container: %w", err)
+	if len(capability.Enabled(*securityConfig)) == 0 {
+		msg := fmt.Sprintf(
+			"the application is labelled with %s=%s but SecurityConfig %s enables no capability",
+			utilities.SecurityEnabledLabelName,
+			utilities.SecurityEnabledLabelValue,
+			securityConfig.Name,
+		)
+		rlog.Warning(msg)
+		return nil, reject(rejectionReasonNoCapabilityEnabled, fmt.Errorf("%s", msg))
 	}
 
 	return &PodSecurityConfiguration{
 		SecurityConfig:  securityConfig,
 		AppName:         appName,
 		SecurityEnabled: true,
-		TexasContainer:  *texasContainer,
 	}, nil
 }
 
-func getTexasContainer(securityConfig v1alpha.SecurityConfig) (*corev1.Container, error) {
-	if securityConfig.Spec.Tokenx == nil || !securityConfig.Spec.Tokenx.Enabled {
-		return nil, fmt.Errorf("a texas container should not be created if tokenx is not enabled")
-	}
-
-	texasImageUrl := fmt.Sprintf(
-		"%s:%s",
-		config.Get().TexasImageName,
-		config.Get().TexasImageTag,
-	)
-	expectedJwkerSecretName := utilities.GetJwkerSecretName(
-		utilities.GetJwkerName(securityConfig.Spec.ApplicationRef),
-	)
-
-	return &corev1.Container{
-		Name:  TexasInitContainerName,
-		Image: texasImageUrl,
-		Ports: []corev1.ContainerPort{
-			{
-				ContainerPort: config.Get().TexasPort,
-				Name:          TexasPortName,
-				Protocol:      corev1.ProtocolTCP,
-			},
-		},
-		// NOTE: RestartPolicy Always is only available for init containers in Kubernetes v1.33+
-		// https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#detailed-behavior
-		RestartPolicy: utilities.Ptr(corev1.ContainerRestartPolicyAlways),
-		SecurityContext: &corev1.SecurityContext{
-			AllowPrivilegeEscalation: utilities.Ptr(false),
-			Capabilities: &corev1.Capabilities{
-				Drop: []corev1.Capability{
-					"ALL",
-				},
-				Add: []corev1.Capability{
-					"NET_BIND_SERVICE",
-				},
-			},
-			Privileged:             utilities.Ptr(false),
-			ReadOnlyRootFilesystem: utilities.Ptr(true),
-			RunAsGroup:             utilities.Ptr(int64(150)),
-			RunAsNonRoot:           utilities.Ptr(true),
-			RunAsUser:              utilities.Ptr(int64(150)),
-		},
-		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
-		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
-		Env: []corev1.EnvVar{
-			{
-				Name:  TokenXEnabledEnvVarName,
-				Value: "true",
-			},
-			{
-				Name:  MaskinportenEnabledEnvVarName,
-				Value: "false",
-			},
-			{
-				Name:  AzureEnabledEnvVarName,
-				Value: "false",
-			},
-			{
-				Name:  IdportenEnabledEnvVarName,
-				Value: "false",
-			},
-		},
-		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: expectedJwkerSecretName}}}},
-	}, nil
+func validatePod(ctx context.Context, crudClient client.Client, obj runtime.Object) (admission.Warnings, error) {
+	start := time.Now()
+	ctx = log.WithAdmission(ctx)
+	outcome, reason, err := getValidationOutcome(ctx, crudClient, obj)
+	metrics.RecordPodAdmission(validatingWebhookName, outcome, reason, time.Since(start))
+	recordRejection(ctx, obj, err)
+	return nil, err
 }
 
-func validatePod(ctx context.Context, crudClient client.Client, obj runtime.Object) (admission.Warnings, error) {
+// getValidationOutcome validates the pod, and returns the outcome and reason of the admission for the metrics.
+func getValidationOutcome(ctx context.Context, crudClient client.Client, obj runtime.Object) (string, string, error) {
 	pod, ok := obj.(*corev1.Pod)
 	if !ok {
-		return nil, fmt.Errorf("expected an Pod object but got %T", obj)
+		return metrics.AdmissionOutcomeRejected, rejectionReasonInvalidObject, fmt.Errorf("expected an Pod object but got %T", obj)
 	}
 
-	podlog.Info("Validating for Pod", "name", pod.GetName())
+	rlog := getLogger(ctx)
+	rlog.Info("Validating for Pod", "name", pod.GetName())
 
 	securityConfigForPod, getSecurityConfigForPodErr := getSecurityConfigForPod(ctx, crudClient, pod)
 	if getSecurityConfigForPodErr != nil {
-		podlog.Error(getSecurityConfigForPodErr, "Failed to validate for Pod")
-		return nil, getSecurityConfigForPodErr
+		rlog.Error(getSecurityConfigForPodErr, "Failed to validate for Pod")
+		return metrics.AdmissionOutcomeRejected, getRejectionReason(getSecurityConfigForPodErr), getSecurityConfigForPodErr
 	}
 	if !securityConfigForPod.SecurityEnabled {
-		return nil, nil
+		return metrics.AdmissionOutcomeSkipped, skipReasonSecurityNotEnabled, nil
 	}
 
-	if securityConfigForPod.SecurityConfig.Spec.Tokenx != nil && securityConfigForPod.SecurityConfig.Spec.Tokenx.Enabled {
-		validateTokenXConfErr := validateTokenxCorrectlyConfigured(pod, securityConfigForPod)
-		if validateTokenXConfErr != nil {
-			podlog.Error(validateTokenXConfErr, "Failed to validate for Pod")
-			return nil, validateTokenXConfErr
-		}
-	}
-
-	return nil, nil
-}
-
-func validateTokenxCorrectlyConfigured(pod *corev1.Pod, securityConfigForPod *PodSecurityConfiguration) error {
-	// Validate that the Texas init container exists
-	hasTexasInitContainer := false
-	for _, initContainer := range pod.Spec.InitContainers {
-		if initContainer.Name == TexasInitContainerName {
-			hasTexasInitContainer = true
-			if !isTexasContainerEqual(
-				securityConfigForPod.TexasContainer,
-				initContainer,
-			) {
-				return fmt.Errorf("texas init container is not as expected given the SecurityConfig")
-			}
-			break
+	for _, c := range capability.Enabled(*securityConfigForPod.SecurityConfig) {
+		if err := c.PodValidation(ctx, pod, *securityConfigForPod.SecurityConfig, securityConfigForPod.AppName); err != nil {
+			rlog.Error(err, "Failed to validate for Pod", "capability", c.Name())
+			return metrics.AdmissionOutcomeRejected, getRejectionReason(err), err
 		}
 	}
-	if !hasTexasInitContainer {
-		podlog.Info("TokenX is enabled but texas init container is missing")
-		return fmt.Errorf("TokenX is enabled but init container '%s' is missing", TexasInitContainerName)
-	}
-
-	// Validate that the application container has the TEXAS_URL env variable
-	hasTexasUrlEnvVar := false
-	for _, container := range pod.Spec.Containers {
-		if container.Name == securityConfigForPod.AppName {
-			for _, envVar := range container.Env {
-				if envVar.Name == config.Get().TexasUrlEnvVarName && envVar.Value == getTexasUrlEnvVarValue() {
-					hasTexasUrlEnvVar = true
-					break
-				}
-			}
-			break
-		}
-	}
-	if !hasTexasUrlEnvVar {
-		errMsg := fmt.Sprintf(
-			"TokenX is enabled but %s env var is missing for pod from skiperator app with name %s/%s",
-			pod.Namespace,
-			securityConfigForPod.AppName,
-			config.Get().TexasUrlEnvVarName,
-		)
-		podlog.Info(errMsg)
-		return fmt.Errorf("%s", errMsg)
-	}
-	return nil
-}
-
-func isTexasContainerEqual(expected, actual corev1.Container) bool {
-	return expected.Name == actual.Name &&
-		expected.Image == actual.Image &&
-		reflect.DeepEqual(expected.RestartPolicy, actual.RestartPolicy) &&
-		reflect.DeepEqual(expected.Env, actual.Env) &&
-		reflect.DeepEqual(expected.EnvFrom, actual.EnvFrom) &&
-		reflect.DeepEqual(expected.Ports, actual.Ports) &&
-		reflect.DeepEqual(expected.SecurityContext, actual.SecurityContext) &&
-		reflect.DeepEqual(expected.TerminationMessagePath, actual.TerminationMessagePath) &&
-		reflect.DeepEqual(expected.TerminationMessagePolicy, actual.TerminationMessagePolicy)
-}
-
-func getTexasUrlEnvVarValue() string {
-	return fmt.Sprintf("http://localhost:%d", config.Get().TexasPort)
+	return metrics.AdmissionOutcomeAllowed, "", nil
 }
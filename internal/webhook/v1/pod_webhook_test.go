@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/capability/tokenx"
 	"github.com/kartverket/accesserator/pkg/config"
 	"github.com/kartverket/accesserator/pkg/utilities"
 	"github.com/kartverket/skiperator/api/v1alpha1"
@@ -14,6 +15,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("pod_webhook.go unit tests", func() {
@@ -37,82 +39,6 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 		})
 	})
 
-	Describe("getTexasUrlEnvVarValue", func() {
-		It("returns a localhost URL including the configured port", func() {
-			v := getTexasUrlEnvVarValue()
-			Expect(v).To(ContainSubstring("http://localhost:"))
-			Expect(v).To(ContainSubstring(fmt.Sprintf(":%d", config.Get().TexasPort)))
-		})
-	})
-
-	Describe("getTexasContainer", func() {
-		It("returns error when tokenx is not enabled", func() {
-			applicationRef := "myapp"
-			securityConfig := v1alpha.SecurityConfig{
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx: &v1alpha.TokenXSpec{
-						Enabled: false,
-					},
-					ApplicationRef: applicationRef,
-				},
-			}
-			c, err := getTexasContainer(securityConfig)
-			Expect(err).To(MatchError(Equal("a texas container should not be created if tokenx is not enabled")))
-			Expect(c).To(BeNil())
-		})
-
-		It("builds a texas init container with expected basic properties", func() {
-			applicationRef := "myapp"
-			securityConfig := v1alpha.SecurityConfig{
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx: &v1alpha.TokenXSpec{
-						Enabled: true,
-					},
-					ApplicationRef: applicationRef,
-				},
-			}
-			c, err := getTexasContainer(securityConfig)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(c.Image).To(Equal(fmt.Sprintf("%s:%s", config.Get().TexasImageName, config.Get().TexasImageTag)))
-			Expect(*c.RestartPolicy).To(Equal(corev1.ContainerRestartPolicyAlways))
-			Expect(c.SecurityContext).ToNot(BeNil())
-			Expect(c.Env).NotTo(BeEmpty())
-			Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: TokenXEnabledEnvVarName, Value: "true"}))
-			Expect(c.EnvFrom).NotTo(BeEmpty())
-			Expect(c.EnvFrom).To(
-				ContainElement(
-					corev1.EnvFromSource{
-						SecretRef: &corev1.SecretEnvSource{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: utilities.GetJwkerSecretName(utilities.GetJwkerName(applicationRef)),
-							},
-						},
-					},
-				),
-			)
-		})
-	})
-
-	Describe("isTexasContainerEqual", func() {
-		It("returns true for identical containers and false when a field differs", func() {
-			securityConfig := v1alpha.SecurityConfig{
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx: &v1alpha.TokenXSpec{
-						Enabled: true,
-					},
-					ApplicationRef: "myapp",
-				}}
-			a, errA := getTexasContainer(securityConfig)
-			Expect(errA).ToNot(HaveOccurred())
-			b, errB := getTexasContainer(securityConfig)
-			Expect(errB).ToNot(HaveOccurred())
-			Expect(isTexasContainerEqual(*a, *b)).To(BeTrue())
-
-			b.Image = b.Image + "-changed"
-			Expect(isTexasContainerEqual(*a, *b)).To(BeFalse())
-		})
-	})
-
 	Describe("getSecurityConfigForPod", func() {
 		It("returns SecurityEnabled=false when Pod is not created from Skiperator Application", func() {
 			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns"}}
@@ -127,7 +53,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -143,7 +69,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -165,7 +91,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -198,7 +124,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -211,7 +137,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 							Name:      skiperatorAppName,
 							Namespace: pod.Namespace,
 							Labels: map[string]string{
-								SecurityEnabledLabelName: SecurityEnabledLabelValue,
+								utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
 							},
 						},
 					},
@@ -221,8 +147,8 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 			Expect(err).To(MatchError(Equal(
 				fmt.Sprintf(
 					"the application is labelled with %s=%s but no SecurityConfig resource was found for Application",
-					SecurityEnabledLabelName,
-					SecurityEnabledLabelValue,
+					utilities.SecurityEnabledLabelName,
+					utilities.SecurityEnabledLabelValue,
 				),
 			)))
 			Expect(cfg).To(BeNil())
@@ -235,7 +161,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -248,7 +174,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 							Name:      skiperatorAppName,
 							Namespace: pod.Namespace,
 							Labels: map[string]string{
-								SecurityEnabledLabelName: SecurityEnabledLabelValue,
+								utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
 							},
 						},
 					},
@@ -277,6 +203,48 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 			Expect(cfg).To(BeNil())
 		})
 
+		It("returns error when the SecurityConfig of the Skiperator Application enables no capability", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "p",
+					Namespace: "ns",
+					Labels: map[string]string{
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
+					},
+				},
+			}
+
+			cfg, err := getSecurityConfigForPod(
+				ctx,
+				utilities.GetMockKubernetesClient(
+					scheme,
+					&v1alpha1.Application{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      skiperatorAppName,
+							Namespace: pod.Namespace,
+							Labels: map[string]string{
+								utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
+							},
+						},
+					},
+					&v1alpha.SecurityConfig{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "security-config",
+							Namespace: pod.Namespace,
+						},
+						Spec: v1alpha.SecurityConfigSpec{
+							Tokenx:         &v1alpha.TokenXSpec{Enabled: false},
+							ApplicationRef: skiperatorAppName,
+						},
+					},
+				),
+				pod,
+			)
+			Expect(err).To(MatchError(ContainSubstring("SecurityConfig security-config enables no capability")))
+			Expect(getRejectionReason(err)).To(Equal(rejectionReasonNoCapabilityEnabled))
+			Expect(cfg).To(BeNil())
+		})
+
 		It("returns full PodSecurityConfiguration when pod is created by Skiperator Application with correct label and when a SecurityConfig referencing the same app exists", func() {
 			skiperatorAppName := skiperatorAppName
 			pod := &corev1.Pod{
@@ -284,7 +252,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 			}
@@ -309,7 +277,7 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 							Name:      skiperatorAppName,
 							Namespace: pod.Namespace,
 							Labels: map[string]string{
-								SecurityEnabledLabelName: SecurityEnabledLabelValue,
+								utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
 							},
 						},
 					},
@@ -318,218 +286,124 @@ var _ = Describe("pod_webhook.go unit tests", func() {
 				pod,
 			)
 
-			texasContainer, getTexasErr := getTexasContainer(securityConfig)
-			Expect(getTexasErr).ToNot(HaveOccurred())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(*cfg).To(Equal(
 				PodSecurityConfiguration{
 					SecurityConfig:  &securityConfig,
 					AppName:         skiperatorAppName,
 					SecurityEnabled: true,
-					TexasContainer:  *texasContainer,
 				},
 			))
 		})
 	})
 
-	Describe("isTexasContainerEqual", func() {
-		It("returns true when two texas containers are equal on the fields that are used", func() {
-			securityConfig := v1alpha.SecurityConfig{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "security-config",
-					Namespace: "ns",
-				},
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx: &v1alpha.TokenXSpec{
-						Enabled: true,
-					},
-					ApplicationRef: "my-app",
-				},
-			}
-			texasContainer, _ := getTexasContainer(securityConfig)
-			result := isTexasContainerEqual(
-				*texasContainer,
-				*texasContainer,
-			)
-			Expect(result).To(BeTrue())
-		})
-
-		It("returns false when two texas containers are not equal on the fields that are used", func() {
-			securityConfig := v1alpha.SecurityConfig{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "security-config",
-					Namespace: "ns",
-				},
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx: &v1alpha.TokenXSpec{
-						Enabled: true,
-					},
-					ApplicationRef: "my-app",
-				},
-			}
-			texasContainer, _ := getTexasContainer(securityConfig)
-			alteredTexasContainer := *texasContainer
-			alteredTexasContainer.Ports = append(
-				alteredTexasContainer.Ports,
-				corev1.ContainerPort{
-					Name:          "dummy-port",
-					ContainerPort: 1234,
-					Protocol:      "UDP",
-				},
-			)
-			result := isTexasContainerEqual(
-				*texasContainer,
-				alteredTexasContainer,
-			)
-			Expect(result).To(BeFalse())
-		})
-	})
+	Describe("Texas injection through the admission handlers", func() {
+		var (
+			securityConfig v1alpha.SecurityConfig
+			k8sClient      client.Client
+			texasContainer *corev1.Container
+		)
 
-	Describe("validateTokenxCorrectlyConfigured", func() {
-		It("returns error when pod should have texas init container but it does not have texas init container", func() {
-			skiperatorAppName := skiperatorAppName
-			pod := &corev1.Pod{
+		getPod := func() *corev1.Pod {
+			return &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "p",
 					Namespace: "ns",
 					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
+						utilities.SkiperatorApplicationRefLabel: skiperatorAppName,
 					},
 				},
 				Spec: corev1.PodSpec{
-					InitContainers: []corev1.Container{},
+					Containers: []corev1.Container{{Name: skiperatorAppName}},
 				},
 			}
+		}
 
-			securityConfig := v1alpha.SecurityConfig{
+		BeforeEach(func() {
+			securityConfig = v1alpha.SecurityConfig{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "security-config",
-					Namespace: pod.Namespace,
+					Namespace: "ns",
 				},
 				Spec: v1alpha.SecurityConfigSpec{
 					Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
 					ApplicationRef: skiperatorAppName,
 				},
 			}
+			k8sClient = utilities.GetMockKubernetesClient(
+				scheme,
+				&v1alpha1.Application{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      skiperatorAppName,
+						Namespace: "ns",
+						Labels: map[string]string{
+							utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
+						},
+					},
+				},
+				&securityConfig,
+			)
+			var err error
+			texasContainer, err = tokenx.GetTexasContainer(securityConfig)
+			Expect(err).ToNot(HaveOccurred())
+		})
 
-			texasContainer, getTexasErr := getTexasContainer(securityConfig)
-			Expect(getTexasErr).ToNot(HaveOccurred())
+		It("injects the texas init container and the texas url into the application container", func() {
+			pod := getPod()
+			Expect((&PodCustomDefaulter{Client: k8sClient}).Default(ctx, pod)).To(Succeed())
 
-			podSecurityConfig := PodSecurityConfiguration{
-				SecurityConfig:  &securityConfig,
-				AppName:         skiperatorAppName,
-				SecurityEnabled: true,
-				TexasContainer:  *texasContainer,
-			}
+			Expect(pod.Spec.InitContainers).To(HaveLen(1))
+			Expect(tokenx.IsTexasContainerEqual(*texasContainer, pod.Spec.InitContainers[0])).To(BeTrue())
+			Expect(pod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name:  config.Get().TexasUrlEnvVarName,
+				Value: tokenx.GetTexasUrlEnvVarValue(),
+			}))
+		})
 
-			validateTokenxErr := validateTokenxCorrectlyConfigured(pod, &podSecurityConfig)
-			Expect(validateTokenxErr).To(
-				MatchError(
-					Equal(
-						fmt.Sprintf("TokenX is enabled but init container '%s' is missing", TexasInitContainerName),
-					),
-				),
+		It("does not mutate a pod whose Application does not enable security", func() {
+			pod := getPod()
+			pod.Labels[utilities.SkiperatorApplicationRefLabel] = "other-app"
+			k8sClient = utilities.GetMockKubernetesClient(
+				scheme,
+				&v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "other-app", Namespace: "ns"}},
 			)
-		})
 
-		It("returns error when TokenX is enabled, texas init container is injected but texas environment variable is missing from main app container", func() {
-			skiperatorAppName := skiperatorAppName
-			securityConfig := v1alpha.SecurityConfig{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "security-config",
-					Namespace: "ns",
-				},
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
-					ApplicationRef: skiperatorAppName,
-				},
-			}
-			texasContainer, getTexasErr := getTexasContainer(securityConfig)
-			Expect(getTexasErr).ToNot(HaveOccurred())
+			Expect((&PodCustomDefaulter{Client: k8sClient}).Default(ctx, pod)).To(Succeed())
+			Expect(pod.Spec.InitContainers).To(BeEmpty())
+		})
 
-			podSecurityConfig := PodSecurityConfiguration{
-				SecurityConfig:  &securityConfig,
-				AppName:         skiperatorAppName,
-				SecurityEnabled: true,
-				TexasContainer:  *texasContainer,
-			}
+		It("admits a pod mutated by the defaulter", func() {
+			pod := getPod()
+			Expect((&PodCustomDefaulter{Client: k8sClient}).Default(ctx, pod)).To(Succeed())
 
-			pod := &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "p",
-					Namespace: securityConfig.Namespace,
-					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
-					},
-				},
-				Spec: corev1.PodSpec{
-					InitContainers: []corev1.Container{*texasContainer},
-				},
-			}
+			_, err := (&PodCustomValidator{Client: k8sClient}).ValidateCreate(ctx, pod)
+			Expect(err).ToNot(HaveOccurred())
+		})
 
-			validateTokenxErr := validateTokenxCorrectlyConfigured(pod, &podSecurityConfig)
-			Expect(validateTokenxErr).To(
-				MatchError(
-					Equal(
-						fmt.Sprintf(
-							"TokenX is enabled but %s env var is missing for pod from skiperator app with name %s/%s",
-							pod.Namespace,
-							podSecurityConfig.AppName,
-							config.Get().TexasUrlEnvVarName,
-						),
-					),
-				),
-			)
+		It("rejects a pod without the texas init container", func() {
+			_, err := (&PodCustomValidator{Client: k8sClient}).ValidateCreate(ctx, getPod())
+			Expect(err).To(MatchError(
+				fmt.Sprintf("TokenX is enabled but init container '%s' is missing", TexasInitContainerName),
+			))
 		})
 
-		It("returns no error when TokenX is enabled, correct Texas container is injected and texas env var is injected in main app container", func() {
-			skiperatorAppName := skiperatorAppName
-			securityConfig := v1alpha.SecurityConfig{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "security-config",
-					Namespace: "ns",
-				},
-				Spec: v1alpha.SecurityConfigSpec{
-					Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
-					ApplicationRef: skiperatorAppName,
-				},
-			}
-			texasContainer, getTexasErr := getTexasContainer(securityConfig)
-			Expect(getTexasErr).ToNot(HaveOccurred())
+		It("rejects a pod whose texas init container differs from the SecurityConfig", func() {
+			pod := getPod()
+			Expect((&PodCustomDefaulter{Client: k8sClient}).Default(ctx, pod)).To(Succeed())
+			pod.Spec.InitContainers[0].Image += "-changed"
 
-			podSecurityConfig := PodSecurityConfiguration{
-				SecurityConfig:  &securityConfig,
-				AppName:         skiperatorAppName,
-				SecurityEnabled: true,
-				TexasContainer:  *texasContainer,
-			}
+			_, err := (&PodCustomValidator{Client: k8sClient}).ValidateCreate(ctx, pod)
+			Expect(err).To(MatchError("texas init container is not as expected given the SecurityConfig"))
+		})
 
-			pod := &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "p",
-					Namespace: securityConfig.Namespace,
-					Labels: map[string]string{
-						SkiperatorApplicationRefLabel: skiperatorAppName,
-					},
-				},
-				Spec: corev1.PodSpec{
-					InitContainers: []corev1.Container{*texasContainer},
-					Containers: []corev1.Container{
-						{
-							Name: podSecurityConfig.AppName,
-							Env: []corev1.EnvVar{
-								{
-									Name:  config.Get().TexasUrlEnvVarName,
-									Value: getTexasUrlEnvVarValue(),
-								},
-							},
-						},
-					},
-				},
-			}
+		It("rejects a pod without the texas url in the application container", func() {
+			pod := getPod()
+			pod.Spec.InitContainers = []corev1.Container{*texasContainer}
 
-			validateTokenxErr := validateTokenxCorrectlyConfigured(pod, &podSecurityConfig)
-			Expect(validateTokenxErr).ToNot(HaveOccurred())
+			_, err := (&PodCustomValidator{Client: k8sClient}).ValidateCreate(ctx, pod)
+			Expect(err).To(MatchError(ContainSubstring(
+				fmt.Sprintf("TokenX is enabled but %s env var is missing", pod.Namespace),
+			)))
 		})
 	})
 })
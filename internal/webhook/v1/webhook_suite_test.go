@@ -12,6 +12,7 @@ import (
 
 	"github.com/kartverket/accesserator/api/v1alpha"
 	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/utilities"
 	"github.com/kartverket/skiperator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -212,7 +213,7 @@ var _ = Describe("Pod mutating and validating webhook", func() {
 				Name:      skiperatorAppName,
 				Namespace: ns.GetName(),
 				Labels: map[string]string{
-					SecurityEnabledLabelName: SecurityEnabledLabelValue,
+					utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue,
 				},
 			},
 		}
@@ -240,7 +241,7 @@ var _ = Describe("Pod mutating and validating webhook", func() {
 			pod.Labels = make(map[string]string)
 		}
 
-		pod.Labels[SkiperatorApplicationRefLabel] = skiperatorAppName
+		pod.Labels[utilities.SkiperatorApplicationRefLabel] = skiperatorAppName
 		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
 
 		mutatedPod := &corev1.Pod{}
@@ -272,7 +273,7 @@ var _ = Describe("Pod mutating and validating webhook", func() {
 		if updatedPod.Labels == nil {
 			updatedPod.Labels = make(map[string]string)
 		}
-		updatedPod.Labels[SkiperatorApplicationRefLabel] = skiperatorAppName
+		updatedPod.Labels[utilities.SkiperatorApplicationRefLabel] = skiperatorAppName
 		Expect(k8sClient.Update(ctx, updatedPod)).To(Succeed())
 
 		// Ensure no new init containers are injected on update
@@ -0,0 +1,145 @@
+package accessgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+	FormatJSON    Format = "json"
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatDOT:
+		return FormatDOT, nil
+	case FormatMermaid:
+		return FormatMermaid, nil
+	case FormatJSON, "":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported access graph format %q, expected one of dot, mermaid or json", format)
+	}
+}
+
+// Write exports the graph in the given format.
+func (g *Graph) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatDOT:
+		return g.WriteDOT(w)
+	case FormatMermaid:
+		return g.WriteMermaid(w)
+	case FormatJSON:
+		return g.WriteJSON(w)
+	default:
+		return fmt.Errorf("unsupported access graph format %q", format)
+	}
+}
+
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph accesserator {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		style := "solid"
+		if node.Missing {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %q [style=%s];\n", node.Workload.String(), style)
+	}
+	for _, edge := range g.Edges {
+		color, style := "darkgreen", "solid"
+		switch edge.Status {
+		case EdgeStatusOneSided:
+			color, style = "orange", "dashed"
+		case EdgeStatusMissingApp:
+			color, style = "red", "dotted"
+		}
+		fmt.Fprintf(
+			&b,
+			"  %q -> %q [label=%q, color=%s, style=%s];\n",
+			edge.From.String(),
+			edge.To.String(),
+			string(edge.Status),
+			color,
+			style,
+		)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.Workload.String()] = id
+		if node.Missing {
+			fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, node.Workload.String())
+		} else {
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, node.Workload.String())
+		}
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		switch edge.Status {
+		case EdgeStatusOneSided:
+			arrow = "-.->"
+		case EdgeStatusMissingApp:
+			arrow = "--x"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[edge.From.String()], arrow, edge.Status, ids[edge.To.String()])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type jsonNode struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	TokenX    bool   `json:"tokenx"`
+	Jwker     bool   `json:"jwker"`
+	Missing   bool   `json:"missing"`
+}
+
+type jsonEdge struct {
+	From   string     `json:"from"`
+	To     string     `json:"to"`
+	Status EdgeStatus `json:"status"`
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+func (g *Graph) WriteJSON(w io.Writer) error {
+	out := jsonGraph{
+		Nodes: make([]jsonNode, 0, len(g.Nodes)),
+		Edges: make([]jsonEdge, 0, len(g.Edges)),
+	}
+	for _, node := range g.Nodes {
+		out.Nodes = append(out.Nodes, jsonNode{
+			Name:      node.Workload.Name,
+			Namespace: node.Workload.Namespace,
+			TokenX:    node.TokenX,
+			Jwker:     node.Jwker,
+			Missing:   node.Missing,
+		})
+	}
+	for _, edge := range g.Edges {
+		out.Edges = append(out.Edges, jsonEdge{From: edge.From.String(), To: edge.To.String(), Status: edge.Status})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
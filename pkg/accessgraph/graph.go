@@ -0,0 +1,193 @@
+package accessgraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/resolver"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type EdgeStatus string
+
+const (
+	// EdgeStatusAllowed means the caller has an outbound rule and the target has a matching inbound rule.
+	EdgeStatusAllowed EdgeStatus = "allowed"
+	// EdgeStatusOneSided means only one of the caller and the target declares the rule.
+	EdgeStatusOneSided EdgeStatus = "one-sided"
+	// EdgeStatusMissingApp means the rule points at an application without TokenX or without an Application.
+	EdgeStatusMissingApp EdgeStatus = "missing-app"
+)
+
+type Node struct {
+	Workload accesspolicy.Workload
+	TokenX   bool
+	Jwker    bool
+	Missing  bool
+}
+
+// Edge is a directed token exchange relation where From is the caller and To is the intended audience.
+type Edge struct {
+	From   accesspolicy.Workload
+	To     accesspolicy.Workload
+	Status EdgeStatus
+}
+
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+type edgeRules struct {
+	outbound bool
+	inbound  bool
+}
+
+// Build creates the access graph from resolved SecurityConfigs and the Jwkers in the cluster. When a Jwker exists for
+// an application, its access policy is used since that is what is registered with Tokendings. Otherwise, the
+// access policy resolved from the Skiperator Application is used.
+func Build(scopes []*state.Scope, jwkers []naisiov1.Jwker) *Graph {
+	nodes := map[accesspolicy.Workload]*Node{}
+	rules := map[accesspolicy.Workload][]accesspolicy.Rule{}
+
+	for _, scope := range scopes {
+		if !scope.TokenXConfig.Enabled {
+			continue
+		}
+		workload := accesspolicy.Workload{
+			Name:      scope.SecurityConfig.Spec.ApplicationRef,
+			Namespace: scope.SecurityConfig.Namespace,
+		}
+		nodes[workload] = &Node{Workload: workload, TokenX: true}
+		rules[workload] = accesspolicy.GetRules(workload, scope.TokenXConfig.AccessPolicy)
+	}
+	for _, jwker := range jwkers {
+		workload := accesspolicy.Workload{Name: jwker.Name, Namespace: jwker.Namespace}
+		node, exists := nodes[workload]
+		if !exists {
+			node = &Node{Workload: workload}
+			nodes[workload] = node
+		}
+		node.Jwker = true
+		rules[workload] = accesspolicy.GetJwkerRules(workload, jwker.Spec.AccessPolicy)
+	}
+
+	pairs := map[[2]accesspolicy.Workload]*edgeRules{}
+	getPair := func(from, to accesspolicy.Workload) *edgeRules {
+		key := [2]accesspolicy.Workload{from, to}
+		if _, exists := pairs[key]; !exists {
+			pairs[key] = &edgeRules{}
+		}
+		return pairs[key]
+	}
+	for _, workloadRules := range rules {
+		for _, rule := range workloadRules {
+			if rule.Direction == accesspolicy.DirectionOutbound {
+				getPair(rule.Owner, rule.Peer).outbound = true
+			} else {
+				getPair(rule.Peer, rule.Owner).inbound = true
+			}
+		}
+	}
+
+	graph := &Graph{}
+	missing := map[accesspolicy.Workload]bool{}
+	for key, pair := range pairs {
+		from, to := key[0], key[1]
+		status := EdgeStatusAllowed
+		switch {
+		case nodes[from] == nil || nodes[to] == nil:
+			status = EdgeStatusMissingApp
+		case !pair.outbound || !pair.inbound:
+			status = EdgeStatusOneSided
+		}
+		for _, workload := range []accesspolicy.Workload{from, to} {
+			if nodes[workload] == nil {
+				missing[workload] = true
+			}
+		}
+		graph.Edges = append(graph.Edges, Edge{From: from, To: to, Status: status})
+	}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	for workload := range missing {
+		graph.Nodes = append(graph.Nodes, Node{Workload: workload, Missing: true})
+	}
+	graph.sort()
+	return graph
+}
+
+// Filter returns the part of the graph touching the given namespace and application. Empty values match everything.
+func (g *Graph) Filter(namespace, app string) *Graph {
+	matches := func(w accesspolicy.Workload) bool {
+		return (namespace == "" || w.Namespace == namespace) && (app == "" || w.Name == app)
+	}
+
+	filtered := &Graph{}
+	keep := map[accesspolicy.Workload]bool{}
+	for _, edge := range g.Edges {
+		if matches(edge.From) || matches(edge.To) {
+			filtered.Edges = append(filtered.Edges, edge)
+			keep[edge.From] = true
+			keep[edge.To] = true
+		}
+	}
+	for _, node := range g.Nodes {
+		if keep[node.Workload] || matches(node.Workload) {
+			filtered.Nodes = append(filtered.Nodes, node)
+		}
+	}
+	return filtered
+}
+
+// Collect resolves every SecurityConfig in the cluster and builds the access graph together with all Jwkers.
+func Collect(ctx context.Context, k8sClient client.Client) (*Graph, error) {
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources: %w", err)
+	}
+	var jwkerList naisiov1.JwkerList
+	if err := k8sClient.List(ctx, &jwkerList); err != nil {
+		return nil, fmt.Errorf("failed to list Jwker resources: %w", err)
+	}
+	accessPolicyIndex, err := resolver.GetAccessPolicyIndex(ctx, k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]*state.Scope, 0, len(securityConfigList.Items))
+	for _, securityConfig := range securityConfigList.Items {
+		scope, resolveErr := resolver.ResolveSecurityConfigWithIndex(ctx, k8sClient, securityConfig, accessPolicyIndex)
+		if resolveErr != nil || scope.Orphaned || scope.InvalidConfig {
+			// The SecurityConfig could not be resolved, e.g. because its Application is missing. It is left out of the
+			// graph so that rules pointing at it are reported as missing.
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	return Build(scopes, jwkerList.Items), nil
+}
+
+func (g *Graph) sort() {
+	less := func(a, b accesspolicy.Workload) bool {
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return less(g.Nodes[i].Workload, g.Nodes[j].Workload)
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return less(g.Edges[i].From, g.Edges[j].From)
+		}
+		return less(g.Edges[i].To, g.Edges[j].To)
+	})
+}
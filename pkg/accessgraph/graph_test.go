@@ -0,0 +1,100 @@
+package accessgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getScope(app, namespace string, accessPolicy *podtypes.AccessPolicy) *state.Scope {
+	return &state.Scope{
+		SecurityConfig: v1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: app, Namespace: namespace},
+			Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: app},
+		},
+		TokenXConfig: state.TokenXConfig{Enabled: true, AccessPolicy: accessPolicy},
+	}
+}
+
+func getTestGraph() *Graph {
+	return Build(
+		[]*state.Scope{
+			getScope("a", "ns", &podtypes.AccessPolicy{Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{
+				{Application: "b"},
+				{Application: "c", Namespace: "other"},
+				{Application: "ghost"},
+			}}}),
+			getScope("c", "other", nil),
+		},
+		[]naisiov1.Jwker{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"},
+				Spec: naisiov1.JwkerSpec{AccessPolicy: &naisiov1.AccessPolicy{
+					Inbound: &naisiov1.AccessPolicyInbound{Rules: naisiov1.AccessPolicyInboundRules{
+						{AccessPolicyRule: naisiov1.AccessPolicyRule{Application: "a"}},
+					}},
+				}},
+			},
+		},
+	)
+}
+
+func TestBuild(t *testing.T) {
+	graph := getTestGraph()
+
+	a := accesspolicy.Workload{Name: "a", Namespace: "ns"}
+	assert.Equal(t, []Edge{
+		{From: a, To: accesspolicy.Workload{Name: "b", Namespace: "ns"}, Status: EdgeStatusAllowed},
+		{From: a, To: accesspolicy.Workload{Name: "ghost", Namespace: "ns"}, Status: EdgeStatusMissingApp},
+		{From: a, To: accesspolicy.Workload{Name: "c", Namespace: "other"}, Status: EdgeStatusOneSided},
+	}, graph.Edges)
+	assert.Len(t, graph.Nodes, 4)
+	assert.Contains(t, graph.Nodes, Node{Workload: accesspolicy.Workload{Name: "ghost", Namespace: "ns"}, Missing: true})
+	assert.Contains(t, graph.Nodes, Node{Workload: accesspolicy.Workload{Name: "b", Namespace: "ns"}, Jwker: true})
+}
+
+func TestFilter(t *testing.T) {
+	filtered := getTestGraph().Filter("other", "")
+	assert.Len(t, filtered.Edges, 1)
+	assert.Len(t, filtered.Nodes, 2)
+
+	assert.Empty(t, getTestGraph().Filter("unknown", "").Nodes)
+}
+
+func TestWrite(t *testing.T) {
+	graph := getTestGraph()
+
+	var dot bytes.Buffer
+	require.NoError(t, graph.Write(&dot, FormatDOT))
+	assert.Contains(t, dot.String(), `"ns/a" -> "ns/b" [label="allowed"`)
+
+	var mermaid bytes.Buffer
+	require.NoError(t, graph.Write(&mermaid, FormatMermaid))
+	assert.Contains(t, mermaid.String(), "flowchart LR")
+	assert.Contains(t, mermaid.String(), "-.->|one-sided|")
+
+	var out bytes.Buffer
+	require.NoError(t, graph.Write(&out, FormatJSON))
+	var decoded jsonGraph
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Len(t, decoded.Edges, 3)
+	assert.Equal(t, EdgeStatusMissingApp, decoded.Edges[1].Status)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	_, err = ParseFormat("png")
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package accessgraph
+
+import (
+	"net/http"
+
+	"github.com/kartverket/accesserator/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Path is the path the access graph is served on by the metrics server of the manager, which protects it with the
+// same authentication and authorization as the metrics.
+const Path = "/accessgraph"
+
+// Handler returns the access graph of the cluster. The query parameters format (dot, mermaid or json), namespace and
+// app can be used to select the output format and filter the graph.
+func Handler(k8sClient client.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format, err := ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		graph, err := Collect(r.Context(), k8sClient)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		graph = graph.Filter(r.URL.Query().Get("namespace"), r.URL.Query().Get("app"))
+
+		switch format {
+		case FormatJSON:
+			w.Header().Set("Content-Type", "application/json")
+		case FormatDOT:
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		if err := graph.Write(w, format); err != nil {
+			rLog := log.GetLogger(r.Context())
+			rLog.Error(err, "failed to write access graph")
+		}
+	})
+}
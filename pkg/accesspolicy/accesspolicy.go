@@ -0,0 +1,253 @@
+package accesspolicy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+)
+
+type Direction string
+
+const (
+	DirectionInbound  Direction = "inbound"
+	DirectionOutbound Direction = "outbound"
+)
+
+// Workload identifies a Skiperator Application by name and namespace.
+type Workload struct {
+	Name      string
+	Namespace string
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("%s/%s", w.Namespace, w.Name)
+}
+
+// Rule is a single access policy rule declared by Owner that points at Peer.
+type Rule struct {
+	Direction Direction
+	Owner     Workload
+	Peer      Workload
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s rule %s", r.Direction, r.Peer)
+}
+
+// Index holds the access policies of all Applications in the cluster, and which of them have TokenX enabled
+// through a SecurityConfig. Unexpired access policy rules of a SecurityConfig are included in the access policy of
+// the Application it refers to.
+type Index struct {
+	policies               map[Workload]*podtypes.AccessPolicy
+	tokenx                 map[Workload]bool
+	securityConfigPolicies map[Workload]*v1alpha.AccessPolicy
+}
+
+func NewIndex(applications []v1alpha1.Application, securityConfigs []v1alpha.SecurityConfig) *Index {
+	index := &Index{
+		policies:               make(map[Workload]*podtypes.AccessPolicy, len(applications)),
+		tokenx:                 make(map[Workload]bool, len(securityConfigs)),
+		securityConfigPolicies: make(map[Workload]*v1alpha.AccessPolicy, len(securityConfigs)),
+	}
+	for _, application := range applications {
+		index.policies[Workload{Name: application.Name, Namespace: application.Namespace}] = application.Spec.AccessPolicy
+	}
+	now := time.Now()
+	for _, securityConfig := range securityConfigs {
+		if securityConfig.Spec.Tokenx != nil && securityConfig.Spec.Tokenx.Enabled {
+			w := Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace}
+			index.tokenx[w] = true
+			index.securityConfigPolicies[w] = securityConfig.Spec.Tokenx.AccessPolicy
+			if accessPolicy, exists := index.policies[w]; exists {
+				index.policies[w] = MergeAccessPolicy(accessPolicy, securityConfig.Spec.Tokenx.AccessPolicy, now)
+			}
+		}
+	}
+	return index
+}
+
+// HasApplication reports whether an Application exists for the workload.
+func (i *Index) HasApplication(w Workload) bool {
+	_, exists := i.policies[w]
+	return exists
+}
+
+// TokenXEnabled reports whether the workload has a SecurityConfig with TokenX enabled.
+func (i *Index) TokenXEnabled(w Workload) bool {
+	return i.tokenx[w]
+}
+
+// GetSecurityConfigAccessPolicy returns the TokenX access policy of the SecurityConfig of the workload as it was
+// given to NewIndex, e.g. including the rules of its approved AccessRequests. It is nil if the workload has no
+// SecurityConfig with TokenX enabled.
+func (i *Index) GetSecurityConfigAccessPolicy(w Workload) *v1alpha.AccessPolicy {
+	return i.securityConfigPolicies[w]
+}
+
+// Workloads returns every workload that has an Application, sorted by namespace and name.
+func (i *Index) Workloads() []Workload {
+	workloads := make([]Workload, 0, len(i.policies))
+	for w := range i.policies {
+		workloads = append(workloads, w)
+	}
+	sortWorkloads(workloads)
+	return workloads
+}
+
+// Rules returns the in-cluster inbound and outbound rules declared by the workload's Application.
+func (i *Index) Rules(w Workload) []Rule {
+	return GetRules(w, i.policies[w])
+}
+
+// GetRules flattens an access policy into rules owned by w. Rules without an explicit namespace refer to the
+// namespace of w. Rules that only select namespaces by label cannot be resolved to a single workload and are skipped.
+func GetRules(w Workload, accessPolicy *podtypes.AccessPolicy) []Rule {
+	if accessPolicy == nil {
+		return nil
+	}
+	var rules []Rule
+	if accessPolicy.Inbound != nil {
+		for _, rule := range accessPolicy.Inbound.Rules {
+			if peer, ok := getPeer(w, rule); ok {
+				rules = append(rules, Rule{Direction: DirectionInbound, Owner: w, Peer: peer})
+			}
+		}
+	}
+	for _, rule := range accessPolicy.Outbound.Rules {
+		if peer, ok := getPeer(w, rule); ok {
+			rules = append(rules, Rule{Direction: DirectionOutbound, Owner: w, Peer: peer})
+		}
+	}
+	return rules
+}
+
+// GetSecurityConfigRules flattens the TokenX access policy of a SecurityConfig into rules owned by w, including the
+// rules that have expired.
+func GetSecurityConfigRules(w Workload, accessPolicy *v1alpha.AccessPolicy) []Rule {
+	if accessPolicy == nil {
+		return nil
+	}
+	rules := make([]Rule, 0, len(accessPolicy.Inbound)+len(accessPolicy.Outbound))
+	for _, rule := range accessPolicy.Inbound {
+		rules = append(rules, Rule{Direction: DirectionInbound, Owner: w, Peer: getSecurityConfigPeer(w, rule)})
+	}
+	for _, rule := range accessPolicy.Outbound {
+		rules = append(rules, Rule{Direction: DirectionOutbound, Owner: w, Peer: getSecurityConfigPeer(w, rule)})
+	}
+	return rules
+}
+
+// HasCounterpart reports whether the peer of the rule declares the opposite rule pointing back at the owner.
+func (i *Index) HasCounterpart(rule Rule) bool {
+	opposite := DirectionInbound
+	if rule.Direction == DirectionInbound {
+		opposite = DirectionOutbound
+	}
+	for _, peerRule := range i.Rules(rule.Peer) {
+		if peerRule.Direction == opposite && peerRule.Peer == rule.Owner {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOneSidedRules returns the rules of w whose peer has TokenX enabled but does not declare the matching rule.
+// Rules pointing at workloads without TokenX are ordinary network rules and are not reported.
+func (i *Index) GetOneSidedRules(w Workload, accessPolicy *podtypes.AccessPolicy) []Rule {
+	var oneSided []Rule
+	for _, rule := range GetRules(w, accessPolicy) {
+		if rule.Peer == w || !i.TokenXEnabled(rule.Peer) {
+			continue
+		}
+		if !i.HasCounterpart(rule) {
+			oneSided = append(oneSided, rule)
+		}
+	}
+	return oneSided
+}
+
+// GetCounterparts returns the workloads that either are referenced by the access policy of w, or that reference w
+// in their own access policy.
+func (i *Index) GetCounterparts(w Workload) []Workload {
+	seen := map[Workload]bool{}
+	for _, rule := range i.Rules(w) {
+		seen[rule.Peer] = true
+	}
+	for other := range i.policies {
+		for _, rule := range i.Rules(other) {
+			if rule.Peer == w {
+				seen[other] = true
+			}
+		}
+	}
+	delete(seen, w)
+
+	counterparts := make([]Workload, 0, len(seen))
+	for counterpart := range seen {
+		counterparts = append(counterparts, counterpart)
+	}
+	sortWorkloads(counterparts)
+	return counterparts
+}
+
+// GetJwkerRules flattens the access policy of a Jwker into rules owned by workload.
+func GetJwkerRules(workload Workload, accessPolicy *naisiov1.AccessPolicy) []Rule {
+	if accessPolicy == nil {
+		return nil
+	}
+	var rules []Rule
+	peerOf := func(rule naisiov1.AccessPolicyRule) Workload {
+		namespace := rule.Namespace
+		if namespace == "" {
+			namespace = workload.Namespace
+		}
+		return Workload{Name: rule.Application, Namespace: namespace}
+	}
+	if accessPolicy.Inbound != nil {
+		for _, rule := range accessPolicy.Inbound.Rules {
+			rules = append(rules, Rule{
+				Direction: DirectionInbound,
+				Owner:     workload,
+				Peer:      peerOf(rule.AccessPolicyRule),
+			})
+		}
+	}
+	if accessPolicy.Outbound != nil {
+		for _, rule := range accessPolicy.Outbound.Rules {
+			rules = append(rules, Rule{
+				Direction: DirectionOutbound,
+				Owner:     workload,
+				Peer:      peerOf(rule),
+			})
+		}
+	}
+	return rules
+}
+
+func getPeer(owner Workload, rule podtypes.InternalRule) (Workload, bool) {
+	if rule.Application == "" {
+		return Workload{}, false
+	}
+	if rule.Namespace == "" && len(rule.NamespacesByLabel) > 0 {
+		return Workload{}, false
+	}
+	namespace := rule.Namespace
+	if namespace == "" {
+		namespace = owner.Namespace
+	}
+	return Workload{Name: rule.Application, Namespace: namespace}, true
+}
+
+func sortWorkloads(workloads []Workload) {
+	sort.Slice(workloads, func(a, b int) bool {
+		if workloads[a].Namespace != workloads[b].Namespace {
+			return workloads[a].Namespace < workloads[b].Namespace
+		}
+		return workloads[a].Name < workloads[b].Name
+	})
+}
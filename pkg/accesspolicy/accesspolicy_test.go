@@ -0,0 +1,106 @@
+package accesspolicy
+
+import (
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getApplication(name, namespace string, accessPolicy *podtypes.AccessPolicy) v1alpha1.Application {
+	return v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v1alpha1.ApplicationSpec{AccessPolicy: accessPolicy},
+	}
+}
+
+func getSecurityConfig(applicationRef, namespace string) v1alpha.SecurityConfig {
+	return v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: applicationRef, Namespace: namespace},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: applicationRef,
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
+		},
+	}
+}
+
+func outbound(rules ...podtypes.InternalRule) *podtypes.AccessPolicy {
+	return &podtypes.AccessPolicy{Outbound: podtypes.OutboundPolicy{Rules: rules}}
+}
+
+func inbound(rules ...podtypes.InternalRule) *podtypes.AccessPolicy {
+	return &podtypes.AccessPolicy{Inbound: &podtypes.InboundPolicy{Rules: rules}}
+}
+
+func TestGetRules(t *testing.T) {
+	owner := Workload{Name: "a", Namespace: "ns"}
+	accessPolicy := &podtypes.AccessPolicy{
+		Inbound: &podtypes.InboundPolicy{Rules: []podtypes.InternalRule{
+			{Application: "b"},
+			{Application: "c", NamespacesByLabel: map[string]string{"team": "x"}},
+		}},
+		Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{
+			{Application: "d", Namespace: "other"},
+		}},
+	}
+
+	rules := GetRules(owner, accessPolicy)
+	assert.Equal(t, []Rule{
+		{Direction: DirectionInbound, Owner: owner, Peer: Workload{Name: "b", Namespace: "ns"}},
+		{Direction: DirectionOutbound, Owner: owner, Peer: Workload{Name: "d", Namespace: "other"}},
+	}, rules)
+	assert.Nil(t, GetRules(owner, nil))
+}
+
+func TestGetOneSidedRules(t *testing.T) {
+	caller := Workload{Name: "caller", Namespace: "ns"}
+	callerPolicy := outbound(
+		podtypes.InternalRule{Application: "target", Namespace: "other"},
+		podtypes.InternalRule{Application: "plain"},
+	)
+	index := NewIndex(
+		[]v1alpha1.Application{
+			getApplication("caller", "ns", callerPolicy),
+			getApplication("target", "other", nil),
+			getApplication("plain", "ns", nil),
+		},
+		[]v1alpha.SecurityConfig{getSecurityConfig("caller", "ns"), getSecurityConfig("target", "other")},
+	)
+
+	oneSided := index.GetOneSidedRules(caller, callerPolicy)
+	assert.Equal(t, []Rule{
+		{Direction: DirectionOutbound, Owner: caller, Peer: Workload{Name: "target", Namespace: "other"}},
+	}, oneSided)
+	assert.Equal(t, "outbound rule other/target", oneSided[0].String())
+}
+
+func TestGetOneSidedRules_Matched(t *testing.T) {
+	caller := Workload{Name: "caller", Namespace: "ns"}
+	callerPolicy := outbound(podtypes.InternalRule{Application: "target", Namespace: "other"})
+	targetPolicy := inbound(podtypes.InternalRule{Application: "caller", Namespace: "ns"})
+	index := NewIndex(
+		[]v1alpha1.Application{getApplication("caller", "ns", callerPolicy), getApplication("target", "other", targetPolicy)},
+		[]v1alpha.SecurityConfig{getSecurityConfig("caller", "ns"), getSecurityConfig("target", "other")},
+	)
+
+	assert.Empty(t, index.GetOneSidedRules(caller, callerPolicy))
+	assert.Empty(t, index.GetOneSidedRules(Workload{Name: "target", Namespace: "other"}, targetPolicy))
+}
+
+func TestGetCounterparts(t *testing.T) {
+	index := NewIndex(
+		[]v1alpha1.Application{
+			getApplication("a", "ns", outbound(podtypes.InternalRule{Application: "b"})),
+			getApplication("b", "ns", nil),
+			getApplication("c", "other", inbound(podtypes.InternalRule{Application: "b", Namespace: "ns"})),
+			getApplication("d", "ns", nil),
+		},
+		nil,
+	)
+
+	assert.Equal(t, []Workload{{Name: "a", Namespace: "ns"}, {Name: "c", Namespace: "other"}}, index.GetCounterparts(Workload{Name: "b", Namespace: "ns"}))
+	assert.Empty(t, index.GetCounterparts(Workload{Name: "d", Namespace: "ns"}))
+}
@@ -0,0 +1,94 @@
+package accesspolicy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+)
+
+// Grant is a time-bound access policy rule declared in a SecurityConfig.
+type Grant struct {
+	Rule
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the grant no longer gives access at now.
+func (g Grant) Expired(now time.Time) bool {
+	return !now.Before(g.ExpiresAt)
+}
+
+// GetGrants returns the rules of the SecurityConfig access policy of w that have an expiry, sorted by expiry.
+func GetGrants(w Workload, accessPolicy *v1alpha.AccessPolicy) []Grant {
+	if accessPolicy == nil {
+		return nil
+	}
+	var grants []Grant
+	add := func(direction Direction, rules []v1alpha.AccessPolicyRule) {
+		for _, rule := range rules {
+			if rule.ExpiresAt == nil {
+				continue
+			}
+			grants = append(grants, Grant{
+				Rule:      Rule{Direction: direction, Owner: w, Peer: getSecurityConfigPeer(w, rule)},
+				ExpiresAt: rule.ExpiresAt.Time,
+			})
+		}
+	}
+	add(DirectionInbound, accessPolicy.Inbound)
+	add(DirectionOutbound, accessPolicy.Outbound)
+	sort.SliceStable(grants, func(a, b int) bool {
+		return grants[a].ExpiresAt.Before(grants[b].ExpiresAt)
+	})
+	return grants
+}
+
+// MergeAccessPolicy returns the access policy of an Application extended with the rules of the SecurityConfig access
+// policy that have not expired at now. The access policy of the Application is not modified.
+func MergeAccessPolicy(
+	applicationAccessPolicy *podtypes.AccessPolicy,
+	securityConfigAccessPolicy *v1alpha.AccessPolicy,
+	now time.Time,
+) *podtypes.AccessPolicy {
+	if securityConfigAccessPolicy == nil {
+		return applicationAccessPolicy
+	}
+	inboundRules := getUnexpiredRules(securityConfigAccessPolicy.Inbound, now)
+	outboundRules := getUnexpiredRules(securityConfigAccessPolicy.Outbound, now)
+	if len(inboundRules) == 0 && len(outboundRules) == 0 {
+		return applicationAccessPolicy
+	}
+
+	merged := &podtypes.AccessPolicy{}
+	if applicationAccessPolicy != nil {
+		merged = applicationAccessPolicy.DeepCopy()
+	}
+	if len(inboundRules) > 0 {
+		if merged.Inbound == nil {
+			merged.Inbound = &podtypes.InboundPolicy{}
+		}
+		merged.Inbound.Rules = append(merged.Inbound.Rules, inboundRules...)
+	}
+	merged.Outbound.Rules = append(merged.Outbound.Rules, outboundRules...)
+	return merged
+}
+
+func getUnexpiredRules(rules []v1alpha.AccessPolicyRule, now time.Time) []podtypes.InternalRule {
+	var unexpired []podtypes.InternalRule
+	for _, rule := range rules {
+		if rule.ExpiresAt != nil && !now.Before(rule.ExpiresAt.Time) {
+			continue
+		}
+		unexpired = append(unexpired, podtypes.InternalRule{Application: rule.Application, Namespace: rule.Namespace})
+	}
+	return unexpired
+}
+
+func getSecurityConfigPeer(owner Workload, rule v1alpha.AccessPolicyRule) Workload {
+	namespace := rule.Namespace
+	if namespace == "" {
+		namespace = owner.Namespace
+	}
+	return Workload{Name: rule.Application, Namespace: namespace}
+}
@@ -0,0 +1,103 @@
+package accesspolicy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetGrants(t *testing.T) {
+	owner := Workload{Name: "a", Namespace: "ns"}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	accessPolicy := &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{
+			{Application: "b", ExpiresAt: &metav1.Time{Time: now.Add(2 * time.Hour)}},
+			{Application: "c"},
+		},
+		Outbound: []v1alpha.AccessPolicyRule{
+			{Application: "d", Namespace: "other", ExpiresAt: &metav1.Time{Time: now.Add(time.Hour)}},
+		},
+	}
+
+	assert.Equal(t, []Grant{
+		{
+			Rule:      Rule{Direction: DirectionOutbound, Owner: owner, Peer: Workload{Name: "d", Namespace: "other"}},
+			ExpiresAt: now.Add(time.Hour),
+		},
+		{
+			Rule:      Rule{Direction: DirectionInbound, Owner: owner, Peer: Workload{Name: "b", Namespace: "ns"}},
+			ExpiresAt: now.Add(2 * time.Hour),
+		},
+	}, GetGrants(owner, accessPolicy))
+	assert.Nil(t, GetGrants(owner, nil))
+}
+
+func TestGrantExpired(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	grant := Grant{ExpiresAt: now}
+
+	assert.False(t, grant.Expired(now.Add(-time.Second)))
+	assert.True(t, grant.Expired(now))
+	assert.True(t, grant.Expired(now.Add(time.Second)))
+}
+
+func TestMergeAccessPolicy(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	applicationAccessPolicy := outbound(podtypes.InternalRule{Application: "b"})
+	securityConfigAccessPolicy := &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{
+			{Application: "c", ExpiresAt: &metav1.Time{Time: now.Add(time.Hour)}},
+			{Application: "d", ExpiresAt: &metav1.Time{Time: now.Add(-time.Hour)}},
+		},
+		Outbound: []v1alpha.AccessPolicyRule{
+			{Application: "e", Namespace: "other"},
+		},
+	}
+
+	merged := MergeAccessPolicy(applicationAccessPolicy, securityConfigAccessPolicy, now)
+	assert.Equal(t, &podtypes.AccessPolicy{
+		Inbound: &podtypes.InboundPolicy{Rules: []podtypes.InternalRule{{Application: "c"}}},
+		Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{
+			{Application: "b"},
+			{Application: "e", Namespace: "other"},
+		}},
+	}, merged)
+	assert.Len(t, applicationAccessPolicy.Outbound.Rules, 1, "the access policy of the Application must not be modified")
+}
+
+func TestMergeAccessPolicy_AllExpired(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	securityConfigAccessPolicy := &v1alpha.AccessPolicy{
+		Outbound: []v1alpha.AccessPolicyRule{{Application: "b", ExpiresAt: &metav1.Time{Time: now}}},
+	}
+
+	assert.Nil(t, MergeAccessPolicy(nil, securityConfigAccessPolicy, now))
+	assert.Nil(t, MergeAccessPolicy(nil, nil, now))
+}
+
+func TestNewIndex_IncludesSecurityConfigRules(t *testing.T) {
+	caller := Workload{Name: "caller", Namespace: "ns"}
+	target := Workload{Name: "target", Namespace: "ns"}
+
+	securityConfig := getSecurityConfig("target", "ns")
+	securityConfig.Spec.Tokenx.AccessPolicy = &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{
+			{Application: "caller", ExpiresAt: &metav1.Time{Time: time.Now().Add(time.Hour)}},
+		},
+	}
+	index := NewIndex(
+		[]v1alpha1.Application{
+			getApplication("caller", "ns", outbound(podtypes.InternalRule{Application: "target"})),
+			getApplication("target", "ns", nil),
+		},
+		[]v1alpha.SecurityConfig{getSecurityConfig("caller", "ns"), securityConfig},
+	)
+
+	assert.Empty(t, index.GetOneSidedRules(caller, index.policies[caller]))
+	assert.Equal(t, []Workload{caller}, index.GetCounterparts(target))
+}
@@ -0,0 +1,145 @@
+package accessrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Targets reports whether the AccessRequest requests access to the application of the SecurityConfig.
+func Targets(request v1alpha.AccessRequest, securityConfig v1alpha.SecurityConfig) bool {
+	return request.Spec.Target.Name == securityConfig.Name && request.Spec.Target.Namespace == securityConfig.Namespace
+}
+
+// Approves reports whether the AccessApproval approves the AccessRequest. The approval must be in the namespace of
+// the target SecurityConfig, must match both the calling application and the target of the request, and must approve
+// the current generation of the request, so that e.g. removing or extending the expiry of the request after it was
+// approved revokes the access.
+func Approves(approval v1alpha.AccessApproval, request v1alpha.AccessRequest) bool {
+	return approval.Spec.AccessRequest.Name == request.Name &&
+		approval.Spec.AccessRequest.Namespace == request.Namespace &&
+		approval.Spec.AccessRequest.Generation == request.Generation &&
+		approval.Spec.Application == request.Spec.ApplicationRef &&
+		approval.Spec.SecurityConfigRef == request.Spec.Target.Name &&
+		approval.Namespace == request.Spec.Target.Namespace
+}
+
+// GetApproval returns the first of approvals that approves the AccessRequest, or nil if there is none.
+func GetApproval(request v1alpha.AccessRequest, approvals []v1alpha.AccessApproval) *v1alpha.AccessApproval {
+	for i, approval := range approvals {
+		if Approves(approval, request) {
+			return &approvals[i]
+		}
+	}
+	return nil
+}
+
+// GetExpiresAt returns the earliest expiry of the AccessRequest and its AccessApproval, or nil if neither expires.
+func GetExpiresAt(request v1alpha.AccessRequest, approval v1alpha.AccessApproval) *metav1.Time {
+	switch {
+	case request.Spec.ExpiresAt == nil:
+		return approval.Spec.ExpiresAt
+	case approval.Spec.ExpiresAt == nil:
+		return request.Spec.ExpiresAt
+	case approval.Spec.ExpiresAt.Before(request.Spec.ExpiresAt):
+		return approval.Spec.ExpiresAt
+	default:
+		return request.Spec.ExpiresAt
+	}
+}
+
+// IsExpired reports whether expiresAt is set and has passed at now.
+func IsExpired(expiresAt *metav1.Time, now time.Time) bool {
+	return expiresAt != nil && !now.Before(expiresAt.Time)
+}
+
+// IsValidated reports whether the AccessRequestReconciler has validated the current generation of the AccessRequest and
+// found it approved. Expired requests are included so their expired rules can be reported. An approval does not grant
+// access to a request that is invalid or not validated yet.
+func IsValidated(request v1alpha.AccessRequest) bool {
+	if request.Status.ObservedGeneration != request.Generation {
+		return false
+	}
+	return request.Status.Phase == v1alpha.AccessRequestPhaseApproved || request.Status.Phase == v1alpha.AccessRequestPhaseExpired
+}
+
+// GetApprovalName returns the namespace and name of the AccessApproval in the form used in AccessRequest status.
+func GetApprovalName(approval v1alpha.AccessApproval) string {
+	return fmt.Sprintf("%s/%s", approval.Namespace, approval.Name)
+}
+
+// GetAccessPolicy returns the TokenX access policy of the SecurityConfig extended with an inbound rule for each
+// approved AccessRequest targeting it that is validated. The SecurityConfig is not modified.
+func GetAccessPolicy(
+	securityConfig v1alpha.SecurityConfig,
+	requests []v1alpha.AccessRequest,
+	approvals []v1alpha.AccessApproval,
+) *v1alpha.AccessPolicy {
+	if securityConfig.Spec.Tokenx == nil {
+		return nil
+	}
+	accessPolicy := securityConfig.Spec.Tokenx.AccessPolicy
+
+	var inboundRules []v1alpha.AccessPolicyRule
+	for _, request := range requests {
+		if !Targets(request, securityConfig) || !IsValidated(request) {
+			continue
+		}
+		approval := GetApproval(request, approvals)
+		if approval == nil {
+			continue
+		}
+		inboundRules = append(inboundRules, v1alpha.AccessPolicyRule{
+			Application: request.Spec.ApplicationRef,
+			Namespace:   request.Namespace,
+			ExpiresAt:   GetExpiresAt(request, *approval),
+		})
+	}
+	if len(inboundRules) == 0 {
+		return accessPolicy
+	}
+
+	merged := &v1alpha.AccessPolicy{}
+	if accessPolicy != nil {
+		merged = accessPolicy.DeepCopy()
+	}
+	merged.Inbound = append(merged.Inbound, inboundRules...)
+	return merged
+}
+
+// WithApprovedAccessRequests returns a copy of the SecurityConfig whose TokenX access policy includes the inbound rules
+// of the approved AccessRequests targeting it.
+func WithApprovedAccessRequests(
+	securityConfig v1alpha.SecurityConfig,
+	requests []v1alpha.AccessRequest,
+	approvals []v1alpha.AccessApproval,
+) v1alpha.SecurityConfig {
+	if securityConfig.Spec.Tokenx == nil {
+		return securityConfig
+	}
+	withRequests := *securityConfig.DeepCopy()
+	withRequests.Spec.Tokenx.AccessPolicy = GetAccessPolicy(securityConfig, requests, approvals)
+	return withRequests
+}
+
+// List returns all AccessRequests in the cluster, and the AccessApprovals in approvalNamespace. AccessApprovals in all
+// namespaces are listed if approvalNamespace is empty.
+func List(
+	ctx context.Context,
+	k8sClient client.Reader,
+	approvalNamespace string,
+) ([]v1alpha.AccessRequest, []v1alpha.AccessApproval, error) {
+	var accessRequestList v1alpha.AccessRequestList
+	if err := k8sClient.List(ctx, &accessRequestList); err != nil {
+		return nil, nil, fmt.Errorf("failed to list AccessRequest resources: %w", err)
+	}
+	var accessApprovalList v1alpha.AccessApprovalList
+	if err := k8sClient.List(ctx, &accessApprovalList, client.InNamespace(approvalNamespace)); err != nil {
+		return nil, nil, fmt.Errorf("failed to list AccessApproval resources: %w", err)
+	}
+	return accessRequestList.Items, accessApprovalList.Items, nil
+}
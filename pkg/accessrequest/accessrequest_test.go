@@ -0,0 +1,163 @@
+package accessrequest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getSecurityConfig() v1alpha.SecurityConfig {
+	return v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "target-sc", Namespace: "target-ns"},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: "target",
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
+		},
+	}
+}
+
+func getAccessRequest() v1alpha.AccessRequest {
+	return v1alpha.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "caller-ns", Generation: 1},
+		Spec: v1alpha.AccessRequestSpec{
+			ApplicationRef: "caller",
+			Target:         v1alpha.SecurityConfigReference{Name: "target-sc", Namespace: "target-ns"},
+		},
+		Status: v1alpha.AccessRequestStatus{ObservedGeneration: 1, Phase: v1alpha.AccessRequestPhaseApproved},
+	}
+}
+
+func getAccessApproval() v1alpha.AccessApproval {
+	return v1alpha.AccessApproval{
+		ObjectMeta: metav1.ObjectMeta{Name: "approval", Namespace: "target-ns"},
+		Spec: v1alpha.AccessApprovalSpec{
+			AccessRequest:     v1alpha.AccessRequestReference{Name: "request", Namespace: "caller-ns", Generation: 1},
+			Application:       "caller",
+			SecurityConfigRef: "target-sc",
+		},
+	}
+}
+
+func TestApproves(t *testing.T) {
+	request := getAccessRequest()
+	assert.True(t, Approves(getAccessApproval(), request))
+
+	wrongNamespace := getAccessApproval()
+	wrongNamespace.Namespace = "caller-ns"
+	assert.False(t, Approves(wrongNamespace, request), "approvals must be made in the namespace of the target")
+
+	changedApplication := getAccessRequest()
+	changedApplication.Spec.ApplicationRef = "another-caller"
+	assert.False(t, Approves(getAccessApproval(), changedApplication), "changing the request must invalidate the approval")
+
+	changedTarget := getAccessRequest()
+	changedTarget.Spec.Target.Name = "another-sc"
+	assert.False(t, Approves(getAccessApproval(), changedTarget), "changing the request must invalidate the approval")
+}
+
+func TestGetAccessPolicy_RequestChangedAfterApproval(t *testing.T) {
+	securityConfig := getSecurityConfig()
+	request := getAccessRequest()
+	request.Spec.ExpiresAt = &metav1.Time{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	approval := getAccessApproval()
+	require.Len(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{request}, []v1alpha.AccessApproval{approval}).Inbound, 1)
+
+	removedExpiry := *request.DeepCopy()
+	removedExpiry.Spec.ExpiresAt = nil
+	removedExpiry.Generation = 2
+	removedExpiry.Status.ObservedGeneration = 2
+	assert.Nil(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{removedExpiry}, []v1alpha.AccessApproval{approval}),
+		"removing the expiry of an approved request must revoke the access")
+
+	extendedExpiry := *request.DeepCopy()
+	extendedExpiry.Spec.ExpiresAt = &metav1.Time{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	extendedExpiry.Generation = 2
+	extendedExpiry.Status.ObservedGeneration = 2
+	assert.Nil(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{extendedExpiry}, []v1alpha.AccessApproval{approval}),
+		"extending the expiry of an approved request must revoke the access")
+
+	approval.Spec.AccessRequest.Generation = 2
+	assert.Len(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{extendedExpiry}, []v1alpha.AccessApproval{approval}).Inbound, 1,
+		"approving the new generation must grant the access again")
+}
+
+func TestGetExpiresAt(t *testing.T) {
+	early := &metav1.Time{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	late := &metav1.Time{Time: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+
+	request := getAccessRequest()
+	approval := getAccessApproval()
+	assert.Nil(t, GetExpiresAt(request, approval))
+
+	request.Spec.ExpiresAt = late
+	assert.Equal(t, late, GetExpiresAt(request, approval))
+
+	approval.Spec.ExpiresAt = early
+	assert.Equal(t, early, GetExpiresAt(request, approval))
+
+	request.Spec.ExpiresAt = nil
+	assert.Equal(t, early, GetExpiresAt(request, approval))
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, IsExpired(nil, now))
+	assert.False(t, IsExpired(&metav1.Time{Time: now.Add(time.Second)}, now))
+	assert.True(t, IsExpired(&metav1.Time{Time: now}, now))
+}
+
+func TestGetAccessPolicy(t *testing.T) {
+	securityConfig := getSecurityConfig()
+	securityConfig.Spec.Tokenx.AccessPolicy = &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{{Application: "existing"}},
+	}
+	expiresAt := &metav1.Time{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	approved := getAccessRequest()
+	approved.Spec.ExpiresAt = expiresAt
+	pending := getAccessRequest()
+	pending.Name = "pending"
+	otherTarget := getAccessRequest()
+	otherTarget.Spec.Target.Name = "another-sc"
+	invalid := getAccessRequest()
+	invalid.Status.Phase = v1alpha.AccessRequestPhaseInvalid
+	notValidated := getAccessRequest()
+	notValidated.Status = v1alpha.AccessRequestStatus{}
+	outdated := getAccessRequest()
+	outdated.Status.ObservedGeneration = 0
+
+	accessPolicy := GetAccessPolicy(
+		securityConfig,
+		[]v1alpha.AccessRequest{approved, pending, otherTarget, invalid, notValidated, outdated},
+		[]v1alpha.AccessApproval{getAccessApproval()},
+	)
+	assert.Equal(t, &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{
+			{Application: "existing"},
+			{Application: "caller", Namespace: "caller-ns", ExpiresAt: expiresAt},
+		},
+	}, accessPolicy)
+	assert.Len(t, securityConfig.Spec.Tokenx.AccessPolicy.Inbound, 1, "the SecurityConfig must not be modified")
+}
+
+func TestGetAccessPolicy_NoApprovals(t *testing.T) {
+	securityConfig := getSecurityConfig()
+	assert.Nil(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{getAccessRequest()}, nil))
+
+	securityConfig.Spec.Tokenx = nil
+	assert.Nil(t, GetAccessPolicy(securityConfig, []v1alpha.AccessRequest{getAccessRequest()}, []v1alpha.AccessApproval{getAccessApproval()}))
+}
+
+func TestWithApprovedAccessRequests(t *testing.T) {
+	securityConfig := getSecurityConfig()
+	withRequests := WithApprovedAccessRequests(
+		securityConfig,
+		[]v1alpha.AccessRequest{getAccessRequest()},
+		[]v1alpha.AccessApproval{getAccessApproval()},
+	)
+	assert.Equal(t, []v1alpha.AccessPolicyRule{{Application: "caller", Namespace: "caller-ns"}}, withRequests.Spec.Tokenx.AccessPolicy.Inbound)
+	assert.Nil(t, securityConfig.Spec.Tokenx.AccessPolicy)
+}
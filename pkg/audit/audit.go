@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ActorAccesserator is the actor of the changes Accesserator makes on its own, as opposed to on behalf of a user
+// creating a pod.
+const ActorAccesserator = "accesserator"
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	// ActionRequestInjection is recorded when the mutating webhook returns the mutation of a pod. The pod may still
+	// be rejected afterwards, e.g. by the validating webhook, which is then recorded as ActionRejectPod.
+	ActionRequestInjection = "request-injection"
+	ActionRejectPod        = "reject-pod"
+)
+
+// Entry is a security-relevant change made by Accesserator.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Actor  string    `json:"actor"`
+	Object ObjectRef `json:"object"`
+	Reason string    `json:"reason"`
+	// CorrelationID is the ID of the reconciliation or admission request that made the change.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Before and After are the specs of the object before and after the change.
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+// ObjectRef identifies the object that was changed.
+type ObjectRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// Sink writes audit entries.
+type Sink interface {
+	Write(entry Entry) error
+	// Close writes the entries that are still buffered and releases the sink. Entries written after Close are lost.
+	Close(ctx context.Context) error
+}
+
+var (
+	mu   sync.RWMutex
+	sink Sink = noopSink{}
+)
+
+// SetSink replaces the sink audit entries are written to.
+func SetSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sink = s
+}
+
+// Close closes the audit sink, so the entries it still buffers are written before Accesserator exits.
+func Close(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	err := sink.Close(ctx)
+	sink = noopSink{}
+	return err
+}
+
+// Record writes the entry to the audit sink, setting the time and correlation ID if they are not set. Failing to
+// write the entry is logged rather than failing the change. Nothing is recorded for dry-run admission requests, since
+// they change nothing.
+func Record(ctx context.Context, entry Entry) {
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.DryRun != nil && *req.DryRun {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if entry.CorrelationID == "" {
+		entry.CorrelationID = GetCorrelationID(ctx)
+	}
+	mu.RLock()
+	s := sink
+	mu.RUnlock()
+	if err := s.Write(entry); err != nil {
+		ctrl.Log.WithName("audit").Error(err, "failed to write audit entry", "action", entry.Action, "object", entry.Object)
+	}
+}
+
+// GetCorrelationID returns the ID of the reconciliation or admission request in the context, falling back to the
+// trace ID.
+func GetCorrelationID(ctx context.Context) string {
+	if reconcileID := controller.ReconcileIDFromContext(ctx); reconcileID != "" {
+		return string(reconcileID)
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.UID != "" {
+		return string(req.UID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+// GetRequester returns the user that made the admission request in the context, or ActorAccesserator if there is
+// none.
+func GetRequester(ctx context.Context) string {
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.UserInfo.Username != "" {
+		return req.UserInfo.Username
+	}
+	return ActorAccesserator
+}
+
+// GetObjectRef returns the reference to the object, resolving its kind from the scheme if it is not set.
+func GetObjectRef(obj client.Object, scheme *runtime.Scheme) ObjectRef {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" && scheme != nil {
+		if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+			kind = gvk.Kind
+		}
+	}
+	name := obj.GetName()
+	if name == "" {
+		name = obj.GetGenerateName()
+	}
+	return ObjectRef{Kind: kind, Namespace: obj.GetNamespace(), Name: name}
+}
+
+// GetSpec returns the spec of the object for the before and after of an entry, or nil if it has none.
+func GetSpec(obj client.Object) any {
+	if obj == nil {
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil
+	}
+	return content["spec"]
+}
+
+type noopSink struct{}
+
+func (noopSink) Write(Entry) error { return nil }
+
+func (noopSink) Close(context.Context) error { return nil }
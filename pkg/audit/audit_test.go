@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func setSink(t *testing.T, s Sink) {
+	SetSink(s)
+	t.Cleanup(func() { SetSink(noopSink{}) })
+}
+
+func decode(t *testing.T, line []byte) map[string]any {
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(line, &entry))
+	return entry
+}
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	setSink(t, NewWriterSink(&buf))
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:      types.UID("request-uid"),
+			UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:replicaset-controller"},
+		},
+	})
+
+	Record(ctx, Entry{
+		Action: ActionRejectPod,
+		Actor:  GetRequester(ctx),
+		Object: ObjectRef{Kind: "Pod", Namespace: "ns", Name: "app-"},
+		Reason: "texas init container is missing",
+	})
+
+	entry := decode(t, buf.Bytes())
+	assert.Equal(t, "reject-pod", entry["action"])
+	assert.Equal(t, "system:serviceaccount:kube-system:replicaset-controller", entry["actor"])
+	assert.Equal(t, "request-uid", entry["correlationId"])
+	assert.Equal(t, map[string]any{"kind": "Pod", "namespace": "ns", "name": "app-"}, entry["object"])
+	assert.NotEmpty(t, entry["time"])
+	assert.NotContains(t, entry, "before")
+}
+
+func TestRecord_DryRun(t *testing.T) {
+	var buf bytes.Buffer
+	setSink(t, NewWriterSink(&buf))
+	dryRun := true
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UID: types.UID("request-uid"), DryRun: &dryRun},
+	})
+
+	Record(ctx, Entry{Action: ActionRequestInjection, Object: ObjectRef{Kind: "Pod", Namespace: "ns", Name: "app-"}})
+	assert.Empty(t, buf.String(), "dry-run admission requests must not be recorded")
+}
+
+func TestGetObjectRefAndSpec(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, networkingv1.AddToScheme(scheme))
+	networkPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "egress", Namespace: "ns"},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}},
+	}
+
+	assert.Equal(t, ObjectRef{Kind: "NetworkPolicy", Namespace: "ns", Name: "egress"}, GetObjectRef(networkPolicy, scheme))
+	assert.Equal(t, map[string]any{
+		"podSelector": map[string]any{},
+		"policyTypes": []any{"Egress"},
+	}, GetSpec(networkPolicy))
+	assert.Equal(t, ActorAccesserator, GetRequester(context.Background()))
+}
+
+func TestHttpSink(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewHttpSink(server.URL).Write(Entry{Action: ActionCreate, Time: time.Now()}))
+	select {
+	case body := <-received:
+		assert.Equal(t, "create", decode(t, body)["action"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("the entry was not posted")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.Error(t, NewHttpSink(failing.URL).post(Entry{Action: ActionCreate}))
+}
+
+func TestHttpSink_QueueFull(t *testing.T) {
+	dropped := testutil.ToFloat64(metrics.AuditEntriesDropped(SinkHttp))
+	s := &HttpSink{entries: make(chan Entry, 1)}
+	require.NoError(t, s.Write(Entry{Action: ActionCreate}))
+	assert.Error(t, s.Write(Entry{Action: ActionDelete}), "entries must be dropped rather than block when the queue is full")
+	assert.Equal(t, dropped+1, testutil.ToFloat64(metrics.AuditEntriesDropped(SinkHttp)), "dropped entries must be counted")
+}
+
+func TestHttpSink_Close(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		actions = append(actions, decode(t, body)["action"].(string))
+	}))
+	defer server.Close()
+
+	s := NewHttpSink(server.URL)
+	require.NoError(t, s.Write(Entry{Action: ActionCreate}))
+	require.NoError(t, s.Write(Entry{Action: ActionUpdate}))
+	require.NoError(t, s.Write(Entry{Action: ActionDelete}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Close(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{ActionCreate, ActionUpdate, ActionDelete}, actions, "the queued entries must be posted on close")
+	assert.Error(t, s.Write(Entry{Action: ActionCreate}), "entries written after close must be reported as dropped")
+}
+
+func TestSetup_File(t *testing.T) {
+	t.Cleanup(func() { SetSink(noopSink{}) })
+	path := filepath.Join(t.TempDir(), "audit.log")
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	t.Setenv("ACCESSERATOR_AUDIT_SINK", "file")
+	t.Setenv("ACCESSERATOR_AUDIT_FILE_PATH", path)
+	require.NoError(t, config.Load())
+	require.NoError(t, Setup())
+
+	Record(context.Background(), Entry{Action: ActionDelete})
+	Record(context.Background(), Entry{Action: ActionCreate})
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Equal(t, "delete", decode(t, lines[0])["action"])
+	assert.Equal(t, "create", decode(t, lines[1])["action"])
+	require.NoError(t, Close(context.Background()))
+
+	t.Setenv("ACCESSERATOR_AUDIT_SINK", "syslog")
+	require.NoError(t, config.Load())
+	assert.Error(t, Setup())
+}
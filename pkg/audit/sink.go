@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/metrics"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	SinkNone   = "none"
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkHttp   = "http"
+
+	httpSinkTimeout    = 2 * time.Second
+	httpSinkBufferSize = 1024
+)
+
+// Setup sets the sink from the config. The default none sink discards the entries.
+func Setup() error {
+	cfg := config.Get()
+	switch cfg.AuditSink {
+	case SinkNone, "":
+		SetSink(noopSink{})
+	case SinkStdout:
+		SetSink(NewWriterSink(os.Stdout))
+	case SinkFile:
+		if cfg.AuditFilePath == "" {
+			return fmt.Errorf("ACCESSERATOR_AUDIT_FILE_PATH must be set for the %s audit sink", SinkFile)
+		}
+		file, err := os.OpenFile(cfg.AuditFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit file: %w", err)
+		}
+		SetSink(NewFileSink(file))
+	case SinkHttp:
+		if cfg.AuditHttpEndpoint == "" {
+			return fmt.Errorf("ACCESSERATOR_AUDIT_HTTP_ENDPOINT must be set for the %s audit sink", SinkHttp)
+		}
+		SetSink(NewHttpSink(cfg.AuditHttpEndpoint))
+	default:
+		return fmt.Errorf(
+			"invalid audit sink %q, must be one of %s, %s, %s or %s",
+			cfg.AuditSink,
+			SinkNone,
+			SinkStdout,
+			SinkFile,
+			SinkHttp,
+		)
+	}
+	return nil
+}
+
+// WriterSink writes the entries as JSON lines.
+type WriterSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	// file is synced and closed by Close if the sink writes to a file.
+	file *os.File
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{encoder: json.NewEncoder(w)}
+}
+
+// NewFileSink returns a WriterSink appending to the file, which is synced and closed when the sink is closed.
+func NewFileSink(file *os.File) *WriterSink {
+	return &WriterSink{encoder: json.NewEncoder(file), file: file}
+}
+
+func (s *WriterSink) Write(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.encoder.Encode(entry); err != nil {
+		metrics.RecordAuditEntryDropped(SinkFile)
+		return err
+	}
+	return nil
+}
+
+func (s *WriterSink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	file := s.file
+	s.file = nil
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync audit file: %w", err)
+	}
+	return file.Close()
+}
+
+// HttpSink posts every entry as a JSON line to a collector. The entries are posted in the background, so a slow
+// collector does not hold up the reconciliations and admission requests recording them.
+type HttpSink struct {
+	endpoint string
+	client   *http.Client
+	// mu guards closed, so that no entry is sent on entries once it is closed.
+	mu      sync.RWMutex
+	closed  bool
+	entries chan Entry
+	done    chan struct{}
+}
+
+func NewHttpSink(endpoint string) *HttpSink {
+	s := &HttpSink{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: httpSinkTimeout},
+		entries:  make(chan Entry, httpSinkBufferSize),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write queues the entry to be posted. The entry is dropped and counted in the
+// accesserator_audit_entries_dropped_total metric if the queue is full, e.g. because the collector is down, or if
+// the sink is closed.
+func (s *HttpSink) Write(entry Entry) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		metrics.RecordAuditEntryDropped(SinkHttp)
+		return errors.New("audit entry dropped since the http sink is closed")
+	}
+	select {
+	case s.entries <- entry:
+		return nil
+	default:
+		metrics.RecordAuditEntryDropped(SinkHttp)
+		return errors.New("audit entry dropped since the queue of the http sink is full")
+	}
+}
+
+// Close stops accepting entries and waits until the queued entries are posted, or until ctx is done. The entries that
+// are not posted by then are counted as dropped.
+func (s *HttpSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.entries)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		dropped := len(s.entries)
+		for range dropped {
+			metrics.RecordAuditEntryDropped(SinkHttp)
+		}
+		return fmt.Errorf("%d audit entries were not posted before the http sink was closed: %w", dropped, ctx.Err())
+	}
+}
+
+func (s *HttpSink) run() {
+	defer close(s.done)
+	for entry := range s.entries {
+		if err := s.post(entry); err != nil {
+			metrics.RecordAuditEntryDropped(SinkHttp)
+			ctrl.Log.WithName("audit").Error(err, "failed to write audit entry", "action", entry.Action, "object", entry.Object)
+		}
+	}
+}
+
+func (s *HttpSink) post(entry Entry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.endpoint, bytes.NewReader(append(body, '\n')))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit entry: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("audit collector responded with %s", resp.Status)
+	}
+	return nil
+}
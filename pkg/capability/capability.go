@@ -0,0 +1,132 @@
+package capability
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/reconciliation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Capability is something a SecurityConfig can enable for its application, such as TokenX. The controller, the pod
+// webhooks and the resolver iterate over the registered capabilities, so a new capability only has to implement this
+// interface and register itself.
+type Capability interface {
+	// Name is the name of the capability, as used in the egress endpoint catalog.
+	Name() string
+	// Enabled reports whether the SecurityConfig enables the capability.
+	Enabled(securityConfig v1alpha.SecurityConfig) bool
+	// Resolve adds the state of the capability to the scope. It is only called when the capability is enabled, and
+	// the access policy index includes the SecurityConfig of the scope.
+	Resolve(ctx context.Context, k8sClient client.Client, scope *state.Scope, accessPolicyIndex *accesspolicy.Index) error
+	// DesiredResources returns the descendants of the SecurityConfig. It is also called when the capability is
+	// disabled, and resources that are not desired are returned with a nil desired resource, so they are deleted.
+	DesiredResources(scope *state.Scope) []reconciliation.ControllerResource
+	// PodMutation changes a pod of the application when it is created. It is only called when the capability is
+	// enabled.
+	PodMutation(ctx context.Context, pod *corev1.Pod, securityConfig v1alpha.SecurityConfig, appName string) error
+	// PodValidation checks that a pod of the application has been mutated as expected. It is only called when the
+	// capability is enabled, and errors returned by Reject are reported with their reason.
+	PodValidation(ctx context.Context, pod *corev1.Pod, securityConfig v1alpha.SecurityConfig, appName string) error
+	// Status returns the status of the capability once its descendants are reconciled. It is only called when the
+	// capability is enabled.
+	Status(ctx context.Context, k8sClient client.Client, scope *state.Scope, now time.Time) (Status, error)
+}
+
+// Status is the status of an enabled capability.
+type Status struct {
+	// Pending explains why the capability is not ready yet, and is nil when it is ready.
+	Pending *Pending
+	// Conditions are added to the conditions of the SecurityConfig.
+	Conditions []metav1.Condition
+}
+
+// Pending is the reason a capability is not ready yet.
+type Pending struct {
+	// PhaseMessage is the message of the Pending phase of the SecurityConfig.
+	PhaseMessage string
+	// ConditionMessage is the message of the Pending condition of the SecurityConfig.
+	ConditionMessage string
+}
+
+var (
+	mu       sync.RWMutex
+	registry []Capability
+)
+
+// Register makes a capability available to the controller and the pod webhooks. It is meant to be called from the
+// init function of the package implementing the capability, and panics if the name is already registered.
+func Register(c Capability) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, registered := range registry {
+		if registered.Name() == c.Name() {
+			panic(fmt.Sprintf("capability %s is already registered", c.Name()))
+		}
+	}
+	registry = append(registry, c)
+}
+
+// All returns the registered capabilities in the order they were registered.
+func All() []Capability {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Capability(nil), registry...)
+}
+
+// Get returns the registered capability with the name, or nil if there is none.
+func Get(name string) Capability {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range registry {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Enabled returns the registered capabilities that the SecurityConfig enables.
+func Enabled(securityConfig v1alpha.SecurityConfig) []Capability {
+	var enabled []Capability
+	for _, c := range All() {
+		if c.Enabled(securityConfig) {
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled
+}
+
+// IsEnabled reports whether the named capability is registered and resolved as enabled for the scope. Like Resolve,
+// capabilities are never enabled for an orphaned or invalid SecurityConfig, so their resources are deleted. Names
+// without a registered capability, such as maskinporten, idporten or entraid, are never enabled.
+func IsEnabled(name string, scope *state.Scope) bool {
+	c := Get(name)
+	return c != nil && !scope.Orphaned && !scope.InvalidConfig && c.Enabled(scope.SecurityConfig)
+}
+
+// RejectionError is an error rejecting the admission of a pod, with a reason used as a metric label.
+type RejectionError struct {
+	Reason string
+	Err    error
+}
+
+// Reject returns an error rejecting the admission of a pod for the reason.
+func Reject(reason string, err error) error {
+	return &RejectionError{Reason: reason, Err: err}
+}
+
+func (e *RejectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectionError) Unwrap() error {
+	return e.Err
+}
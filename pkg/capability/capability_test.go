@@ -0,0 +1,86 @@
+package capability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/reconciliation"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeCapability struct {
+	name    string
+	enabled bool
+}
+
+func (f fakeCapability) Name() string                          { return f.name }
+func (f fakeCapability) Enabled(_ v1alpha.SecurityConfig) bool { return f.enabled }
+func (f fakeCapability) Resolve(context.Context, client.Client, *state.Scope, *accesspolicy.Index) error {
+	return nil
+}
+func (f fakeCapability) DesiredResources(*state.Scope) []reconciliation.ControllerResource {
+	return nil
+}
+func (f fakeCapability) PodMutation(context.Context, *corev1.Pod, v1alpha.SecurityConfig, string) error {
+	return nil
+}
+func (f fakeCapability) PodValidation(context.Context, *corev1.Pod, v1alpha.SecurityConfig, string) error {
+	return nil
+}
+func (f fakeCapability) Status(context.Context, client.Client, *state.Scope, time.Time) (Status, error) {
+	return Status{}, nil
+}
+
+// withRegistry replaces the registry for the duration of the test.
+func withRegistry(t *testing.T, capabilities ...Capability) {
+	previous := registry
+	registry = capabilities
+	t.Cleanup(func() { registry = previous })
+}
+
+func TestRegister(t *testing.T) {
+	withRegistry(t)
+	first := fakeCapability{name: "first", enabled: true}
+	second := fakeCapability{name: "second"}
+	Register(first)
+	Register(second)
+
+	assert.Equal(t, []Capability{first, second}, All())
+	assert.Equal(t, second, Get("second"))
+	assert.Nil(t, Get("missing"))
+	assert.Equal(t, []Capability{first}, Enabled(v1alpha.SecurityConfig{}))
+	assert.PanicsWithValue(t, "capability first is already registered", func() { Register(first) })
+}
+
+func TestAll_ReturnsCopy(t *testing.T) {
+	withRegistry(t, fakeCapability{name: "first"})
+	All()[0] = fakeCapability{name: "changed"}
+	assert.Equal(t, "first", All()[0].Name())
+}
+
+func TestIsEnabled(t *testing.T) {
+	withRegistry(t, fakeCapability{name: "enabled", enabled: true}, fakeCapability{name: "disabled"})
+
+	assert.True(t, IsEnabled("enabled", &state.Scope{}))
+	assert.False(t, IsEnabled("disabled", &state.Scope{}))
+	assert.False(t, IsEnabled("missing", &state.Scope{}))
+	assert.False(t, IsEnabled("enabled", &state.Scope{Orphaned: true}))
+	assert.False(t, IsEnabled("enabled", &state.Scope{InvalidConfig: true}))
+}
+
+func TestReject(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", Reject("reason", errors.New("rejected")))
+
+	var rejection *RejectionError
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, "reason", rejection.Reason)
+	assert.Equal(t, "wrapped: rejected", err.Error())
+}
@@ -0,0 +1,108 @@
+package tokenx
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	TexasInitContainerName = "texas"
+	TexasPortName          = "http"
+
+	MaskinportenEnabledEnvVarName = "MASKINPORTEN_ENABLED"
+	AzureEnabledEnvVarName        = "AZURE_ENABLED"
+	IdportenEnabledEnvVarName     = "IDPORTEN_ENABLED"
+	TokenXEnabledEnvVarName       = "TOKEN_X_ENABLED"
+)
+
+// GetTexasContainer returns the Texas init container injected into the pods of the application of the SecurityConfig.
+func GetTexasContainer(securityConfig v1alpha.SecurityConfig) (*corev1.Container, error) {
+	if securityConfig.Spec.Tokenx == nil || !securityConfig.Spec.Tokenx.Enabled {
+		return nil, fmt.Errorf("a texas container should not be created if tokenx is not enabled")
+	}
+
+	texasImageUrl := fmt.Sprintf(
+		"%s:%s",
+		config.Get().TexasImageName,
+		config.Get().TexasImageTag,
+	)
+	expectedJwkerSecretName := naming.GetJwkerSecretName(
+		naming.GetJwkerName(securityConfig.Spec.ApplicationRef),
+	)
+
+	return &corev1.Container{
+		Name:  TexasInitContainerName,
+		Image: texasImageUrl,
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: config.Get().TexasPort,
+				Name:          TexasPortName,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		// NOTE: RestartPolicy Always is only available for init containers in Kubernetes v1.33+
+		// https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#detailed-behavior
+		RestartPolicy: utilities.Ptr(corev1.ContainerRestartPolicyAlways),
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: utilities.Ptr(false),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{
+					"ALL",
+				},
+				Add: []corev1.Capability{
+					"NET_BIND_SERVICE",
+				},
+			},
+			Privileged:             utilities.Ptr(false),
+			ReadOnlyRootFilesystem: utilities.Ptr(true),
+			RunAsGroup:             utilities.Ptr(int64(150)),
+			RunAsNonRoot:           utilities.Ptr(true),
+			RunAsUser:              utilities.Ptr(int64(150)),
+		},
+		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		Env: []corev1.EnvVar{
+			{
+				Name:  TokenXEnabledEnvVarName,
+				Value: "true",
+			},
+			{
+				Name:  MaskinportenEnabledEnvVarName,
+				Value: "false",
+			},
+			{
+				Name:  AzureEnabledEnvVarName,
+				Value: "false",
+			},
+			{
+				Name:  IdportenEnabledEnvVarName,
+				Value: "false",
+			},
+		},
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: expectedJwkerSecretName}}}},
+	}, nil
+}
+
+// IsTexasContainerEqual compares the fields of the Texas containers that are set by GetTexasContainer.
+func IsTexasContainerEqual(expected, actual corev1.Container) bool {
+	return expected.Name == actual.Name &&
+		expected.Image == actual.Image &&
+		reflect.DeepEqual(expected.RestartPolicy, actual.RestartPolicy) &&
+		reflect.DeepEqual(expected.Env, actual.Env) &&
+		reflect.DeepEqual(expected.EnvFrom, actual.EnvFrom) &&
+		reflect.DeepEqual(expected.Ports, actual.Ports) &&
+		reflect.DeepEqual(expected.SecurityContext, actual.SecurityContext) &&
+		reflect.DeepEqual(expected.TerminationMessagePath, actual.TerminationMessagePath) &&
+		reflect.DeepEqual(expected.TerminationMessagePolicy, actual.TerminationMessagePolicy)
+}
+
+// GetTexasUrlEnvVarValue returns the URL of Texas injected into the application container.
+func GetTexasUrlEnvVarValue() string {
+	return fmt.Sprintf("http://localhost:%d", config.Get().TexasPort)
+}
@@ -0,0 +1,299 @@
+package tokenx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/reconciliation"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/tokenx/egress"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/tokenx/jwker"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	rejectionReasonTexasContainerMissing  = "texas_container_missing"
+	rejectionReasonTexasContainerMismatch = "texas_container_mismatch"
+	rejectionReasonTexasUrlMissing        = "texas_url_missing"
+)
+
+func init() {
+	capability.Register(Capability{})
+}
+
+// Capability registers an OAuth client in TokenX through a Jwker, and injects Texas into the pods of the application
+// to exchange tokens with it.
+type Capability struct{}
+
+var _ capability.Capability = Capability{}
+
+func (Capability) Name() string {
+	return state.CapabilityTokenX
+}
+
+func (Capability) Enabled(securityConfig v1alpha.SecurityConfig) bool {
+	return securityConfig.Spec.Tokenx != nil && securityConfig.Spec.Tokenx.Enabled
+}
+
+// Resolve merges the access policy of the Application with the rules of the SecurityConfig and its approved
+// AccessRequests.
+func (Capability) Resolve(
+	ctx context.Context,
+	k8sClient client.Client,
+	scope *state.Scope,
+	accessPolicyIndex *accesspolicy.Index,
+) error {
+	securityConfig := scope.SecurityConfig
+	var skiperatorApplication v1alpha1.Application
+	if err := k8sClient.Get(ctx, types.NamespacedName{
+		Name:      securityConfig.Spec.ApplicationRef,
+		Namespace: securityConfig.Namespace,
+	}, &skiperatorApplication); err != nil {
+		return fmt.Errorf(
+			"failed to fetch Application resource named %s: %w",
+			securityConfig.Spec.ApplicationRef,
+			err,
+		)
+	}
+
+	workload := accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace}
+	// The access policy of the SecurityConfig in the index includes the rules of its approved AccessRequests.
+	securityConfigAccessPolicy := accessPolicyIndex.GetSecurityConfigAccessPolicy(workload)
+
+	// Rules declared in the SecurityConfig or approved through AccessRequests are added to the access policy of the
+	// Application, except for the ones that have expired. This drops expired rules from the generated Jwker.
+	accessPolicy := accesspolicy.MergeAccessPolicy(skiperatorApplication.Spec.AccessPolicy, securityConfigAccessPolicy, time.Now())
+
+	scope.TokenXConfig = state.TokenXConfig{
+		Enabled:       true,
+		AccessPolicy:  accessPolicy,
+		OneSidedRules: accessPolicyIndex.GetOneSidedRules(workload, accessPolicy),
+		AccessGrants:  accesspolicy.GetGrants(workload, securityConfigAccessPolicy),
+		// The stale Jwkers are resolved by the resolver whether or not TokenX is enabled.
+		StaleJwkerNames: scope.TokenXConfig.StaleJwkerNames,
+	}
+	return nil
+}
+
+// GetStaleJwkerNames returns the names of the Jwkers controlled by the SecurityConfig other than the one of its
+// current application, which are left behind when applicationRef is changed. They are stale whether or not TokenX is
+// enabled, and whether or not the SecurityConfig is valid or orphaned.
+func GetStaleJwkerNames(ctx context.Context, k8sClient client.Client, securityConfig v1alpha.SecurityConfig) ([]string, error) {
+	var jwkerList naisiov1.JwkerList
+	if err := k8sClient.List(ctx, &jwkerList, client.InNamespace(securityConfig.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Jwker resources: %w", err)
+	}
+	jwkerName := naming.GetJwkerName(securityConfig.Spec.ApplicationRef)
+	var staleJwkerNames []string
+	for i := range jwkerList.Items {
+		if jwkerList.Items[i].Name != jwkerName && metav1.IsControlledBy(&jwkerList.Items[i], &securityConfig) {
+			staleJwkerNames = append(staleJwkerNames, jwkerList.Items[i].Name)
+		}
+	}
+	return staleJwkerNames, nil
+}
+
+// DesiredResources returns the Jwker and the egress policy allowing the application to reach TokenX. Only the egress
+// policy of the selected egress backend is desired, so switching backend deletes the policy of the previous one. The
+// Jwkers named after a previous applicationRef are not desired either, so they are deleted.
+func (Capability) DesiredResources(scope *state.Scope) []reconciliation.ControllerResource {
+	jwkerObjectMeta := metav1.ObjectMeta{
+		Name:      naming.GetJwkerName(scope.SecurityConfig.Spec.ApplicationRef),
+		Namespace: scope.SecurityConfig.Namespace,
+	}
+	egressObjectMeta := metav1.ObjectMeta{
+		Name:      naming.GetTokenxEgressName(scope.SecurityConfig.Name, config.Get().TokenxName),
+		Namespace: scope.SecurityConfig.Namespace,
+	}
+
+	resources := []reconciliation.ControllerResource{
+		reconciliation.ReconcilerAdapter[*naisiov1.Jwker]{
+			Func: reconciliation.ResourceReconciler[*naisiov1.Jwker]{
+				ResourceKind:    "Jwker",
+				ResourceName:    jwkerObjectMeta.Name,
+				DesiredResource: utilities.Ptr(jwker.GetDesired(jwkerObjectMeta, scope)),
+				Scope:           scope,
+				ShouldUpdate: func(current, desired *naisiov1.Jwker) bool {
+					return !equality.Semantic.DeepEqual(current.Spec, desired.Spec)
+				},
+				UpdateFields: func(current, desired *naisiov1.Jwker) {
+					current.Spec = desired.Spec
+				},
+			},
+		},
+		reconciliation.ReconcilerAdapter[*networkv1.NetworkPolicy]{
+			Func: reconciliation.ResourceReconciler[*networkv1.NetworkPolicy]{
+				ResourceKind:    "NetworkPolicy",
+				ResourceName:    egressObjectMeta.Name,
+				DesiredResource: utilities.Ptr(egress.GetDesired(egressObjectMeta, scope)),
+				Scope:           scope,
+				ShouldUpdate: func(current, desired *networkv1.NetworkPolicy) bool {
+					return !equality.Semantic.DeepEqual(current.Spec, desired.Spec)
+				},
+				UpdateFields: func(current, desired *networkv1.NetworkPolicy) {
+					current.Spec = desired.Spec
+				},
+			},
+		},
+		reconciliation.NewUnstructuredResource(
+			"CiliumNetworkPolicy",
+			egressObjectMeta.Name,
+			egress.GetDesiredCiliumNetworkPolicy(egressObjectMeta, scope),
+			scope,
+			cilium.CiliumNetworkPolicyGVK,
+		),
+		// Sidecars are no longer generated by the istio egress backend, and are only deleted.
+		reconciliation.NewUnstructuredResource("Sidecar", egressObjectMeta.Name, nil, scope, egress.SidecarGVK),
+	}
+	return append(resources, GetStaleJwkerResources(scope)...)
+}
+
+// GetStaleJwkerResources returns the Jwkers named after a previous applicationRef without a desired resource, so they
+// are deleted. The controller also reconciles them for SecurityConfigs whose other descendants are left as they are.
+func GetStaleJwkerResources(scope *state.Scope) []reconciliation.ControllerResource {
+	var resources []reconciliation.ControllerResource
+	for _, name := range scope.TokenXConfig.StaleJwkerNames {
+		resources = append(resources, reconciliation.ReconcilerAdapter[*naisiov1.Jwker]{
+			Func: reconciliation.ResourceReconciler[*naisiov1.Jwker]{
+				ResourceKind: "Jwker",
+				ResourceName: name,
+				Scope:        scope,
+			},
+		})
+	}
+	return resources
+}
+
+// PodMutation injects the Texas init container into the pod, and the URL of Texas into the application container.
+func (Capability) PodMutation(ctx context.Context, pod *corev1.Pod, securityConfig v1alpha.SecurityConfig, appName string) error {
+	texasContainer, err := GetTexasContainer(securityConfig)
+	if err != nil {
+		return err
+	}
+
+	rlog := log.GetLogger(ctx)
+	rlog.Info("Tokenx is enabled, injecting texas init container")
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, *texasContainer)
+
+	rlog.Info("Injecting texas url")
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == appName {
+			pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, corev1.EnvVar{
+				Name:  config.Get().TexasUrlEnvVarName,
+				Value: GetTexasUrlEnvVarValue(),
+			})
+		}
+	}
+	return nil
+}
+
+// PodValidation checks that the pod has the expected Texas init container, and that the application container has
+// the URL of Texas.
+func (Capability) PodValidation(_ context.Context, pod *corev1.Pod, securityConfig v1alpha.SecurityConfig, appName string) error {
+	expectedTexasContainer, err := GetTexasContainer(securityConfig)
+	if err != nil {
+		return err
+	}
+
+	hasTexasInitContainer := false
+	for _, initContainer := range pod.Spec.InitContainers {
+		if initContainer.Name == TexasInitContainerName {
+			hasTexasInitContainer = true
+			if !IsTexasContainerEqual(*expectedTexasContainer, initContainer) {
+				return capability.Reject(
+					rejectionReasonTexasContainerMismatch,
+					fmt.Errorf("texas init container is not as expected given the SecurityConfig"),
+				)
+			}
+			break
+		}
+	}
+	if !hasTexasInitContainer {
+		return capability.Reject(
+			rejectionReasonTexasContainerMissing,
+			fmt.Errorf("TokenX is enabled but init container '%s' is missing", TexasInitContainerName),
+		)
+	}
+
+	hasTexasUrlEnvVar := false
+	for _, container := range pod.Spec.Containers {
+		if container.Name == appName {
+			for _, envVar := range container.Env {
+				if envVar.Name == config.Get().TexasUrlEnvVarName && envVar.Value == GetTexasUrlEnvVarValue() {
+					hasTexasUrlEnvVar = true
+					break
+				}
+			}
+			break
+		}
+	}
+	if !hasTexasUrlEnvVar {
+		return capability.Reject(
+			rejectionReasonTexasUrlMissing,
+			fmt.Errorf(
+				"TokenX is enabled but %s env var is missing for pod from skiperator app with name %s/%s",
+				pod.Namespace,
+				appName,
+				config.Get().TexasUrlEnvVarName,
+			),
+		)
+	}
+	return nil
+}
+
+// Status is pending until the Jwker has registered the OAuth client, and reports whether the access policy rules
+// have a matching rule on the other side.
+func (Capability) Status(ctx context.Context, k8sClient client.Client, scope *state.Scope, now time.Time) (capability.Status, error) {
+	status := capability.Status{
+		Conditions: []metav1.Condition{getAccessPolicyConsistentCondition(scope, now)},
+	}
+	jwkerResource, err := scope.GetJwker(ctx, k8sClient)
+	if err != nil || jwkerResource.Status.SynchronizationState != utilities.JwkerSynchronizationStateReady {
+		status.Pending = &capability.Pending{
+			PhaseMessage: "SecurityConfig pending due to missing TokenX secret.",
+			ConditionMessage: fmt.Sprintf(
+				"Jwker resource with name %s has not finished registering an OAuth client",
+				naming.GetJwkerName(scope.SecurityConfig.Spec.ApplicationRef),
+			),
+		}
+	}
+	return status, err
+}
+
+func getAccessPolicyConsistentCondition(scope *state.Scope, now time.Time) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               v1alpha.ConditionTypeAccessPolicyConsistent,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	if len(scope.TokenXConfig.OneSidedRules) == 0 {
+		v1alpha.SetConditionAccessPolicyConsistent(&cond, "All TokenX access policy rules have a matching rule on the other side.")
+		return cond
+	}
+
+	oneSidedRules := make([]string, 0, len(scope.TokenXConfig.OneSidedRules))
+	for _, rule := range scope.TokenXConfig.OneSidedRules {
+		oneSidedRules = append(oneSidedRules, rule.String())
+	}
+	v1alpha.SetConditionAccessPolicyInconsistent(
+		&cond,
+		fmt.Sprintf("Access policy rules without a matching rule on the other side: %s", strings.Join(oneSidedRules, ", ")),
+	)
+	return cond
+}
@@ -0,0 +1,301 @@
+package tokenx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const appName = "app"
+
+func loadConfig(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	require.NoError(t, config.Load())
+}
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func getSecurityConfig(enabled bool) v1alpha.SecurityConfig {
+	return v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "security-config", Namespace: "ns"},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: appName,
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: enabled},
+		},
+	}
+}
+
+func getPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: appName}}},
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, Capability{}, capability.Get(state.CapabilityTokenX))
+	assert.True(t, Capability{}.Enabled(getSecurityConfig(true)))
+	assert.False(t, Capability{}.Enabled(getSecurityConfig(false)))
+	assert.False(t, Capability{}.Enabled(v1alpha.SecurityConfig{}))
+}
+
+func TestGetTexasContainer(t *testing.T) {
+	loadConfig(t)
+
+	t.Run("returns an error when TokenX is not enabled", func(t *testing.T) {
+		c, err := GetTexasContainer(getSecurityConfig(false))
+		assert.EqualError(t, err, "a texas container should not be created if tokenx is not enabled")
+		assert.Nil(t, c)
+	})
+
+	t.Run("builds the Texas init container", func(t *testing.T) {
+		c, err := GetTexasContainer(getSecurityConfig(true))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:%s", config.Get().TexasImageName, config.Get().TexasImageTag), c.Image)
+		assert.Equal(t, corev1.ContainerRestartPolicyAlways, *c.RestartPolicy)
+		assert.NotNil(t, c.SecurityContext)
+		assert.Contains(t, c.Env, corev1.EnvVar{Name: TokenXEnabledEnvVarName, Value: "true"})
+		assert.Contains(t, c.EnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: naming.GetJwkerSecretName(naming.GetJwkerName(appName)),
+				},
+			},
+		})
+	})
+
+	t.Run("returns a localhost URL including the configured port", func(t *testing.T) {
+		assert.Equal(t, fmt.Sprintf("http://localhost:%d", config.Get().TexasPort), GetTexasUrlEnvVarValue())
+	})
+}
+
+func TestIsTexasContainerEqual(t *testing.T) {
+	loadConfig(t)
+	expected, err := GetTexasContainer(getSecurityConfig(true))
+	require.NoError(t, err)
+	assert.True(t, IsTexasContainerEqual(*expected, *expected))
+
+	altered := *expected.DeepCopy()
+	altered.Ports = append(altered.Ports, corev1.ContainerPort{Name: "dummy-port", ContainerPort: 1234, Protocol: "UDP"})
+	assert.False(t, IsTexasContainerEqual(*expected, altered))
+
+	altered = *expected.DeepCopy()
+	altered.Image += "-changed"
+	assert.False(t, IsTexasContainerEqual(*expected, altered))
+}
+
+func TestPodMutationAndValidation(t *testing.T) {
+	loadConfig(t)
+	ctx := context.Background()
+	securityConfig := getSecurityConfig(true)
+
+	t.Run("a mutated pod is valid", func(t *testing.T) {
+		pod := getPod()
+		require.NoError(t, Capability{}.PodMutation(ctx, pod, securityConfig, appName))
+		require.Len(t, pod.Spec.InitContainers, 1)
+		assert.Equal(t, TexasInitContainerName, pod.Spec.InitContainers[0].Name)
+		assert.Equal(t, []corev1.EnvVar{{Name: config.Get().TexasUrlEnvVarName, Value: GetTexasUrlEnvVarValue()}}, pod.Spec.Containers[0].Env)
+		assert.NoError(t, Capability{}.PodValidation(ctx, pod, securityConfig, appName))
+	})
+
+	t.Run("rejects a pod without the Texas init container", func(t *testing.T) {
+		err := Capability{}.PodValidation(ctx, getPod(), securityConfig, appName)
+		assert.EqualError(t, err, fmt.Sprintf("TokenX is enabled but init container '%s' is missing", TexasInitContainerName))
+		assertRejectionReason(t, rejectionReasonTexasContainerMissing, err)
+	})
+
+	t.Run("rejects a pod with a different Texas init container", func(t *testing.T) {
+		pod := getPod()
+		require.NoError(t, Capability{}.PodMutation(ctx, pod, securityConfig, appName))
+		pod.Spec.InitContainers[0].Image = "other"
+		err := Capability{}.PodValidation(ctx, pod, securityConfig, appName)
+		assert.EqualError(t, err, "texas init container is not as expected given the SecurityConfig")
+		assertRejectionReason(t, rejectionReasonTexasContainerMismatch, err)
+	})
+
+	t.Run("rejects a pod without the Texas URL", func(t *testing.T) {
+		pod := getPod()
+		require.NoError(t, Capability{}.PodMutation(ctx, pod, securityConfig, appName))
+		pod.Spec.Containers[0].Env = nil
+		err := Capability{}.PodValidation(ctx, pod, securityConfig, appName)
+		assert.EqualError(t, err, fmt.Sprintf(
+			"TokenX is enabled but %s env var is missing for pod from skiperator app with name %s/%s",
+			pod.Namespace, appName, config.Get().TexasUrlEnvVarName,
+		))
+		assertRejectionReason(t, rejectionReasonTexasUrlMissing, err)
+	})
+}
+
+func assertRejectionReason(t *testing.T, expected string, err error) {
+	t.Helper()
+	var rejection *capability.RejectionError
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, expected, rejection.Reason)
+}
+
+func TestResolve(t *testing.T) {
+	securityConfig := getSecurityConfig(true)
+	securityConfig.Spec.Tokenx.AccessPolicy = &v1alpha.AccessPolicy{
+		Inbound: []v1alpha.AccessPolicyRule{{Application: "other"}},
+	}
+	application := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: appName, Namespace: "ns"},
+		Spec: v1alpha1.ApplicationSpec{AccessPolicy: &podtypes.AccessPolicy{
+			Inbound: &podtypes.InboundPolicy{Rules: []podtypes.InternalRule{{Application: "caller"}}},
+		}},
+	}
+	k8sClient := utilities.GetMockKubernetesClient(getScheme(t), application)
+	index := accesspolicy.NewIndex(nil, []v1alpha.SecurityConfig{securityConfig})
+
+	t.Run("merges the access policies of the Application and the SecurityConfig", func(t *testing.T) {
+		scope := &state.Scope{SecurityConfig: securityConfig}
+		require.NoError(t, Capability{}.Resolve(context.Background(), k8sClient, scope, index))
+		assert.True(t, scope.TokenXConfig.Enabled)
+		assert.Equal(t, []podtypes.InternalRule{
+			{Application: "caller"},
+			{Application: "other"},
+		}, scope.TokenXConfig.AccessPolicy.Inbound.Rules)
+	})
+
+	t.Run("keeps the Jwkers named after a previous applicationRef", func(t *testing.T) {
+		scope := &state.Scope{SecurityConfig: securityConfig, TokenXConfig: state.TokenXConfig{StaleJwkerNames: []string{"old-app"}}}
+		require.NoError(t, Capability{}.Resolve(context.Background(), k8sClient, scope, index))
+		assert.Equal(t, []string{"old-app"}, scope.TokenXConfig.StaleJwkerNames)
+	})
+
+	t.Run("returns an error when the Application does not exist", func(t *testing.T) {
+		missing := securityConfig.DeepCopy()
+		missing.Spec.ApplicationRef = "missing"
+		err := Capability{}.Resolve(context.Background(), k8sClient, &state.Scope{SecurityConfig: *missing}, index)
+		assert.ErrorContains(t, err, "failed to fetch Application resource named missing")
+	})
+}
+
+func TestGetStaleJwkerNames(t *testing.T) {
+	securityConfig := getSecurityConfig(true)
+	securityConfig.UID = "uid"
+	getJwker := func(name string, controlled bool) *naisiov1.Jwker {
+		jwker := &naisiov1.Jwker{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+		if controlled {
+			require.NoError(t, controllerutil.SetControllerReference(&securityConfig, jwker, getScheme(t)))
+		}
+		return jwker
+	}
+	k8sClient := utilities.GetMockKubernetesClient(
+		getScheme(t),
+		getJwker(naming.GetJwkerName(appName), true),
+		getJwker(naming.GetJwkerName("old-app"), true),
+		getJwker(naming.GetJwkerName("unrelated"), false),
+	)
+
+	staleJwkerNames, err := GetStaleJwkerNames(context.Background(), k8sClient, securityConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{naming.GetJwkerName("old-app")}, staleJwkerNames)
+}
+
+func TestDesiredResources(t *testing.T) {
+	loadConfig(t)
+	scope := &state.Scope{SecurityConfig: getSecurityConfig(true), TokenXConfig: state.TokenXConfig{Enabled: true}}
+
+	resources := Capability{}.DesiredResources(scope)
+	var kinds, desired []string
+	for _, resource := range resources {
+		kinds = append(kinds, resource.GetResourceKind())
+		if !resource.IsResourceNil() {
+			desired = append(desired, resource.GetResourceKind())
+		}
+	}
+	assert.Equal(t, []string{"Jwker", "NetworkPolicy", "CiliumNetworkPolicy", "Sidecar"}, kinds)
+	assert.Equal(t, []string{"Jwker", "NetworkPolicy"}, desired)
+	assert.Equal(t, naming.GetJwkerName(appName), resources[0].GetResourceName())
+
+	scope.TokenXConfig.StaleJwkerNames = []string{"old-app"}
+	resources = Capability{}.DesiredResources(scope)
+	stale := resources[len(resources)-1]
+	assert.Equal(t, "Jwker", stale.GetResourceKind())
+	assert.Equal(t, "old-app", stale.GetResourceName())
+	assert.True(t, stale.IsResourceNil())
+
+	scope.TokenXConfig.Enabled = false
+	resources = Capability{}.DesiredResources(scope)
+	for _, resource := range resources {
+		assert.True(t, resource.IsResourceNil(), "%s should not be desired when TokenX is disabled", resource.GetResourceKind())
+	}
+	assert.Equal(t, "old-app", resources[len(resources)-1].GetResourceName(), "stale Jwkers should be deleted when TokenX is disabled")
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	scope := &state.Scope{SecurityConfig: getSecurityConfig(true), TokenXConfig: state.TokenXConfig{Enabled: true}}
+	jwker := &naisiov1.Jwker{ObjectMeta: metav1.ObjectMeta{Name: naming.GetJwkerName(appName), Namespace: "ns"}}
+
+	t.Run("is pending when the Jwker does not exist", func(t *testing.T) {
+		status, err := Capability{}.Status(ctx, utilities.GetMockKubernetesClient(getScheme(t)), scope, now)
+		assert.Error(t, err)
+		require.NotNil(t, status.Pending)
+		assert.Equal(t, "SecurityConfig pending due to missing TokenX secret.", status.Pending.PhaseMessage)
+	})
+
+	t.Run("is pending until the Jwker is ready", func(t *testing.T) {
+		status, err := Capability{}.Status(ctx, utilities.GetMockKubernetesClient(getScheme(t), jwker), scope, now)
+		require.NoError(t, err)
+		require.NotNil(t, status.Pending)
+		assert.Equal(
+			t,
+			fmt.Sprintf("Jwker resource with name %s has not finished registering an OAuth client", jwker.Name),
+			status.Pending.ConditionMessage,
+		)
+	})
+
+	t.Run("is ready when the Jwker is ready", func(t *testing.T) {
+		ready := jwker.DeepCopy()
+		ready.Status.SynchronizationState = utilities.JwkerSynchronizationStateReady
+		status, err := Capability{}.Status(ctx, utilities.GetMockKubernetesClient(getScheme(t), ready), scope, now)
+		require.NoError(t, err)
+		assert.Nil(t, status.Pending)
+		require.Len(t, status.Conditions, 1)
+		assert.Equal(t, v1alpha.ConditionTypeAccessPolicyConsistent, status.Conditions[0].Type)
+		assert.Equal(t, metav1.ConditionTrue, status.Conditions[0].Status)
+	})
+
+	t.Run("reports one-sided access policy rules", func(t *testing.T) {
+		inconsistent := &state.Scope{SecurityConfig: scope.SecurityConfig, TokenXConfig: scope.TokenXConfig}
+		inconsistent.TokenXConfig.OneSidedRules = []accesspolicy.Rule{{
+			Direction: accesspolicy.DirectionOutbound,
+			Peer:      accesspolicy.Workload{Name: "other", Namespace: "ns"},
+		}}
+		status, _ := Capability{}.Status(ctx, utilities.GetMockKubernetesClient(getScheme(t)), inconsistent, now)
+		require.Len(t, status.Conditions, 1)
+		assert.Equal(t, metav1.ConditionFalse, status.Conditions[0].Status)
+		assert.Contains(t, status.Conditions[0].Message, "outbound rule ns/other")
+	})
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -15,14 +18,142 @@ type Config struct {
 	TexasImageTag      string `split_words:"true"`
 	TexasPort          int32  `split_words:"true" default:"3000"`
 	TexasUrlEnvVarName string `split_words:"true" default:"TEXAS_URL"`
+	// AccessGrantExpiryWarning is how long before expiry a time-bound access policy rule is reported as expiring soon.
+	AccessGrantExpiryWarning time.Duration `split_words:"true" default:"24h"`
+
+	// DescendantReconcileWorkers is how many descendants of a SecurityConfig are reconciled concurrently.
+	DescendantReconcileWorkers int `split_words:"true" default:"4"`
+	// DescendantReconcileTimeout is the deadline for reconciling a single descendant of a SecurityConfig. A zero
+	// timeout disables the deadline.
+	DescendantReconcileTimeout time.Duration `split_words:"true" default:"30s"`
+	// OrphanCleanupEnabled makes the descendants of a SecurityConfig whose Application does not exist be deleted once
+	// OrphanGracePeriod has passed. It is opt-in, since the descendants include the OAuth client of the application.
+	OrphanCleanupEnabled bool `split_words:"true" default:"false"`
+	// OrphanGracePeriod is how long the descendants of an orphaned SecurityConfig are kept, so an Application that is
+	// recreated, e.g. during a redeploy, keeps its OAuth client.
+	OrphanGracePeriod time.Duration `split_words:"true" default:"1h"`
+
+	// EgressPodSelectorLabel is the pod label whose value is the name of the application, used to select the pods
+	// of the application in the egress NetworkPolicy.
+	EgressPodSelectorLabel string `split_words:"true" default:"app"`
+	// TokenxPodSelectorLabels select the Tokendings pods in the egress NetworkPolicy. Defaults to app=<TokenxName>.
+	TokenxPodSelectorLabels map[string]string `split_words:"true"`
+	// TokenxNamespaceSelectorLabels select the namespace of Tokendings in the egress NetworkPolicy.
+	// Defaults to kubernetes.io/metadata.name=<TokenxNamespace>.
+	TokenxNamespaceSelectorLabels map[string]string `split_words:"true"`
+	// TokenxPorts restricts egress to Tokendings to these TCP ports. All ports are allowed if empty.
+	TokenxPorts []int32 `split_words:"true"`
+	// EgressDnsEnabled adds an egress rule to the DNS pods to the egress NetworkPolicy.
+	EgressDnsEnabled     bool              `split_words:"true" default:"false"`
+	DnsNamespace         string            `split_words:"true" default:"kube-system"`
+	DnsPodSelectorLabels map[string]string `split_words:"true" default:"k8s-app:kube-dns"`
+	// DnsNamespaceSelectorLabels select the namespace of the DNS pods in the egress NetworkPolicy.
+	// Defaults to kubernetes.io/metadata.name=<DnsNamespace>.
+	DnsNamespaceSelectorLabels map[string]string `split_words:"true"`
+	DnsPort                    int32             `split_words:"true" default:"53"`
+	// EgressBackend selects the kind of resources restricting egress from the applications: networkpolicy,
+	// cilium or istio.
+	EgressBackend EgressBackend `split_words:"true" default:"networkpolicy"`
+	// IstioTrustDomain is the trust domain of the mTLS principals the AuthorizationPolicy generated by the istio egress
+	// backend allows to reach Tokendings.
+	IstioTrustDomain string `split_words:"true" default:"cluster.local"`
+
+	// TokenxIngressEnabled makes Accesserator manage a NetworkPolicy in TokenxNamespace allowing ingress to
+	// Tokendings from the applications of all SecurityConfigs with TokenX enabled.
+	TokenxIngressEnabled bool   `split_words:"true" default:"false"`
+	TokenxIngressName    string `split_words:"true" default:"accesserator-tokenx-ingress"`
+	// TokenxIngressNamespaceSelectorLabel is the namespace label whose value is the name of the namespace, used to
+	// select the namespaces of the applications in the ingress NetworkPolicy.
+	TokenxIngressNamespaceSelectorLabel string `split_words:"true" default:"kubernetes.io/metadata.name"`
+
+	// TracingExporter is none or otlp. The otlp exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+	// environment variables.
+	TracingExporter    string  `split_words:"true" default:"none"`
+	TracingSampleRatio float64 `split_words:"true" default:"1"`
+
+	// AuditSink is where the audit log of security-relevant changes is written: none, stdout, file or http.
+	AuditSink         string `split_words:"true" default:"none"`
+	AuditFilePath     string `split_words:"true"`
+	AuditHttpEndpoint string `split_words:"true"`
+
+	// EgressEndpoints is a JSON object mapping a capability to the external endpoints Texas needs to reach when the
+	// capability is enabled, e.g. {"maskinporten":[{"host":"maskinporten.no","port":443}]}.
+	EgressEndpoints EgressEndpointCatalog `split_words:"true"`
+}
+
+// EgressBackend is the kind of resources restricting egress from the applications.
+type EgressBackend string
+
+const (
+	EgressBackendNetworkPolicy EgressBackend = "networkpolicy"
+	EgressBackendCilium        EgressBackend = "cilium"
+	EgressBackendIstio         EgressBackend = "istio"
+)
+
+// Decode implements envconfig.Decoder.
+func (b *EgressBackend) Decode(value string) error {
+	switch backend := EgressBackend(value); backend {
+	case EgressBackendNetworkPolicy, EgressBackendCilium, EgressBackendIstio:
+		*b = backend
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid egress backend %q, must be one of %s, %s or %s",
+			value,
+			EgressBackendNetworkPolicy,
+			EgressBackendCilium,
+			EgressBackendIstio,
+		)
+	}
+}
+
+// EgressEndpoint is a host outside the cluster that Texas needs to reach for a capability.
+type EgressEndpoint struct {
+	Host string `json:"host"`
+	Port int32  `json:"port"`
+	// CIDRs restricts the egress NetworkPolicy rule to these IP ranges. All addresses are allowed if empty. The
+	// cilium egress backend matches the host name instead.
+	CIDRs []string `json:"cidrs,omitempty"`
+}
+
+// EgressEndpointCatalog maps the name of a capability to the external endpoints it needs.
+type EgressEndpointCatalog map[string][]EgressEndpoint
+
+// Decode implements envconfig.Decoder.
+func (c *EgressEndpointCatalog) Decode(value string) error {
+	catalog := EgressEndpointCatalog{}
+	if err := json.Unmarshal([]byte(value), &catalog); err != nil {
+		return fmt.Errorf("invalid egress endpoint catalog: %w", err)
+	}
+	for capability, endpoints := range catalog {
+		for _, endpoint := range endpoints {
+			if endpoint.Host == "" || endpoint.Port <= 0 {
+				return fmt.Errorf("egress endpoint for capability %s must have a host and a port", capability)
+			}
+		}
+	}
+	*c = catalog
+	return nil
+}
+
+// Capabilities returns the capabilities in the catalog, sorted by name.
+func (c EgressEndpointCatalog) Capabilities() []string {
+	capabilities := make([]string, 0, len(c))
+	for capability := range c {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities
 }
 
 var cfg Config
 
 func Load() error {
-	if err := envconfig.Process("accesserator", &cfg); err != nil {
+	var loaded Config
+	if err := envconfig.Process("accesserator", &loaded); err != nil {
 		return err
 	}
+	cfg = loaded
 
 	missing := make([]string, 0, 3)
 	if cfg.ClusterName == "" {
@@ -43,3 +174,27 @@ func Load() error {
 func Get() Config {
 	return cfg
 }
+
+// GetTokenxPodSelectorLabels returns the labels selecting the Tokendings pods.
+func (c Config) GetTokenxPodSelectorLabels() map[string]string {
+	if len(c.TokenxPodSelectorLabels) > 0 {
+		return c.TokenxPodSelectorLabels
+	}
+	return map[string]string{"app": c.TokenxName}
+}
+
+// GetTokenxNamespaceSelectorLabels returns the labels selecting the namespace of Tokendings.
+func (c Config) GetTokenxNamespaceSelectorLabels() map[string]string {
+	if len(c.TokenxNamespaceSelectorLabels) > 0 {
+		return c.TokenxNamespaceSelectorLabels
+	}
+	return map[string]string{"kubernetes.io/metadata.name": c.TokenxNamespace}
+}
+
+// GetDnsNamespaceSelectorLabels returns the labels selecting the namespace of the DNS pods.
+func (c Config) GetDnsNamespaceSelectorLabels() map[string]string {
+	if len(c.DnsNamespaceSelectorLabels) > 0 {
+		return c.DnsNamespaceSelectorLabels
+	}
+	return map[string]string{"kubernetes.io/metadata.name": c.DnsNamespace}
+}
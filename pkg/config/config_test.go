@@ -0,0 +1,100 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+}
+
+func TestLoad_MissingRequired(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "")
+
+	err := Load()
+	require.Error(t, err)
+	assert.Equal(
+		t,
+		"missing required config: ACCESSERATOR_CLUSTER_NAME, ACCESSERATOR_TOKENX_NAMESPACE, ACCESSERATOR_TEXAS_IMAGE_TAG",
+		err.Error(),
+	)
+}
+
+func TestLoad_EgressDefaults(t *testing.T) {
+	setRequiredEnv(t)
+	require.NoError(t, Load())
+
+	cfg := Get()
+	assert.Equal(t, "app", cfg.EgressPodSelectorLabel)
+	assert.Equal(t, map[string]string{"app": "tokendings"}, cfg.GetTokenxPodSelectorLabels())
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "obo"}, cfg.GetTokenxNamespaceSelectorLabels())
+	assert.Empty(t, cfg.TokenxPorts)
+	assert.False(t, cfg.EgressDnsEnabled)
+	assert.Equal(t, map[string]string{"k8s-app": "kube-dns"}, cfg.DnsPodSelectorLabels)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "kube-system"}, cfg.GetDnsNamespaceSelectorLabels())
+}
+
+func TestLoad_EgressOverrides(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ACCESSERATOR_EGRESS_POD_SELECTOR_LABEL", "app.kubernetes.io/name")
+	t.Setenv("ACCESSERATOR_TOKENX_POD_SELECTOR_LABELS", "app.kubernetes.io/name:tokendings,team:nais")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE_SELECTOR_LABELS", "team:nais")
+	t.Setenv("ACCESSERATOR_TOKENX_PORTS", "7456,8080")
+	t.Setenv("ACCESSERATOR_EGRESS_DNS_ENABLED", "true")
+	t.Setenv("ACCESSERATOR_DNS_NAMESPACE_SELECTOR_LABELS", "name:kube-system")
+	require.NoError(t, Load())
+
+	cfg := Get()
+	assert.Equal(t, "app.kubernetes.io/name", cfg.EgressPodSelectorLabel)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/name": "tokendings", "team": "nais"}, cfg.GetTokenxPodSelectorLabels())
+	assert.Equal(t, map[string]string{"team": "nais"}, cfg.GetTokenxNamespaceSelectorLabels())
+	assert.Equal(t, []int32{7456, 8080}, cfg.TokenxPorts)
+	assert.True(t, cfg.EgressDnsEnabled)
+	assert.Equal(t, map[string]string{"name": "kube-system"}, cfg.GetDnsNamespaceSelectorLabels())
+}
+
+func TestLoad_EgressEndpoints(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv(
+		"ACCESSERATOR_EGRESS_ENDPOINTS",
+		`{"tokenx":[{"host":"tokenx.example.com","port":443}],"maskinporten":[{"host":"maskinporten.no","port":443,"cidrs":["10.0.0.0/8"]}]}`,
+	)
+	require.NoError(t, Load())
+
+	cfg := Get()
+	assert.Equal(t, []string{"maskinporten", "tokenx"}, cfg.EgressEndpoints.Capabilities())
+	assert.Equal(
+		t,
+		[]EgressEndpoint{{Host: "maskinporten.no", Port: 443, CIDRs: []string{"10.0.0.0/8"}}},
+		cfg.EgressEndpoints["maskinporten"],
+	)
+}
+
+func TestLoad_InvalidEgressEndpoints(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ACCESSERATOR_EGRESS_ENDPOINTS", `{"tokenx":[{"host":"tokenx.example.com"}]}`)
+	assert.Error(t, Load())
+
+	t.Setenv("ACCESSERATOR_EGRESS_ENDPOINTS", `not json`)
+	assert.Error(t, Load())
+}
+
+func TestLoad_EgressBackend(t *testing.T) {
+	setRequiredEnv(t)
+	require.NoError(t, Load())
+	assert.Equal(t, EgressBackendNetworkPolicy, Get().EgressBackend)
+
+	t.Setenv("ACCESSERATOR_EGRESS_BACKEND", "cilium")
+	require.NoError(t, Load())
+	assert.Equal(t, EgressBackendCilium, Get().EgressBackend)
+
+	t.Setenv("ACCESSERATOR_EGRESS_BACKEND", "calico")
+	assert.Error(t, Load())
+}
@@ -0,0 +1,384 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// skiperatorAppLabel is the label Skiperator sets on the pods of an application, also when the app-name label the
+// webhook relies on is missing.
+const skiperatorAppLabel = "app"
+
+// Check is a single precondition for Texas being injected into the pods of an application.
+type Check struct {
+	Subject string
+	Passed  bool
+	Message string
+	// Fix describes how to resolve a failed check.
+	Fix string
+}
+
+// Report is the outcome of diagnosing an application.
+type Report struct {
+	Workload accesspolicy.Workload
+	Checks   []Check
+	Healthy  bool
+}
+
+func (r *Report) pass(subject, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Passed: true, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) fail(subject, fix, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Message: fmt.Sprintf(format, args...), Fix: fix})
+	r.Healthy = false
+}
+
+// diagnosis holds the objects of the application that the checks are run against.
+type diagnosis struct {
+	report         *Report
+	application    *v1alpha1.Application
+	namespace      *corev1.Namespace
+	pods           []corev1.Pod
+	securityConfig *v1alpha.SecurityConfig
+	jwker          *naisiov1.Jwker
+	secret         *corev1.Secret
+}
+
+// Diagnose walks every precondition for Texas, and thereby TEXAS_URL, to be injected into the pods of the
+// application, and reports a fix for every failed check. Checks depending on a failed check are skipped.
+func Diagnose(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) (*Report, error) {
+	d := &diagnosis{report: &Report{Workload: w, Healthy: true}}
+
+	application := &v1alpha1.Application{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: w.Name, Namespace: w.Namespace}, application); err != nil {
+		return nil, err
+	} else if application.Name != "" {
+		d.application = application
+	}
+	namespace := &corev1.Namespace{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: w.Namespace}, namespace); err != nil {
+		return nil, err
+	} else if namespace.Name != "" {
+		d.namespace = namespace
+	}
+	pods, err := getPods(ctx, k8sClient, w)
+	if err != nil {
+		return nil, err
+	}
+	d.pods = pods
+
+	d.checkPodLabels()
+	d.checkApplicationLabel()
+	d.checkNamespaceLabel()
+	if err := d.checkSecurityConfig(ctx, k8sClient); err != nil {
+		return nil, err
+	}
+	if d.securityConfig == nil {
+		return d.report, nil
+	}
+	if !d.checkTokenx() {
+		return d.report, nil
+	}
+	if err := d.checkJwker(ctx, k8sClient); err != nil {
+		return nil, err
+	}
+	if err := d.checkNetworkPolicy(ctx, k8sClient); err != nil {
+		return nil, err
+	}
+	d.checkPodAge()
+	return d.report, nil
+}
+
+// Print writes the checks with the fix of every failed check.
+func (r *Report) Print(w io.Writer) error {
+	verdict := "OK"
+	if !r.Healthy {
+		verdict = "PROBLEMS FOUND"
+	}
+	if _, err := fmt.Fprintf(w, "Diagnosing %s: %s\n", r.Workload, verdict); err != nil {
+		return err
+	}
+	for _, check := range r.Checks {
+		mark := "✔"
+		if !check.Passed {
+			mark = "✘"
+		}
+		if _, err := fmt.Fprintf(w, "  %s [%s] %s\n", mark, check.Subject, check.Message); err != nil {
+			return err
+		}
+		if check.Fix != "" {
+			if _, err := fmt.Fprintf(w, "      fix: %s\n", check.Fix); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getPods returns the pods of the application, found either by the app-name label or by the app label.
+func getPods(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	seen := map[string]bool{}
+	for _, labels := range []client.MatchingLabels{
+		{utilities.SkiperatorApplicationRefLabel: w.Name},
+		{skiperatorAppLabel: w.Name},
+	} {
+		var podList corev1.PodList
+		if err := k8sClient.List(ctx, &podList, client.InNamespace(w.Namespace), labels); err != nil {
+			return nil, fmt.Errorf("failed to list pods of application %s: %w", w, err)
+		}
+		for _, pod := range podList.Items {
+			if !seen[pod.Name] {
+				seen[pod.Name] = true
+				pods = append(pods, pod)
+			}
+		}
+	}
+	return pods, nil
+}
+
+func (d *diagnosis) checkPodLabels() {
+	w := d.report.Workload
+	if len(d.pods) == 0 {
+		d.report.fail(
+			"pod-label",
+			fmt.Sprintf("make sure the pods are created by the Skiperator Application %s and are running", w),
+			"no pods of application %s were found", w,
+		)
+		return
+	}
+	for _, pod := range d.pods {
+		if pod.Labels[utilities.SkiperatorApplicationRefLabel] != w.Name {
+			d.report.fail(
+				"pod-label",
+				fmt.Sprintf("label the pod template with %s=%s, or let Skiperator manage the workload", utilities.SkiperatorApplicationRefLabel, w.Name),
+				"pod %s is not labelled %s=%s, so the webhook cannot find its application",
+				pod.Name, utilities.SkiperatorApplicationRefLabel, w.Name,
+			)
+			return
+		}
+	}
+	d.report.pass("pod-label", "%d pod(s) are labelled %s=%s", len(d.pods), utilities.SkiperatorApplicationRefLabel, w.Name)
+}
+
+func (d *diagnosis) checkApplicationLabel() {
+	w := d.report.Workload
+	if d.application == nil {
+		d.report.fail(
+			"app-label",
+			fmt.Sprintf("create the Skiperator Application %s", w),
+			"Application %s does not exist", w,
+		)
+		return
+	}
+	if d.application.Labels[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue {
+		d.report.fail(
+			"app-label",
+			fmt.Sprintf(
+				"kubectl label application %s -n %s %s=%s",
+				w.Name, w.Namespace, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+			),
+			"Application %s is not labelled %s=%s", w, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+		)
+		return
+	}
+	d.report.pass("app-label", "Application %s is labelled %s=%s", w, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue)
+}
+
+func (d *diagnosis) checkNamespaceLabel() {
+	w := d.report.Workload
+	if d.namespace == nil {
+		d.report.fail("namespace-label", fmt.Sprintf("create the namespace %s", w.Namespace), "namespace %s does not exist", w.Namespace)
+		return
+	}
+	if d.namespace.Labels[utilities.WebhookNamespaceLabelName] != utilities.WebhookNamespaceLabelValue {
+		d.report.fail(
+			"namespace-label",
+			fmt.Sprintf(
+				"kubectl label namespace %s %s=%s",
+				w.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+			),
+			"namespace %s is not labelled %s=%s, so the webhook is not called for its pods",
+			w.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+		)
+		return
+	}
+	d.report.pass(
+		"namespace-label", "namespace %s is labelled %s=%s",
+		w.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+	)
+}
+
+func (d *diagnosis) checkSecurityConfig(ctx context.Context, k8sClient client.Client) error {
+	w := d.report.Workload
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList, client.InNamespace(w.Namespace)); err != nil {
+		return fmt.Errorf("failed to list SecurityConfig resources in namespace %s: %w", w.Namespace, err)
+	}
+	var securityConfigs []v1alpha.SecurityConfig
+	for _, securityConfig := range securityConfigList.Items {
+		if securityConfig.Spec.ApplicationRef == w.Name {
+			securityConfigs = append(securityConfigs, securityConfig)
+		}
+	}
+	switch len(securityConfigs) {
+	case 0:
+		d.report.fail(
+			"securityconfig",
+			fmt.Sprintf("create a SecurityConfig in namespace %s with spec.applicationRef: %s", w.Namespace, w.Name),
+			"no SecurityConfig references application %s", w,
+		)
+	case 1:
+		d.securityConfig = &securityConfigs[0]
+		d.report.pass("securityconfig", "SecurityConfig %s/%s references application %s", w.Namespace, d.securityConfig.Name, w)
+	default:
+		d.report.fail(
+			"securityconfig",
+			fmt.Sprintf("delete all but one of the SecurityConfigs with spec.applicationRef: %s", w.Name),
+			"%d SecurityConfigs reference application %s, expected exactly one", len(securityConfigs), w,
+		)
+	}
+	return nil
+}
+
+func (d *diagnosis) checkTokenx() bool {
+	w := d.report.Workload
+	if d.securityConfig.Spec.Tokenx == nil || !d.securityConfig.Spec.Tokenx.Enabled {
+		d.report.fail(
+			"tokenx",
+			fmt.Sprintf("set spec.tokenx.enabled: true in SecurityConfig %s/%s", w.Namespace, d.securityConfig.Name),
+			"SecurityConfig %s/%s does not enable TokenX", w.Namespace, d.securityConfig.Name,
+		)
+		return false
+	}
+	d.report.pass("tokenx", "SecurityConfig %s/%s enables TokenX", w.Namespace, d.securityConfig.Name)
+	return true
+}
+
+func (d *diagnosis) checkJwker(ctx context.Context, k8sClient client.Client) error {
+	w := d.report.Workload
+	jwkerName := naming.GetJwkerName(w.Name)
+	jwker := &naisiov1.Jwker{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: jwkerName, Namespace: w.Namespace}, jwker); err != nil {
+		return err
+	}
+	if jwker.Name == "" {
+		d.report.fail(
+			"jwker",
+			fmt.Sprintf("check the status of SecurityConfig %s/%s for why the Jwker was not created", w.Namespace, d.securityConfig.Name),
+			"Jwker %s/%s does not exist", w.Namespace, jwkerName,
+		)
+		return nil
+	}
+	d.jwker = jwker
+	if jwker.Status.SynchronizationState != utilities.JwkerSynchronizationStateReady {
+		d.report.fail(
+			"jwker",
+			fmt.Sprintf("check the status of Jwker %s/%s and the logs of jwker for why it was not registered", w.Namespace, jwkerName),
+			"Jwker %s/%s is in synchronization state %q, expected %q",
+			w.Namespace, jwkerName, jwker.Status.SynchronizationState, utilities.JwkerSynchronizationStateReady,
+		)
+	} else {
+		d.report.pass("jwker", "Jwker %s/%s is %s", w.Namespace, jwkerName, utilities.JwkerSynchronizationStateReady)
+	}
+
+	secretName := jwker.Spec.SecretName
+	if secretName == "" {
+		secretName = naming.GetJwkerSecretName(jwkerName)
+	}
+	secret := &corev1.Secret{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: secretName, Namespace: w.Namespace}, secret); err != nil {
+		return err
+	}
+	if secret.Name == "" {
+		d.report.fail(
+			"secret",
+			fmt.Sprintf("wait for jwker to create the secret once Jwker %s/%s is %s", w.Namespace, jwkerName, utilities.JwkerSynchronizationStateReady),
+			"secret %s/%s with the TokenX credentials does not exist", w.Namespace, secretName,
+		)
+		return nil
+	}
+	d.secret = secret
+	d.report.pass("secret", "secret %s/%s with the TokenX credentials exists", w.Namespace, secretName)
+	return nil
+}
+
+func (d *diagnosis) checkNetworkPolicy(ctx context.Context, k8sClient client.Client) error {
+	w := d.report.Workload
+	var networkPolicyList networkingv1.NetworkPolicyList
+	if err := k8sClient.List(ctx, &networkPolicyList, client.InNamespace(w.Namespace)); err != nil {
+		return fmt.Errorf("failed to list NetworkPolicy resources in namespace %s: %w", w.Namespace, err)
+	}
+	for _, networkPolicy := range networkPolicyList.Items {
+		if owner := metav1.GetControllerOf(&networkPolicy); owner != nil && owner.UID == d.securityConfig.UID {
+			d.report.pass("netpol", "egress NetworkPolicy %s/%s allows the pods to reach Tokendings", w.Namespace, networkPolicy.Name)
+			return nil
+		}
+	}
+	d.report.fail(
+		"netpol",
+		fmt.Sprintf("check the status of SecurityConfig %s/%s for why the NetworkPolicy was not created", w.Namespace, d.securityConfig.Name),
+		"no egress NetworkPolicy to Tokendings is owned by SecurityConfig %s/%s", w.Namespace, d.securityConfig.Name,
+	)
+	return nil
+}
+
+// checkPodAge verifies that the pods were created after the last change of the SecurityConfig and the TokenX
+// secret, since Texas is only injected when a pod is created.
+func (d *diagnosis) checkPodAge() {
+	w := d.report.Workload
+	if len(d.pods) == 0 {
+		return
+	}
+	lastChange := getLastChange(d.securityConfig)
+	if d.secret != nil && d.secret.CreationTimestamp.After(lastChange) {
+		lastChange = d.secret.CreationTimestamp.Time
+	}
+	for _, pod := range d.pods {
+		if pod.CreationTimestamp.Time.Before(lastChange) {
+			d.report.fail(
+				"pod-age",
+				fmt.Sprintf("restart the workload, e.g. kubectl rollout restart deployment/%s -n %s", w.Name, w.Namespace),
+				"pod %s was created at %s, before the last config change at %s",
+				pod.Name, pod.CreationTimestamp.UTC().Format(time.RFC3339), lastChange.UTC().Format(time.RFC3339),
+			)
+			return
+		}
+	}
+	d.report.pass("pod-age", "all pods were created after the last config change at %s", lastChange.UTC().Format(time.RFC3339))
+}
+
+// getLastChange returns when the SecurityConfig was last changed, i.e. the latest of its creation and of the updates
+// to anything but its status.
+func getLastChange(securityConfig *v1alpha.SecurityConfig) time.Time {
+	lastChange := securityConfig.CreationTimestamp.Time
+	for _, managedField := range securityConfig.ManagedFields {
+		if managedField.Subresource == "" && managedField.Time != nil && managedField.Time.After(lastChange) {
+			lastChange = managedField.Time.Time
+		}
+	}
+	return lastChange
+}
+
+func getOptional(ctx context.Context, k8sClient client.Client, key types.NamespacedName, obj client.Object) error {
+	if err := k8sClient.Get(ctx, key, obj); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to fetch %T %s: %w", obj, key, err)
+	}
+	return nil
+}
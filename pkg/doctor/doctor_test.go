@@ -0,0 +1,170 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const namespace = "ns"
+
+var (
+	workload   = accesspolicy.Workload{Name: "app", Namespace: namespace}
+	configTime = metav1.NewTime(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+)
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, networkingv1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func getObjects() []client.Object {
+	uid := types.UID("app-uid")
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   namespace,
+			Labels: map[string]string{utilities.WebhookNamespaceLabelName: utilities.WebhookNamespaceLabelValue},
+		}},
+		&v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: namespace,
+			Labels:    map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              "app-abc",
+			Namespace:         namespace,
+			Labels:            map[string]string{utilities.SkiperatorApplicationRefLabel: "app", skiperatorAppLabel: "app"},
+			CreationTimestamp: metav1.NewTime(configTime.Add(time.Hour)),
+		}},
+		&v1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace, UID: uid, CreationTimestamp: configTime},
+			Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: "app", Tokenx: &v1alpha.TokenXSpec{Enabled: true}},
+		},
+		&naisiov1.Jwker{
+			ObjectMeta: metav1.ObjectMeta{Name: naming.GetJwkerName("app"), Namespace: namespace},
+			Spec:       naisiov1.JwkerSpec{SecretName: "app-secret"},
+			Status:     naisiov1.JwkerStatus{SynchronizationState: utilities.JwkerSynchronizationStateReady},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: namespace, CreationTimestamp: configTime}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-egress",
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha.GroupVersion.String(),
+				Kind:       "SecurityConfig",
+				Name:       "app",
+				UID:        uid,
+				Controller: utilities.Ptr(true),
+			}},
+		}},
+	}
+}
+
+func getFailedChecks(report *Report) []string {
+	var failed []string
+	for _, check := range report.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Subject)
+		}
+	}
+	return failed
+}
+
+func TestDiagnose(t *testing.T) {
+	report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), getObjects()...), workload)
+	require.NoError(t, err)
+	assert.True(t, report.Healthy)
+	assert.Empty(t, getFailedChecks(report))
+
+	var subjects []string
+	for _, check := range report.Checks {
+		subjects = append(subjects, check.Subject)
+	}
+	assert.Equal(t, []string{
+		"pod-label", "app-label", "namespace-label", "securityconfig", "tokenx", "jwker", "secret", "netpol", "pod-age",
+	}, subjects)
+
+	var out bytes.Buffer
+	require.NoError(t, report.Print(&out))
+	assert.Contains(t, out.String(), "Diagnosing ns/app: OK")
+}
+
+func TestDiagnose_Failures(t *testing.T) {
+	t.Run("reports missing labels with fixes", func(t *testing.T) {
+		objects := getObjects()
+		objects[0].(*corev1.Namespace).Labels = nil
+		objects[1].(*v1alpha1.Application).Labels = nil
+		delete(objects[2].(*corev1.Pod).Labels, utilities.SkiperatorApplicationRefLabel)
+
+		report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), workload)
+		require.NoError(t, err)
+		assert.False(t, report.Healthy)
+		assert.Equal(t, []string{"pod-label", "app-label", "namespace-label"}, getFailedChecks(report))
+		assert.Equal(t, "kubectl label namespace ns accesserator-webhooks=enabled", report.Checks[2].Fix)
+
+		var out bytes.Buffer
+		require.NoError(t, report.Print(&out))
+		assert.Contains(t, out.String(), "fix: kubectl label application app -n ns skiperator/security=enabled")
+	})
+
+	t.Run("stops after a disabled TokenX", func(t *testing.T) {
+		objects := getObjects()
+		objects[3].(*v1alpha.SecurityConfig).Spec.Tokenx = nil
+
+		report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), workload)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tokenx"}, getFailedChecks(report))
+		assert.Equal(t, "tokenx", report.Checks[len(report.Checks)-1].Subject)
+	})
+
+	t.Run("reports several SecurityConfigs", func(t *testing.T) {
+		objects := append(getObjects(), &v1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace},
+			Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: "app"},
+		})
+
+		report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), workload)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"securityconfig"}, getFailedChecks(report))
+	})
+
+	t.Run("reports an unsynchronized Jwker without secret and NetworkPolicy", func(t *testing.T) {
+		objects := getObjects()
+		objects[4].(*naisiov1.Jwker).Status.SynchronizationState = ""
+
+		report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects[:5]...), workload)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"jwker", "secret", "netpol"}, getFailedChecks(report))
+	})
+
+	t.Run("reports pods created before the last config change", func(t *testing.T) {
+		objects := getObjects()
+		objects[5].(*corev1.Secret).CreationTimestamp = metav1.NewTime(configTime.Add(2 * time.Hour))
+
+		report, err := Diagnose(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), workload)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pod-age"}, getFailedChecks(report))
+		assert.Equal(t, "restart the workload, e.g. kubectl rollout restart deployment/app -n ns", report.Checks[len(report.Checks)-1].Fix)
+	})
+}
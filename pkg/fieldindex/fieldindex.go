@@ -0,0 +1,120 @@
+package fieldindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SecurityConfigApplicationRef indexes SecurityConfigs by spec.applicationRef. Lookups are namespaced.
+	SecurityConfigApplicationRef = "spec.applicationRef"
+	// SecurityConfigPeers indexes SecurityConfigs by the workloads their TokenX access policy rules refer to, keyed by
+	// Key.
+	SecurityConfigPeers = "spec.tokenx.accessPolicy.peers"
+	// ApplicationPeers indexes Applications by the workloads their access policy rules refer to, keyed by Key.
+	ApplicationPeers = "spec.accessPolicy.peers"
+	// AccessRequestApplicationRef indexes AccessRequests by spec.applicationRef. Lookups are namespaced.
+	AccessRequestApplicationRef = "spec.applicationRef"
+	// AccessRequestTarget indexes AccessRequests by the SecurityConfig they request access to, keyed by Key.
+	AccessRequestTarget = "spec.target"
+	// AccessApprovalSecurityConfigRef indexes AccessApprovals by spec.securityConfigRef. Lookups are namespaced.
+	AccessApprovalSecurityConfigRef = "spec.securityConfigRef"
+)
+
+// Index is a field index of the cache of the manager, which lets the controllers list the objects related to a
+// workload instead of every object in the cluster.
+type Index struct {
+	Object       client.Object
+	Field        string
+	ExtractValue client.IndexerFunc
+}
+
+// Key returns the value objects in other namespaces are indexed by.
+func Key(namespace, name string) string {
+	return accesspolicy.Workload{Name: name, Namespace: namespace}.String()
+}
+
+// All returns the field indexes the controllers depend on.
+func All() []Index {
+	return []Index{
+		{
+			Object: &v1alpha.SecurityConfig{},
+			Field:  SecurityConfigApplicationRef,
+			ExtractValue: func(obj client.Object) []string {
+				return []string{obj.(*v1alpha.SecurityConfig).Spec.ApplicationRef}
+			},
+		},
+		{
+			Object: &v1alpha.SecurityConfig{},
+			Field:  SecurityConfigPeers,
+			ExtractValue: func(obj client.Object) []string {
+				securityConfig := obj.(*v1alpha.SecurityConfig)
+				if securityConfig.Spec.Tokenx == nil {
+					return nil
+				}
+				w := accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace}
+				return getPeerKeys(accesspolicy.GetSecurityConfigRules(w, securityConfig.Spec.Tokenx.AccessPolicy))
+			},
+		},
+		{
+			Object: &v1alpha1.Application{},
+			Field:  ApplicationPeers,
+			ExtractValue: func(obj client.Object) []string {
+				application := obj.(*v1alpha1.Application)
+				w := accesspolicy.Workload{Name: application.Name, Namespace: application.Namespace}
+				return getPeerKeys(accesspolicy.GetRules(w, application.Spec.AccessPolicy))
+			},
+		},
+		{
+			Object: &v1alpha.AccessRequest{},
+			Field:  AccessRequestApplicationRef,
+			ExtractValue: func(obj client.Object) []string {
+				return []string{obj.(*v1alpha.AccessRequest).Spec.ApplicationRef}
+			},
+		},
+		{
+			Object: &v1alpha.AccessRequest{},
+			Field:  AccessRequestTarget,
+			ExtractValue: func(obj client.Object) []string {
+				target := obj.(*v1alpha.AccessRequest).Spec.Target
+				return []string{Key(target.Namespace, target.Name)}
+			},
+		},
+		{
+			Object: &v1alpha.AccessApproval{},
+			Field:  AccessApprovalSecurityConfigRef,
+			ExtractValue: func(obj client.Object) []string {
+				return []string{obj.(*v1alpha.AccessApproval).Spec.SecurityConfigRef}
+			},
+		},
+	}
+}
+
+// Setup registers the field indexes with the field indexer of the manager. It must be called before the manager is
+// started.
+func Setup(ctx context.Context, indexer client.FieldIndexer) error {
+	for _, index := range All() {
+		if err := indexer.IndexField(ctx, index.Object, index.Field, index.ExtractValue); err != nil {
+			return fmt.Errorf("failed to index %T by %s: %w", index.Object, index.Field, err)
+		}
+	}
+	return nil
+}
+
+func getPeerKeys(rules []accesspolicy.Rule) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, rule := range rules {
+		key := Key(rule.Peer.Namespace, rule.Peer.Name)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,185 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatText, "":
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatSARIF:
+		return FormatSARIF, nil
+	default:
+		return "", fmt.Errorf("unsupported lint format %q, expected one of text, json or sarif", format)
+	}
+}
+
+// Write exports the findings in the given format.
+func Write(w io.Writer, findings []Finding, format Format) error {
+	switch format {
+	case FormatText:
+		return WriteText(w, findings)
+	case FormatJSON:
+		return WriteJSON(w, findings)
+	case FormatSARIF:
+		return WriteSARIF(w, findings)
+	default:
+		return fmt.Errorf("unsupported lint format %q", format)
+	}
+}
+
+func WriteText(w io.Writer, findings []Finding) error {
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(
+			w, "%s:%d: %s [%s] %s\n", finding.File, finding.Line, finding.Rule.Level, finding.Rule.ID, finding.Message,
+		); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d finding(s)\n", len(findings))
+	return err
+}
+
+type jsonFinding struct {
+	Rule    string `json:"rule"`
+	Level   Level  `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+func WriteJSON(w io.Writer, findings []Finding) error {
+	out := make([]jsonFinding, 0, len(findings))
+	for _, finding := range findings {
+		out = append(out, jsonFinding{
+			Rule:    finding.Rule.ID,
+			Level:   finding.Rule.Level,
+			Message: finding.Message,
+			File:    finding.File,
+			Line:    finding.Line,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifRuleConfiguration struct {
+	Level Level `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Level           `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF exports the findings as a SARIF 2.1.0 log, as read by code scanning and review tools.
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	driver := sarifDriver{
+		Name:           "accesserator-lint",
+		InformationURI: "https://github.com/kartverket/accesserator",
+		Rules:          make([]sarifRule, 0, len(Rules)),
+	}
+	ruleIndex := make(map[string]int, len(Rules))
+	for i, rule := range Rules {
+		ruleIndex[rule.ID] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifRuleConfiguration{Level: rule.Level},
+		})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		results = append(results, sarifResult{
+			RuleID:    finding.Rule.ID,
+			RuleIndex: ruleIndex[finding.Rule.ID],
+			Level:     finding.Rule.Level,
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(finding.File)},
+				Region:           sarifRegion{StartLine: finding.Line},
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
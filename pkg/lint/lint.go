@@ -0,0 +1,294 @@
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Level is the severity of a finding.
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+)
+
+// Rule is a check run against the manifests.
+type Rule struct {
+	ID          string
+	Level       Level
+	Description string
+}
+
+var (
+	RuleDuplicateSecurityConfig = Rule{
+		ID:          "duplicate-securityconfig",
+		Level:       LevelError,
+		Description: "An application is referenced by more than one SecurityConfig, so the webhook rejects its pods.",
+	}
+	RuleMissingApplication = Rule{
+		ID:          "missing-application",
+		Level:       LevelError,
+		Description: "The applicationRef of a SecurityConfig points to an Application that is not in the manifests.",
+	}
+	RuleMissingSecurityLabel = Rule{
+		ID:    "missing-security-label",
+		Level: LevelError,
+		Description: fmt.Sprintf(
+			"The Application of a SecurityConfig is not labelled %s=%s, so Texas is not injected into its pods.",
+			utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+		),
+	}
+	RuleOneSidedRule = Rule{
+		ID:          "one-sided-rule",
+		Level:       LevelWarning,
+		Description: "An access policy rule points to an application with TokenX enabled that does not declare the matching rule.",
+	}
+	RuleMissingWebhookLabel = Rule{
+		ID:    "missing-webhook-label",
+		Level: LevelError,
+		Description: fmt.Sprintf(
+			"TokenX is enabled in a namespace that is not labelled %s=%s, so the webhook is not called for its pods.",
+			utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+		),
+	}
+
+	// Rules are all the rules, in the order they are checked.
+	Rules = []Rule{
+		RuleDuplicateSecurityConfig,
+		RuleMissingApplication,
+		RuleMissingSecurityLabel,
+		RuleOneSidedRule,
+		RuleMissingWebhookLabel,
+	}
+)
+
+// Object is an object in a manifest, with the position of its document.
+type Object struct {
+	client.Object
+	File string
+	// Line is the 1-based line where the document of the object starts.
+	Line int
+}
+
+// Finding is a problem found in the manifests.
+type Finding struct {
+	Rule    Rule
+	Message string
+	File    string
+	Line    int
+}
+
+// Load reads the Applications, SecurityConfigs and Namespaces in the YAML and JSON files in dir and its
+// subdirectories. Objects of other kinds are skipped. Objects without a namespace are put in namespace.
+func Load(dir, namespace string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		fileObjects, err := loadFile(path, namespace)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests in %s: %w", dir, err)
+	}
+	return objects, nil
+}
+
+func loadFile(path, namespace string) ([]Object, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objects []Object
+	for _, document := range splitDocuments(string(content)) {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(document.content), &u.Object); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, document.line, err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		var obj client.Object
+		switch u.GroupVersionKind() {
+		case v1alpha1.GroupVersion.WithKind("Application"):
+			obj = &v1alpha1.Application{}
+		case v1alpha.GroupVersion.WithKind("SecurityConfig"):
+			obj = &v1alpha.SecurityConfig{}
+		case corev1.SchemeGroupVersion.WithKind("Namespace"):
+			obj = &corev1.Namespace{}
+		default:
+			continue
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+			return nil, fmt.Errorf("%s:%d: failed to convert %s %s: %w", path, document.line, u.GetKind(), u.GetName(), err)
+		}
+		if _, isNamespace := obj.(*corev1.Namespace); !isNamespace && obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		objects = append(objects, Object{Object: obj, File: path, Line: document.line})
+	}
+	return objects, nil
+}
+
+type document struct {
+	content string
+	line    int
+}
+
+// splitDocuments splits a YAML stream at the --- separators, keeping the line where every document starts.
+func splitDocuments(content string) []document {
+	var documents []document
+	lines := strings.Split(content, "\n")
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && !isSeparator(lines[i]) {
+			continue
+		}
+		documents = append(documents, document{
+			content: strings.Join(lines[start:i], "\n"),
+			line:    getFirstContentLine(lines, start, i) + 1,
+		})
+		start = i + 1
+	}
+	return documents
+}
+
+func isSeparator(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ")
+}
+
+func getFirstContentLine(lines []string, start, end int) int {
+	for i := start; i < end; i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return i
+		}
+	}
+	return start
+}
+
+// Lint checks the objects and returns the findings sorted by file and line.
+func Lint(objects []Object) []Finding {
+	var (
+		applications    = map[accesspolicy.Workload]Object{}
+		namespaces      = map[string]Object{}
+		securityConfigs []Object
+	)
+	var indexApplications []v1alpha1.Application
+	var indexSecurityConfigs []v1alpha.SecurityConfig
+	for _, obj := range objects {
+		switch o := obj.Object.(type) {
+		case *v1alpha1.Application:
+			applications[accesspolicy.Workload{Name: o.Name, Namespace: o.Namespace}] = obj
+			indexApplications = append(indexApplications, *o)
+		case *v1alpha.SecurityConfig:
+			securityConfigs = append(securityConfigs, obj)
+			indexSecurityConfigs = append(indexSecurityConfigs, *o)
+		case *corev1.Namespace:
+			namespaces[o.Name] = obj
+		}
+	}
+	index := accesspolicy.NewIndex(indexApplications, indexSecurityConfigs)
+	now := time.Now()
+
+	var findings []Finding
+	add := func(rule Rule, obj Object, format string, args ...any) {
+		findings = append(findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), File: obj.File, Line: obj.Line})
+	}
+
+	referencedBy := map[accesspolicy.Workload]Object{}
+	unlabelledNamespaces := map[string]bool{}
+	for _, obj := range securityConfigs {
+		securityConfig := obj.Object.(*v1alpha.SecurityConfig)
+		name := fmt.Sprintf("%s/%s", securityConfig.Namespace, securityConfig.Name)
+		w := accesspolicy.Workload{Name: securityConfig.Spec.ApplicationRef, Namespace: securityConfig.Namespace}
+
+		if first, exists := referencedBy[w]; exists {
+			add(
+				RuleDuplicateSecurityConfig, obj, "SecurityConfig %s references application %s, which is already referenced by SecurityConfig %s (%s:%d)",
+				name, w, first.GetName(), first.File, first.Line,
+			)
+			continue
+		}
+		referencedBy[w] = obj
+
+		application, exists := applications[w]
+		if !exists {
+			add(RuleMissingApplication, obj, "SecurityConfig %s references Application %s, which is not in the manifests", name, w)
+		} else if application.GetLabels()[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue {
+			add(
+				RuleMissingSecurityLabel, application, "Application %s is referenced by SecurityConfig %s but is not labelled %s=%s",
+				w, name, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+			)
+		}
+
+		if securityConfig.Spec.Tokenx == nil || !securityConfig.Spec.Tokenx.Enabled {
+			continue
+		}
+		if exists {
+			accessPolicy := accesspolicy.MergeAccessPolicy(
+				application.Object.(*v1alpha1.Application).Spec.AccessPolicy,
+				securityConfig.Spec.Tokenx.AccessPolicy,
+				now,
+			)
+			for _, rule := range index.GetOneSidedRules(w, accessPolicy) {
+				add(RuleOneSidedRule, application, "%s of %s has no matching rule in %s", rule, w, rule.Peer)
+			}
+		}
+		// The namespace is only reported once, for the first SecurityConfig enabling TokenX in it.
+		if namespace, exists := namespaces[securityConfig.Namespace]; exists && !unlabelledNamespaces[namespace.GetName()] &&
+			namespace.GetLabels()[utilities.WebhookNamespaceLabelName] != utilities.WebhookNamespaceLabelValue {
+			unlabelledNamespaces[namespace.GetName()] = true
+			add(
+				RuleMissingWebhookLabel, namespace, "SecurityConfig %s enables TokenX, but namespace %s is not labelled %s=%s",
+				name, securityConfig.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+			)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Rule.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	namespaceManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+`
+	callerManifest = `# The caller is missing the security label.
+apiVersion: skiperator.kartverket.no/v1alpha1
+kind: Application
+metadata:
+  name: caller
+spec:
+  image: image
+  port: 8080
+  accessPolicy:
+    outbound:
+      rules:
+        - application: target
+---
+apiVersion: accesserator.kartverket.no/v1alpha
+kind: SecurityConfig
+metadata:
+  name: caller
+spec:
+  applicationRef: caller
+  tokenx:
+    enabled: true
+`
+	targetApplicationManifest = `apiVersion: skiperator.kartverket.no/v1alpha1
+kind: Application
+metadata:
+  name: target
+  labels:
+    skiperator/security: enabled
+spec:
+  image: image
+  port: 8080
+---
+apiVersion: accesserator.kartverket.no/v1alpha
+kind: SecurityConfig
+metadata:
+  name: target
+spec:
+  applicationRef: target
+  tokenx:
+    enabled: true
+`
+	targetManifest = targetApplicationManifest + `---
+apiVersion: accesserator.kartverket.no/v1alpha
+kind: SecurityConfig
+metadata:
+  name: target-duplicate
+spec:
+  applicationRef: target
+---
+apiVersion: accesserator.kartverket.no/v1alpha
+kind: SecurityConfig
+metadata:
+  name: missing
+spec:
+  applicationRef: missing
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ignored
+`
+)
+
+func writeManifests(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespace.yaml"), []byte(namespaceManifest), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "apps"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps", "caller.yaml"), []byte(callerManifest), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps", "target.yml"), []byte(targetManifest), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0o600))
+	return dir
+}
+
+func TestLint(t *testing.T) {
+	dir := writeManifests(t)
+	objects, err := Load(dir, "ns")
+	require.NoError(t, err)
+	assert.Len(t, objects, 7)
+
+	findings := Lint(objects)
+	type position struct {
+		rule string
+		file string
+		line int
+	}
+	var positions []position
+	for _, finding := range findings {
+		rel, err := filepath.Rel(dir, finding.File)
+		require.NoError(t, err)
+		positions = append(positions, position{rule: finding.Rule.ID, file: rel, line: finding.Line})
+	}
+	assert.Equal(t, []position{
+		{rule: RuleMissingSecurityLabel.ID, file: "apps/caller.yaml", line: 2},
+		{rule: RuleOneSidedRule.ID, file: "apps/caller.yaml", line: 2},
+		{rule: RuleDuplicateSecurityConfig.ID, file: "apps/target.yml", line: 20},
+		{rule: RuleMissingApplication.ID, file: "apps/target.yml", line: 27},
+		{rule: RuleMissingWebhookLabel.ID, file: "namespace.yaml", line: 1},
+	}, positions)
+	assert.True(t, HasErrors(findings))
+	assert.Equal(t, "outbound rule ns/target of ns/caller has no matching rule in ns/target", findings[1].Message)
+}
+
+func TestLint_Clean(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "target.yaml"), []byte(targetApplicationManifest), 0o600))
+
+	objects, err := Load(dir, "ns")
+	require.NoError(t, err)
+	findings := Lint(objects)
+	assert.Empty(t, findings)
+	assert.False(t, HasErrors(findings))
+}
+
+func TestWrite(t *testing.T) {
+	findings := []Finding{{Rule: RuleOneSidedRule, Message: "one-sided", File: "apps/app.yaml", Line: 3}}
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Write(&out, findings, FormatText))
+		assert.Equal(t, "apps/app.yaml:3: warning [one-sided-rule] one-sided\n1 finding(s)\n", out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Write(&out, findings, FormatJSON))
+		var decoded []map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+		assert.Equal(t, []map[string]any{{
+			"rule": "one-sided-rule", "level": "warning", "message": "one-sided", "file": "apps/app.yaml", "line": float64(3),
+		}}, decoded)
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Write(&out, findings, FormatSARIF))
+		var decoded sarifLog
+		require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+		assert.Equal(t, "2.1.0", decoded.Version)
+		require.Len(t, decoded.Runs, 1)
+		assert.Len(t, decoded.Runs[0].Tool.Driver.Rules, len(Rules))
+		require.Len(t, decoded.Runs[0].Results, 1)
+		result := decoded.Runs[0].Results[0]
+		assert.Equal(t, "one-sided-rule", result.RuleID)
+		assert.Equal(t, 3, result.RuleIndex)
+		assert.Equal(t, "apps/app.yaml", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		assert.Equal(t, 3, result.Locations[0].PhysicalLocation.Region.StartLine)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ParseFormat("xml")
+		assert.Error(t, err)
+	})
+}
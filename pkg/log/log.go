@@ -4,7 +4,26 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Keys of the structured fields attached to log entries, so logs can be filtered and joined on them.
+const (
+	AdmissionUIDKey   = "admissionUID"
+	OperationKey      = "operation"
+	UserKey           = "user"
+	TraceIDKey        = "traceID"
+	SecurityConfigKey = "securityConfig"
+	ApplicationKey    = "application"
+	DescendantKindKey = "descendantKind"
+	DescendantNameKey = "descendantName"
+	SeverityKey       = "severity"
 )
 
 type _ interface {
@@ -22,8 +41,16 @@ func (l *Logger) Error(err error, msg string, keysAndValues ...interface{}) {
 	l.Logger.Error(err, msg, keysAndValues...)
 }
 
+// Warning logs at the warn level when the logger is backed by zap. logr has no warn level, so other loggers get an
+// info entry with severity=warning instead.
 func (l *Logger) Warning(msg string, keysAndValues ...interface{}) {
-	l.Logger.V(-1).Info(msg, keysAndValues...)
+	if underlier, ok := l.GetSink().(zapr.Underlier); ok {
+		// The underlying logger skips the logr frames, which are not on the stack here.
+		underlier.GetUnderlying().WithOptions(zap.AddCallerSkip(-1)).Sugar().Warnw(msg, keysAndValues...)
+		return
+	}
+	// keysAndValues is copied, since appending to it could overwrite the backing array of a slice of the caller.
+	l.Logger.Info(msg, append(append(make([]interface{}, 0, len(keysAndValues)+2), keysAndValues...), SeverityKey, "warning")...)
 }
 
 func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
@@ -34,8 +61,65 @@ func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
 	l.Logger.V(1).Info(msg, keysAndValues...)
 }
 
+// WithValues returns a Logger with the key-value pairs added to every entry.
+func (l Logger) WithValues(keysAndValues ...interface{}) Logger {
+	return Logger{l.Logger.WithValues(keysAndValues...)}
+}
+
+// WithName returns a Logger with the name appended to the logger name.
+func (l Logger) WithName(name string) Logger {
+	return Logger{l.Logger.WithName(name)}
+}
+
+// GetLogger returns the logger in the context. Loggers set up by controller-runtime already carry the reconcile ID of
+// reconciliations and the request ID of admission requests.
 func GetLogger(ctx context.Context) Logger {
 	return Logger{
 		ctrl.LoggerFrom(ctx),
 	}
 }
+
+// WithSecurityConfig returns a context whose logger carries the SecurityConfig, its application and the trace ID of
+// the context.
+func WithSecurityConfig(ctx context.Context, securityConfig *v1alpha.SecurityConfig) context.Context {
+	keysAndValues := []interface{}{
+		SecurityConfigKey, klog.KObj(securityConfig),
+		ApplicationKey, securityConfig.Spec.ApplicationRef,
+	}
+	return withValues(ctx, append(keysAndValues, correlationValues(ctx)...)...)
+}
+
+// WithDescendant returns a context whose logger carries the kind and name of a resource generated from a
+// SecurityConfig.
+func WithDescendant(ctx context.Context, kind, name string) context.Context {
+	return withValues(ctx, DescendantKindKey, kind, DescendantNameKey, name)
+}
+
+// WithAdmission returns a context whose logger carries the UID, operation and requesting user of the admission
+// request in the context, and the trace ID of the context. The context is returned unchanged outside admission
+// requests.
+func WithAdmission(ctx context.Context) context.Context {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return ctx
+	}
+	keysAndValues := []interface{}{
+		AdmissionUIDKey, string(req.UID),
+		OperationKey, string(req.Operation),
+		UserKey, req.UserInfo.Username,
+	}
+	return withValues(ctx, append(keysAndValues, correlationValues(ctx)...)...)
+}
+
+// correlationValues returns the trace ID of the context. The reconcile ID is not included since controller-runtime
+// already adds it to the logger of every reconciliation.
+func correlationValues(ctx context.Context) []interface{} {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return []interface{}{TraceIDKey, spanContext.TraceID().String()}
+	}
+	return nil
+}
+
+func withValues(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return ctrl.LoggerInto(ctx, ctrl.LoggerFrom(ctx).WithValues(keysAndValues...))
+}
@@ -6,10 +6,16 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/go-logr/zapr"
+	"github.com/kartverket/accesserator/api/v1alpha"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func setupTestLogger() (*Logger, *bytes.Buffer) {
@@ -144,3 +150,107 @@ func assertContainsKeyValue(t *testing.T, output, key, value string) {
 		t.Errorf("Expected log to contain key-value pair %s/%s, got: %s", key, value, output)
 	}
 }
+
+func setupTestContext() (context.Context, *bytes.Buffer) {
+	logger, buf := setupTestLogger()
+	return ctrl.LoggerInto(context.Background(), logger.Logger), buf
+}
+
+func TestLogger_WarningLevel(t *testing.T) {
+	var buf bytes.Buffer
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = ""
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	logger := Logger{Logger: zapr.NewLogger(zap.New(core, zap.AddCaller()))}
+
+	logger.Warning("warning message", "key", "value")
+
+	output := buf.String()
+	assertContains(t, output, "\"level\":\"warn\"")
+	assertContains(t, output, "\"caller\":\"log/log_test.go")
+}
+
+func TestLogger_WarningWithoutZap(t *testing.T) {
+	var messages []string
+	logger := Logger{Logger: funcr.New(func(prefix, args string) {
+		messages = append(messages, args)
+	}, funcr.Options{})}
+
+	logger.Warning("warning message", "key", "value")
+
+	if len(messages) != 1 {
+		t.Fatalf("Expected one log entry, got %d", len(messages))
+	}
+	assertContains(t, messages[0], "\"severity\"=\"warning\"")
+
+	keysAndValues := make([]interface{}, 2, 4)
+	keysAndValues[0], keysAndValues[1] = "key", "value"
+	spare := keysAndValues[:4]
+	spare[2], spare[3] = "other", "value"
+	logger.Warning("warning message", keysAndValues...)
+	if spare[2] != "other" || spare[3] != "value" {
+		t.Fatalf("Expected the slice of the caller to be left alone, got %v", spare)
+	}
+}
+
+func TestLogger_WithValues(t *testing.T) {
+	logger, buf := setupTestLogger()
+
+	withValues := logger.WithValues("key", "value").WithName("test")
+	withValues.Warning("warning message")
+
+	output := buf.String()
+	assertContainsKeyValue(t, output, "key", "value")
+	assertContains(t, output, "\"logger\":\"test\"")
+}
+
+func TestWithSecurityConfig(t *testing.T) {
+	ctx, buf := setupTestContext()
+	securityConfig := &v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-securityconfig", Namespace: "my-namespace"},
+		Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: "my-app"},
+	}
+
+	ctx = WithSecurityConfig(ctx, securityConfig)
+	ctx = WithDescendant(ctx, "Jwker", "my-app-jwker")
+	logger := GetLogger(ctx)
+	logger.Info("test message")
+
+	output := buf.String()
+	assertContains(t, output, "\"securityConfig\":{\"name\":\"my-securityconfig\",\"namespace\":\"my-namespace\"}")
+	assertContains(t, output, "\"application\":\"my-app\"")
+	assertContains(t, output, "\"descendantKind\":\"Jwker\"")
+	assertContains(t, output, "\"descendantName\":\"my-app-jwker\"")
+}
+
+func TestWithAdmission(t *testing.T) {
+	ctx, buf := setupTestContext()
+	ctx = admission.NewContextWithRequest(ctx, admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       "1234",
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "system:serviceaccount:my-namespace:deployer"},
+		},
+	})
+
+	ctx = WithAdmission(ctx)
+	logger := GetLogger(ctx)
+	logger.Info("test message")
+
+	output := buf.String()
+	assertContains(t, output, "\"admissionUID\":\"1234\"")
+	assertContains(t, output, "\"operation\":\"CREATE\"")
+	assertContains(t, output, "\"user\":\"system:serviceaccount:my-namespace:deployer\"")
+}
+
+func TestWithAdmission_NoRequest(t *testing.T) {
+	ctx, buf := setupTestContext()
+
+	ctx = WithAdmission(ctx)
+	logger := GetLogger(ctx)
+	logger.Info("test message")
+
+	if contains(buf.String(), AdmissionUIDKey) {
+		t.Errorf("Expected log not to contain %s, got: %s", AdmissionUIDKey, buf.String())
+	}
+}
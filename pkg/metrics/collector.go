@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const collectTimeout = 10 * time.Second
+
+var (
+	securityConfigsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "securityconfigs"),
+		"Number of SecurityConfigs, by namespace and phase.",
+		[]string{"namespace", "phase"},
+		nil,
+	)
+	jwkerSynchronizationStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "jwker_synchronization_state"),
+		"Synchronization state of the Jwkers generated from SecurityConfigs. The value is always 1.",
+		[]string{"namespace", "name", "state"},
+		nil,
+	)
+)
+
+// Collector reports the SecurityConfigs and the Jwkers generated from them as they are when scraped, so the metrics
+// also reflect objects that are not reconciled by this replica.
+type Collector struct {
+	Client client.Reader
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- securityConfigsDesc
+	ch <- jwkerSynchronizationStateDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	rlog := ctrl.Log.WithName("metrics")
+
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := c.Client.List(ctx, &securityConfigList); err != nil {
+		rlog.Error(err, "failed to list SecurityConfig resources")
+		ch <- prometheus.NewInvalidMetric(securityConfigsDesc, err)
+	} else {
+		for key, count := range countByPhase(securityConfigList.Items) {
+			ch <- prometheus.MustNewConstMetric(
+				securityConfigsDesc,
+				prometheus.GaugeValue,
+				float64(count),
+				key.namespace,
+				string(key.phase),
+			)
+		}
+	}
+
+	var jwkerList naisiov1.JwkerList
+	if err := c.Client.List(ctx, &jwkerList); err != nil {
+		rlog.Error(err, "failed to list Jwker resources")
+		ch <- prometheus.NewInvalidMetric(jwkerSynchronizationStateDesc, err)
+		return
+	}
+	for _, jwker := range jwkerList.Items {
+		if owner := metav1.GetControllerOf(&jwker); owner == nil || owner.Kind != "SecurityConfig" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			jwkerSynchronizationStateDesc,
+			prometheus.GaugeValue,
+			1,
+			jwker.Namespace,
+			jwker.Name,
+			jwker.Status.SynchronizationState,
+		)
+	}
+}
+
+type phaseKey struct {
+	namespace string
+	phase     v1alpha.Phase
+}
+
+func countByPhase(securityConfigs []v1alpha.SecurityConfig) map[phaseKey]int {
+	counts := map[phaseKey]int{}
+	for _, securityConfig := range securityConfigs {
+		counts[phaseKey{namespace: securityConfig.Namespace, phase: securityConfig.Status.Phase}]++
+	}
+	return counts
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "accesserator"
+
+// Admission outcomes of the pod webhooks.
+const (
+	AdmissionOutcomeInjected = "injected"
+	AdmissionOutcomeAllowed  = "allowed"
+	AdmissionOutcomeSkipped  = "skipped"
+	AdmissionOutcomeRejected = "rejected"
+)
+
+var (
+	descendantReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "descendant_reconcile_errors_total",
+			Help:      "Number of failed reconciliations of resources generated from SecurityConfigs, by kind.",
+		},
+		[]string{"kind"},
+	)
+	securityConfigTimeToReady = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "securityconfig_time_to_ready_seconds",
+			Help:      "Time from the creation of a SecurityConfig until it first becomes Ready.",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+	)
+	podAdmissions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pod_admissions_total",
+			Help:      "Number of pod admissions handled by the webhooks, by webhook, outcome and reason.",
+		},
+		[]string{"webhook", "outcome", "reason"},
+	)
+	podAdmissionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "pod_admission_duration_seconds",
+			Help:      "Latency of the pod webhooks, by webhook.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"webhook"},
+	)
+	auditEntriesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_entries_dropped_total",
+			Help:      "Number of audit entries that could not be written, by sink.",
+		},
+		[]string{"sink"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		descendantReconcileErrors,
+		securityConfigTimeToReady,
+		podAdmissions,
+		podAdmissionDuration,
+		auditEntriesDropped,
+	)
+}
+
+// RecordDescendantReconcileError counts a failed reconciliation of a resource of the kind.
+func RecordDescendantReconcileError(kind string) {
+	descendantReconcileErrors.WithLabelValues(kind).Inc()
+}
+
+// RecordSecurityConfigReady observes the time from creation until a SecurityConfig first became Ready.
+func RecordSecurityConfigReady(createdAt, readyAt time.Time) {
+	securityConfigTimeToReady.Observe(readyAt.Sub(createdAt).Seconds())
+}
+
+// RecordPodAdmission counts a pod admission by a webhook and observes how long it took.
+func RecordPodAdmission(webhook, outcome, reason string, duration time.Duration) {
+	podAdmissions.WithLabelValues(webhook, outcome, reason).Inc()
+	podAdmissionDuration.WithLabelValues(webhook).Observe(duration.Seconds())
+}
+
+// RecordAuditEntryDropped counts an audit entry that the sink could not write.
+func RecordAuditEntryDropped(sink string) {
+	auditEntriesDropped.WithLabelValues(sink).Inc()
+}
+
+// AuditEntriesDropped returns the counter of the audit entries the sink could not write.
+func AuditEntriesDropped(sink string) prometheus.Counter {
+	return auditEntriesDropped.WithLabelValues(sink)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getSecurityConfig(name, namespace string, phase v1alpha.Phase) *v1alpha.SecurityConfig {
+	return &v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     v1alpha.SecurityConfigStatus{Phase: phase},
+	}
+}
+
+func getJwker(name, namespace, ownerKind, state string) *naisiov1.Jwker {
+	jwker := &naisiov1.Jwker{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     naisiov1.JwkerStatus{SynchronizationState: state},
+	}
+	if ownerKind != "" {
+		jwker.OwnerReferences = []metav1.OwnerReference{
+			{Kind: ownerKind, Name: name, Controller: utilities.Ptr(true)},
+		}
+	}
+	return jwker
+}
+
+func TestCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	c := utilities.GetMockKubernetesClient(
+		scheme,
+		[]client.Object{
+			getSecurityConfig("a", "team-a", v1alpha.PhaseReady),
+			getSecurityConfig("b", "team-a", v1alpha.PhaseReady),
+			getSecurityConfig("c", "team-a", v1alpha.PhasePending),
+			getSecurityConfig("d", "team-b", v1alpha.PhaseFailed),
+			getJwker("a", "team-a", "SecurityConfig", "RolloutComplete"),
+			getJwker("c", "team-a", "SecurityConfig", "Failed"),
+			getJwker("unmanaged", "team-a", "", "RolloutComplete"),
+		}...,
+	)
+
+	expected := `
+# HELP accesserator_jwker_synchronization_state Synchronization state of the Jwkers generated from SecurityConfigs. The value is always 1.
+# TYPE accesserator_jwker_synchronization_state gauge
+accesserator_jwker_synchronization_state{name="a",namespace="team-a",state="RolloutComplete"} 1
+accesserator_jwker_synchronization_state{name="c",namespace="team-a",state="Failed"} 1
+# HELP accesserator_securityconfigs Number of SecurityConfigs, by namespace and phase.
+# TYPE accesserator_securityconfigs gauge
+accesserator_securityconfigs{namespace="team-a",phase="Pending"} 1
+accesserator_securityconfigs{namespace="team-a",phase="Ready"} 2
+accesserator_securityconfigs{namespace="team-b",phase="Failed"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(&Collector{Client: c}, strings.NewReader(expected)))
+}
+
+func TestRecordPodAdmission(t *testing.T) {
+	before := testutil.ToFloat64(podAdmissions.WithLabelValues("mutating", AdmissionOutcomeRejected, "application_not_found"))
+	RecordPodAdmission("mutating", AdmissionOutcomeRejected, "application_not_found", 10*time.Millisecond)
+	assert.Equal(
+		t,
+		before+1,
+		testutil.ToFloat64(podAdmissions.WithLabelValues("mutating", AdmissionOutcomeRejected, "application_not_found")),
+	)
+}
+
+func TestRecordDescendantReconcileError(t *testing.T) {
+	before := testutil.ToFloat64(descendantReconcileErrors.WithLabelValues("Jwker"))
+	RecordDescendantReconcileError("Jwker")
+	assert.Equal(t, before+1, testutil.ToFloat64(descendantReconcileErrors.WithLabelValues("Jwker")))
+}
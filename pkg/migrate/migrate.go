@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	naisiov1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultPort is the port of a NAIS application that does not set one.
+	defaultPort = 8080
+	// wildcard matches all applications or namespaces in a NAIS access policy rule.
+	wildcard = "*"
+)
+
+// Result is a NAIS Application converted to a Skiperator Application and a SecurityConfig.
+type Result struct {
+	Application *v1alpha1.Application
+	// SecurityConfig is nil when the NAIS Application does not enable TokenX.
+	SecurityConfig *v1alpha.SecurityConfig
+	// Unmapped describes the features of the NAIS Application that could not be converted.
+	Unmapped []string
+}
+
+// Decode reads the nais.io/v1alpha1 Applications in YAML or JSON manifests, with several documents separated by ---.
+// Objects of other kinds are skipped. Applications without a namespace are put in namespace.
+func Decode(r io.Reader, namespace string) ([]*naisiov1alpha1.Application, error) {
+	var applications []*naisiov1alpha1.Application
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return applications, nil
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if u.GroupVersionKind() != naisiov1alpha1.GroupVersion.WithKind("Application") {
+			continue
+		}
+		application := &naisiov1alpha1.Application{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, application); err != nil {
+			return nil, fmt.Errorf("failed to convert NAIS Application %s: %w", u.GetName(), err)
+		}
+		if application.Namespace == "" {
+			application.Namespace = namespace
+		}
+		applications = append(applications, application)
+	}
+}
+
+// Convert maps the image, port, access policy and TokenX client of a NAIS Application to a Skiperator Application
+// and a SecurityConfig. Other features are not converted, and the security-related ones are reported as unmapped.
+func Convert(naisApplication *naisiov1alpha1.Application) *Result {
+	result := &Result{}
+	spec := naisApplication.Spec
+	tokenxEnabled := spec.TokenX != nil && spec.TokenX.Enabled
+
+	port := spec.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	application := &v1alpha1.Application{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "Application"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      naisApplication.Name,
+			Namespace: naisApplication.Namespace,
+		},
+		Spec: v1alpha1.ApplicationSpec{
+			Image:        spec.Image,
+			Port:         port,
+			AccessPolicy: result.convertAccessPolicy(spec.AccessPolicy),
+		},
+	}
+	result.Application = application
+
+	if tokenxEnabled {
+		application.Labels = map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue}
+		// The access policy of the Application is used for the Jwker, so the SecurityConfig needs no rules of its own.
+		result.SecurityConfig = &v1alpha.SecurityConfig{
+			TypeMeta: metav1.TypeMeta{APIVersion: v1alpha.GroupVersion.String(), Kind: "SecurityConfig"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      naisApplication.Name,
+				Namespace: naisApplication.Namespace,
+			},
+			Spec: v1alpha.SecurityConfigSpec{
+				ApplicationRef: naisApplication.Name,
+				Tokenx:         &v1alpha.TokenXSpec{Enabled: true},
+			},
+		}
+	}
+
+	if spec.Maskinporten != nil && spec.Maskinporten.Enabled {
+		result.addUnmapped("maskinporten: Maskinporten clients are not supported by SecurityConfig yet")
+	}
+	if spec.Azure != nil && spec.Azure.Application != nil && spec.Azure.Application.Enabled {
+		result.addUnmapped("azure.application: Entra ID clients are not supported by SecurityConfig yet")
+	}
+	if spec.Azure != nil && spec.Azure.Sidecar != nil && spec.Azure.Sidecar.Enabled {
+		result.addUnmapped("azure.sidecar: the login proxy sidecar is not supported")
+	}
+	if spec.IDPorten != nil && spec.IDPorten.Enabled {
+		result.addUnmapped("idporten: ID-porten clients are not supported by SecurityConfig yet")
+	}
+	return result
+}
+
+func (r *Result) addUnmapped(format string, args ...any) {
+	r.Unmapped = append(r.Unmapped, fmt.Sprintf(format, args...))
+}
+
+func (r *Result) convertAccessPolicy(accessPolicy *naisiov1.AccessPolicy) *podtypes.AccessPolicy {
+	if accessPolicy == nil {
+		return nil
+	}
+	converted := &podtypes.AccessPolicy{}
+	if accessPolicy.Inbound != nil {
+		var inboundRules []podtypes.InternalRule
+		for i, rule := range accessPolicy.Inbound.Rules {
+			path := fmt.Sprintf("accessPolicy.inbound.rules[%d]", i)
+			if rule.Permissions != nil {
+				r.addUnmapped("%s.permissions: Entra ID scopes and roles are not supported", path)
+			}
+			if internalRule, ok := r.convertRule(path, rule.AccessPolicyRule); ok {
+				inboundRules = append(inboundRules, internalRule)
+			}
+		}
+		if len(inboundRules) > 0 {
+			converted.Inbound = &podtypes.InboundPolicy{Rules: inboundRules}
+		}
+	}
+	if accessPolicy.Outbound != nil {
+		for i, rule := range accessPolicy.Outbound.Rules {
+			if internalRule, ok := r.convertRule(fmt.Sprintf("accessPolicy.outbound.rules[%d]", i), rule); ok {
+				converted.Outbound.Rules = append(converted.Outbound.Rules, internalRule)
+			}
+		}
+		for _, rule := range accessPolicy.Outbound.External {
+			converted.Outbound.External = append(converted.Outbound.External, convertExternalRule(rule))
+		}
+	}
+	return converted
+}
+
+// convertRule converts a rule to another application, and reports whether it could be mapped.
+func (r *Result) convertRule(path string, rule naisiov1.AccessPolicyRule) (podtypes.InternalRule, bool) {
+	switch {
+	case rule.Cluster != "":
+		r.addUnmapped("%s: the rule to %s in cluster %s cannot be mapped, since rules cannot span clusters", path, rule.Application, rule.Cluster)
+		return podtypes.InternalRule{}, false
+	case rule.Application == wildcard || rule.Namespace == wildcard:
+		r.addUnmapped("%s: wildcard rules cannot be mapped, list the applications explicitly", path)
+		return podtypes.InternalRule{}, false
+	}
+	return podtypes.InternalRule{Application: rule.Application, Namespace: rule.Namespace}, true
+}
+
+func convertExternalRule(rule naisiov1.AccessPolicyExternalRule) podtypes.ExternalRule {
+	externalRule := podtypes.ExternalRule{Host: rule.Host, Ip: rule.IPv4}
+	for _, port := range rule.Ports {
+		externalRule.Ports = append(externalRule.Ports, podtypes.ExternalPort{
+			Name:     fmt.Sprintf("tcp-%d", port.Port),
+			Port:     int(port.Port),
+			Protocol: "TCP",
+		})
+	}
+	return externalRule
+}
+
+// Print writes the unmapped features as comments, followed by the converted objects as YAML manifests.
+func (r *Result) Print(w io.Writer) error {
+	for _, unmapped := range r.Unmapped {
+		if _, err := fmt.Fprintf(w, "# WARNING: %s/%s: %s\n", r.Application.Namespace, r.Application.Name, unmapped); err != nil {
+			return err
+		}
+	}
+	objects := []client.Object{r.Application}
+	if r.SecurityConfig != nil {
+		objects = append(objects, r.SecurityConfig)
+	}
+	for _, obj := range objects {
+		// Status is not part of a manifest.
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		delete(content, "status")
+		out, err := yaml.Marshal(content)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const manifests = `apiVersion: nais.io/v1alpha1
+kind: Application
+metadata:
+  name: app
+spec:
+  image: ghcr.io/navikt/app:1
+  tokenx:
+    enabled: true
+  maskinporten:
+    enabled: true
+  azure:
+    application:
+      enabled: true
+  accessPolicy:
+    inbound:
+      rules:
+        - application: caller
+        - application: other
+          namespace: other-team
+          cluster: prod-fss
+        - application: frontend
+          permissions:
+            roles:
+              - read
+    outbound:
+      rules:
+        - application: "*"
+        - application: backend
+          namespace: team
+      external:
+        - host: api.example.com
+          ports:
+            - port: 8443
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`
+
+func TestConvert(t *testing.T) {
+	applications, err := Decode(strings.NewReader(manifests), "ns")
+	require.NoError(t, err)
+	require.Len(t, applications, 1)
+	assert.Equal(t, "ns", applications[0].Namespace)
+
+	result := Convert(applications[0])
+
+	application := result.Application
+	assert.Equal(t, "ghcr.io/navikt/app:1", application.Spec.Image)
+	assert.Equal(t, defaultPort, application.Spec.Port)
+	assert.Equal(t, utilities.SecurityEnabledLabelValue, application.Labels[utilities.SecurityEnabledLabelName])
+	assert.Equal(t, &podtypes.AccessPolicy{
+		Inbound: &podtypes.InboundPolicy{Rules: []podtypes.InternalRule{{Application: "caller"}, {Application: "frontend"}}},
+		Outbound: podtypes.OutboundPolicy{
+			Rules: []podtypes.InternalRule{{Application: "backend", Namespace: "team"}},
+			External: []podtypes.ExternalRule{{
+				Host:  "api.example.com",
+				Ports: []podtypes.ExternalPort{{Name: "tcp-8443", Port: 8443, Protocol: "TCP"}},
+			}},
+		},
+	}, application.Spec.AccessPolicy)
+
+	require.NotNil(t, result.SecurityConfig)
+	assert.Equal(t, v1alpha.SecurityConfigSpec{ApplicationRef: "app", Tokenx: &v1alpha.TokenXSpec{Enabled: true}}, result.SecurityConfig.Spec)
+
+	assert.Equal(t, []string{
+		"accessPolicy.inbound.rules[1]: the rule to other in cluster prod-fss cannot be mapped, since rules cannot span clusters",
+		"accessPolicy.inbound.rules[2].permissions: Entra ID scopes and roles are not supported",
+		"accessPolicy.outbound.rules[0]: wildcard rules cannot be mapped, list the applications explicitly",
+		"maskinporten: Maskinporten clients are not supported by SecurityConfig yet",
+		"azure.application: Entra ID clients are not supported by SecurityConfig yet",
+	}, result.Unmapped)
+
+	var out bytes.Buffer
+	require.NoError(t, result.Print(&out))
+	assert.True(t, strings.HasPrefix(out.String(), "# WARNING: ns/app: accessPolicy.inbound.rules[1]"))
+	assert.Contains(t, out.String(), "kind: SecurityConfig")
+}
+
+func TestConvert_TokenxDisabled(t *testing.T) {
+	applications, err := Decode(strings.NewReader("apiVersion: nais.io/v1alpha1\nkind: Application\nmetadata:\n  name: app\nspec:\n  image: image\n  port: 9090\n"), "ns")
+	require.NoError(t, err)
+
+	result := Convert(applications[0])
+	assert.Nil(t, result.SecurityConfig)
+	assert.Nil(t, result.Application.Spec.AccessPolicy)
+	assert.Empty(t, result.Application.Labels)
+	assert.Equal(t, 9090, result.Application.Spec.Port)
+	assert.Empty(t, result.Unmapped)
+}
@@ -0,0 +1,125 @@
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	JwkerSecretNameSuffix = "jwker-secret"
+	EgressNameSuffix      = "egress"
+	// ExternalEgressNameSuffix is the suffix of the ServiceEntry and NetworkPolicy allowing egress to the external
+	// endpoints of a capability.
+	ExternalEgressNameSuffix = "external-egress"
+
+	// MaxLength is the maximum length of a generated name. Names are kept within the length limit of label values
+	// rather than the one of object names, since names end up in labels, e.g. the ones Jwker sets on its secret.
+	MaxLength = validation.DNS1123LabelMaxLength
+
+	hashLength = 8
+)
+
+// Name joins the parts with dashes. A name longer than MaxLength is truncated and suffixed with a hash of the whole
+// name, so the same parts always give the same name, and names that only differ after the truncation stay different.
+// Names within MaxLength are returned as they are, so existing resources keep their names.
+func Name(parts ...string) string {
+	name := strings.Join(parts, "-")
+	if len(name) <= MaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	prefix := strings.TrimRight(name[:MaxLength-hashLength-1], "-.")
+	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(sum[:])[:hashLength])
+}
+
+// GetJwkerName returns the name of the Jwker of the application. The name is the applicationRef as it is, since it is
+// part of the TokenX client ID (cluster:namespace:name) that the access policies of other applications and the
+// audience of Texas refer to. The resolver rejects applicationRefs longer than MaxLength instead.
+func GetJwkerName(applicationRef string) string {
+	return applicationRef
+}
+
+func GetJwkerSecretName(jwkerName string) string {
+	return Name(jwkerName, JwkerSecretNameSuffix)
+}
+
+func GetTokenxEgressName(securityConfigName string, tokenxConfigName string) string {
+	return Name(securityConfigName, tokenxConfigName, EgressNameSuffix)
+}
+
+func GetExternalEgressName(securityConfigName string, capability string) string {
+	return Name(securityConfigName, capability, ExternalEgressNameSuffix)
+}
+
+// GeneratedName is the name of a resource generated from a SecurityConfig.
+type GeneratedName struct {
+	Kind string
+	Name string
+	// Path is the field of the SecurityConfig the name is derived from.
+	Path *field.Path
+}
+
+// GetGeneratedNames returns the names of all the resources that can be generated from the SecurityConfig, whether or
+// not its capabilities are enabled.
+func GetGeneratedNames(securityConfig v1alpha.SecurityConfig) []GeneratedName {
+	applicationRefPath := field.NewPath("spec", "applicationRef")
+	namePath := field.NewPath("metadata", "name")
+	jwkerName := GetJwkerName(securityConfig.Spec.ApplicationRef)
+	tokenxEgressName := GetTokenxEgressName(securityConfig.Name, config.Get().TokenxName)
+
+	names := []GeneratedName{
+		{Kind: "Jwker", Name: jwkerName, Path: applicationRefPath},
+		{Kind: "Secret", Name: GetJwkerSecretName(jwkerName), Path: applicationRefPath},
+		{Kind: "NetworkPolicy", Name: tokenxEgressName, Path: namePath},
+		{Kind: "CiliumNetworkPolicy", Name: tokenxEgressName, Path: namePath},
+	}
+	for _, capability := range config.Get().EgressEndpoints.Capabilities() {
+		externalEgressName := GetExternalEgressName(securityConfig.Name, capability)
+		names = append(
+			names,
+			GeneratedName{Kind: "ServiceEntry", Name: externalEgressName, Path: namePath},
+			GeneratedName{Kind: "NetworkPolicy", Name: externalEgressName, Path: namePath},
+			GeneratedName{Kind: "CiliumNetworkPolicy", Name: externalEgressName, Path: namePath},
+		)
+	}
+	return names
+}
+
+// Collision is a name generated from a SecurityConfig that is also generated from another SecurityConfig.
+type Collision struct {
+	GeneratedName
+	// SecurityConfig is the name of the other SecurityConfig.
+	SecurityConfig string
+}
+
+// GetCollisions returns the names generated from the SecurityConfig that are also generated from one of the other
+// SecurityConfigs in its namespace. SecurityConfigs are expected to be in the same namespace.
+func GetCollisions(securityConfig v1alpha.SecurityConfig, others []v1alpha.SecurityConfig) []Collision {
+	type key struct{ kind, name string }
+	generatedBy := map[key]string{}
+	for _, other := range others {
+		if other.Name == securityConfig.Name {
+			continue
+		}
+		for _, generatedName := range GetGeneratedNames(other) {
+			if _, exists := generatedBy[key{generatedName.Kind, generatedName.Name}]; !exists {
+				generatedBy[key{generatedName.Kind, generatedName.Name}] = other.Name
+			}
+		}
+	}
+
+	var collisions []Collision
+	for _, generatedName := range GetGeneratedNames(securityConfig) {
+		if other, exists := generatedBy[key{generatedName.Kind, generatedName.Name}]; exists {
+			collisions = append(collisions, Collision{GeneratedName: generatedName, SecurityConfig: other})
+		}
+	}
+	return collisions
+}
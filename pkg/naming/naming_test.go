@@ -0,0 +1,92 @@
+package naming
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func loadConfig(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	require.NoError(t, config.Load())
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "sec-tok-egress", Name("sec", "tok", "egress"))
+
+	long := strings.Repeat("a", 60)
+	name := Name(long, "tokendings", "egress")
+	assert.Len(t, name, MaxLength)
+	assert.Empty(t, validation.IsDNS1123Label(name))
+	assert.Equal(t, name, Name(long, "tokendings", "egress"), "names should be stable")
+	assert.NotEqual(t, name, Name(long, "tokendings", "other"), "names that differ after the truncation should differ")
+
+	// The dash before the hash is not doubled when the truncation ends at a dash.
+	name = Name(strings.Repeat("a", MaxLength-hashLength-2), "b", "c")
+	assert.NotContains(t, name, "--")
+	assert.Empty(t, validation.IsDNS1123Label(name))
+}
+
+func TestGetJwkerName(t *testing.T) {
+	appRef := "my-app"
+	assert.Equal(t, appRef, GetJwkerName(appRef))
+	// The Jwker name is part of the TokenX client ID, so it is never truncated.
+	long := strings.Repeat("a", MaxLength+10)
+	assert.Equal(t, long, GetJwkerName(long))
+}
+
+func TestGetJwkerSecretName(t *testing.T) {
+	jwkerName := "foo"
+	want := fmt.Sprintf("%s-%s", jwkerName, JwkerSecretNameSuffix)
+	assert.Equal(t, want, GetJwkerSecretName(jwkerName))
+	assert.Len(t, GetJwkerSecretName(strings.Repeat("a", MaxLength)), MaxLength)
+}
+
+func TestGetTokenxEgressName(t *testing.T) {
+	secName := "sec"
+	tokenx := "tok"
+	want := fmt.Sprintf("%s-%s-%s", secName, tokenx, EgressNameSuffix)
+	assert.Equal(t, want, GetTokenxEgressName(secName, tokenx))
+}
+
+func TestGetExternalEgressName(t *testing.T) {
+	assert.Equal(t, "sec-maskinporten-external-egress", GetExternalEgressName("sec", "maskinporten"))
+}
+
+func getSecurityConfig(name, applicationRef string) v1alpha.SecurityConfig {
+	return v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: applicationRef},
+	}
+}
+
+func TestGetCollisions(t *testing.T) {
+	loadConfig(t)
+	t.Setenv("ACCESSERATOR_TOKENX_NAME", "external")
+	t.Setenv("ACCESSERATOR_EGRESS_ENDPOINTS", `{"tokenx": [{"host": "tokenx.example.com", "port": 443}]}`)
+	require.NoError(t, config.Load())
+
+	securityConfig := getSecurityConfig("b-tokenx", "a")
+	// The external egress of b for TokenX is named b-tokenx-external-egress, like the TokenX egress of b-tokenx.
+	other := getSecurityConfig("b", "b")
+
+	collisions := GetCollisions(securityConfig, []v1alpha.SecurityConfig{securityConfig, other, getSecurityConfig("c", "c")})
+	require.Len(t, collisions, 2)
+	for _, collision := range collisions {
+		assert.Equal(t, "b-tokenx-external-egress", collision.Name)
+		assert.Equal(t, "b", collision.SecurityConfig)
+		assert.Equal(t, "metadata.name", collision.Path.String())
+	}
+	assert.Equal(t, []string{"NetworkPolicy", "CiliumNetworkPolicy"}, []string{collisions[0].Kind, collisions[1].Kind})
+
+	assert.Empty(t, GetCollisions(getSecurityConfig("c", "c"), []v1alpha.SecurityConfig{securityConfig, other}))
+}
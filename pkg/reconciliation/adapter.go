@@ -0,0 +1,79 @@
+package reconciliation
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/kartverket/accesserator/internal/state"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ ControllerResource = ReconcilerAdapter[client.Object]{}
+
+func (c ReconcilerAdapter[T]) Reconcile(
+	ctx context.Context,
+	k8sClient client.Client,
+	scheme *runtime.Scheme,
+) (ctrl.Result, Outcome, error) {
+	return ReconcileControllerResource(
+		ctx,
+		k8sClient,
+		scheme,
+		c.Func.Scope,
+		c.Func.ResourceKind,
+		c.Func.ResourceName,
+		c.Func.DesiredResource,
+		c.Func.ShouldUpdate,
+		c.Func.UpdateFields,
+		c.Func.GroupVersionKind,
+	)
+}
+
+func (c ReconcilerAdapter[T]) GetResourceKind() string {
+	return c.Func.ResourceKind
+}
+
+func (c ReconcilerAdapter[T]) GetResourceName() string {
+	return c.Func.ResourceName
+}
+
+func (c ReconcilerAdapter[T]) IsResourceNil() bool {
+	return c.Func.DesiredResource == nil || reflect.ValueOf(*c.Func.DesiredResource).IsNil()
+}
+
+func (c ReconcilerAdapter[T]) GetDesiredResource() client.Object {
+	if c.IsResourceNil() {
+		return nil
+	}
+	return *c.Func.DesiredResource
+}
+
+// NewUnstructuredResource returns a controller resource for a type without a Go API in the scheme, such as the Istio
+// and Cilium resources. Only the spec is reconciled.
+func NewUnstructuredResource(
+	kind, name string,
+	desired *unstructured.Unstructured,
+	scope *state.Scope,
+	gvk schema.GroupVersionKind,
+) ControllerResource {
+	return ReconcilerAdapter[*unstructured.Unstructured]{
+		Func: ResourceReconciler[*unstructured.Unstructured]{
+			ResourceKind:    kind,
+			ResourceName:    name,
+			DesiredResource: &desired,
+			Scope:           scope,
+			ShouldUpdate: func(current, desired *unstructured.Unstructured) bool {
+				return !equality.Semantic.DeepEqual(current.Object["spec"], desired.Object["spec"])
+			},
+			UpdateFields: func(current, desired *unstructured.Unstructured) {
+				current.Object["spec"] = desired.Object["spec"]
+			},
+			GroupVersionKind: gvk,
+		},
+	}
+}
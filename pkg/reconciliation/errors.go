@@ -0,0 +1,16 @@
+package reconciliation
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// IsPermanentError reports whether reconciling a resource failed in a way that retrying cannot fix, because the API
+// server rejected the desired resource itself. Such errors are only resolved by changing the SecurityConfig, so they
+// make it Invalid instead of being requeued. All other errors, including timeouts, conflicts and unavailable API
+// servers, are transient.
+func IsPermanentError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) || apierrors.IsRequestEntityTooLargeError(err)
+}
@@ -4,20 +4,29 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/audit"
 	"github.com/kartverket/accesserator/pkg/log"
+	"github.com/kartverket/accesserator/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type ControllerResource interface {
-	Reconcile(ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme) (ctrl.Result, error)
+	Reconcile(ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme) (ctrl.Result, Outcome, error)
 	GetResourceKind() string
 	GetResourceName() string
 	IsResourceNil() bool
+	// GetDesiredResource returns the desired resource, or nil if the resource is not desired.
+	GetDesiredResource() client.Object
 }
 
 type ReconcilerAdapter[T client.Object] struct {
@@ -31,6 +40,27 @@ type ResourceReconciler[T client.Object] struct {
 	Scope           *state.Scope
 	ShouldUpdate    func(current T, desired T) bool
 	UpdateFields    func(current T, desired T)
+	// GroupVersionKind must be set when T is *unstructured.Unstructured, since the kind cannot be derived from T.
+	GroupVersionKind schema.GroupVersionKind
+}
+
+// Operation is what reconciling a resource did to it.
+type Operation string
+
+const (
+	OperationNone    Operation = "None"
+	OperationCreated Operation = "Created"
+	OperationUpdated Operation = "Updated"
+	OperationDeleted Operation = "Deleted"
+)
+
+// Outcome describes the change made by reconciling a resource, so events are only emitted for real changes.
+type Outcome struct {
+	Operation Operation
+	// Object is the resource that was changed, if any.
+	Object client.Object
+	// Changes lists the top-level spec fields changed by an update, e.g. "spec.egress".
+	Changes []string
 }
 
 func CountReconciledResources(rfs []ControllerResource) int {
@@ -52,36 +82,57 @@ func ReconcileControllerResource[T client.Object](
 	desired *T,
 	shouldUpdate func(current, desired T) bool,
 	updateFields func(current, desired T),
-) (ctrl.Result, error) {
+	gvk schema.GroupVersionKind,
+) (ctrl.Result, Outcome, error) {
+	ctx, span := tracing.Start(
+		ctx,
+		fmt.Sprintf("ReconcileControllerResource %s", resourceKind),
+		append(
+			tracing.SecurityConfigAttributes(scope.SecurityConfig),
+			attribute.String("accesserator.resource.kind", resourceKind),
+			attribute.String("accesserator.resource.name", resourceName),
+		)...,
+	)
+	ctx = log.WithDescendant(ctx, resourceKind, resourceName)
+	result, outcome, err := reconcileControllerResource(
+		ctx, k8sClient, scheme, scope, resourceKind, resourceName, desired, shouldUpdate, updateFields, gvk,
+	)
+	tracing.End(span, err)
+	return result, outcome, err
+}
+
+func reconcileControllerResource[T client.Object](
+	ctx context.Context,
+	k8sClient client.Client,
+	scheme *runtime.Scheme,
+	scope *state.Scope,
+	resourceKind, resourceName string,
+	desired *T,
+	shouldUpdate func(current, desired T) bool,
+	updateFields func(current, desired T),
+	gvk schema.GroupVersionKind,
+) (ctrl.Result, Outcome, error) {
+	noChange := Outcome{Operation: OperationNone}
 	rLog := log.GetLogger(ctx)
 	if desired == nil || reflect.ValueOf(*desired).IsNil() {
 		// Resource is not desired. Try deleting the existing one if it exists.
 		resourceType := reflect.TypeOf((*T)(nil)).Elem()
 		current, _ := reflect.New(resourceType.Elem()).Interface().(T)
+		setGroupVersionKind(current, gvk)
 
 		accessor := current
 		accessor.SetNamespace(scope.SecurityConfig.Namespace)
 		accessor.SetName(resourceName)
 
-		rLog.Info(
-			fmt.Sprintf(
-				"Desired %s %s/%s is nil. Will try to delete it if it exist",
-				resourceKind,
-				accessor.GetNamespace(),
-				accessor.GetName(),
-			),
-		)
-		rLog.Debug(
-			fmt.Sprintf("Checking if %s %s/%s exists", resourceKind, accessor.GetNamespace(), accessor.GetName()),
-		)
+		rLog.Info("Desired resource is nil. Will try to delete it if it exists")
+		rLog.Debug("Checking if resource exists")
 
 		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(accessor), current)
 		if err != nil {
-			if apierrors.IsNotFound(err) {
-				rLog.Debug(
-					fmt.Sprintf("%s %s/%s already deleted", resourceKind, accessor.GetNamespace(), accessor.GetName()),
-				)
-				return ctrl.Result{}, nil
+			// A resource whose CRD is not installed cannot exist either.
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				rLog.Debug("Resource already deleted")
+				return ctrl.Result{}, noChange, nil
 			}
 			getErrorMessage := fmt.Sprintf(
 				"Failed to get %s %s/%s when trying to delete it.",
@@ -89,19 +140,12 @@ func ReconcileControllerResource[T client.Object](
 				accessor.GetNamespace(),
 				accessor.GetName(),
 			)
-			rLog.Error(err, getErrorMessage)
+			rLog.Error(err, "Failed to get resource when trying to delete it")
 			scope.ReplaceDescendant(accessor, &getErrorMessage, nil, resourceKind, resourceName)
-			return ctrl.Result{}, err
+			return ctrl.Result{}, noChange, err
 		}
 
-		rLog.Info(
-			fmt.Sprintf(
-				"Deleting %s %s/%s as it's no longer desired",
-				resourceKind,
-				accessor.GetNamespace(),
-				accessor.GetName(),
-			),
-		)
+		rLog.Info("Deleting resource as it's no longer desired")
 		if deleteErr := k8sClient.Delete(ctx, current); deleteErr != nil {
 			deleteErrorMessage := fmt.Sprintf(
 				"Failed to delete %s %s/%s",
@@ -109,14 +153,19 @@ func ReconcileControllerResource[T client.Object](
 				accessor.GetNamespace(),
 				accessor.GetName(),
 			)
-			rLog.Error(deleteErr, deleteErrorMessage)
+			rLog.Error(deleteErr, "Failed to delete resource")
 			scope.ReplaceDescendant(accessor, &deleteErrorMessage, nil, resourceKind, resourceName)
-			return ctrl.Result{}, deleteErr
+			return ctrl.Result{}, noChange, deleteErr
 		}
 
-		rLog.Debug(
-			fmt.Sprintf("Successfully deleted %s %s/%s", resourceKind, accessor.GetNamespace(), accessor.GetName()),
-		)
+		rLog.Debug("Successfully deleted resource")
+		audit.Record(ctx, audit.Entry{
+			Action: audit.ActionDelete,
+			Actor:  audit.ActorAccesserator,
+			Object: audit.GetObjectRef(current, scheme),
+			Reason: fmt.Sprintf("No longer desired by SecurityConfig %s/%s", scope.SecurityConfig.Namespace, scope.SecurityConfig.Name),
+			Before: audit.GetSpec(current),
+		})
 		successMsg := fmt.Sprintf(
 			"Deleted %s %s/%s as it is no longer desired.",
 			resourceKind,
@@ -124,41 +173,20 @@ func ReconcileControllerResource[T client.Object](
 			accessor.GetName(),
 		)
 		scope.ReplaceDescendant(accessor, nil, &successMsg, resourceKind, resourceName)
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, Outcome{Operation: OperationDeleted, Object: current}, nil
 	}
 
 	deReferencedDesired := *desired
 
 	kind := reflect.TypeOf(deReferencedDesired).Elem().Name()
 	current, _ := reflect.New(reflect.TypeOf(deReferencedDesired).Elem()).Interface().(T)
+	setGroupVersionKind(current, gvk)
 
-	rLog.Info(
-		fmt.Sprintf(
-			"Trying to generate %s %s/%s",
-			kind,
-			deReferencedDesired.GetNamespace(),
-			deReferencedDesired.GetName(),
-		),
-	)
-
-	rLog.Debug(
-		fmt.Sprintf(
-			"Checking if %s %s/%s exists",
-			kind,
-			deReferencedDesired.GetNamespace(),
-			deReferencedDesired.GetName(),
-		),
-	)
+	rLog.Info("Trying to generate resource")
+	rLog.Debug("Checking if resource exists")
 	err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deReferencedDesired), current)
 	if apierrors.IsNotFound(err) {
-		rLog.Debug(
-			fmt.Sprintf(
-				"%s %s/%s does not exist",
-				kind,
-				deReferencedDesired.GetNamespace(),
-				deReferencedDesired.GetName(),
-			),
-		)
+		rLog.Debug("Resource does not exist")
 		if controllerRefErr := ctrl.SetControllerReference(
 			&scope.SecurityConfig,
 			deReferencedDesired,
@@ -171,12 +199,10 @@ func ReconcileControllerResource[T client.Object](
 				deReferencedDesired.GetName(),
 			)
 			scope.ReplaceDescendant(deReferencedDesired, &errorReason, nil, resourceKind, resourceName)
-			return ctrl.Result{}, controllerRefErr
+			return ctrl.Result{}, noChange, controllerRefErr
 		}
 
-		rLog.Info(
-			fmt.Sprintf("Creating %s %s/%s", kind, deReferencedDesired.GetNamespace(), deReferencedDesired.GetName()),
-		)
+		rLog.Info("Creating resource")
 		if createErr := k8sClient.Create(ctx, deReferencedDesired); createErr != nil {
 			errorReason := fmt.Sprintf(
 				"Unable to create %s %s/%s",
@@ -185,8 +211,15 @@ func ReconcileControllerResource[T client.Object](
 				deReferencedDesired.GetName(),
 			)
 			scope.ReplaceDescendant(deReferencedDesired, &errorReason, nil, resourceKind, resourceName)
-			return ctrl.Result{}, createErr
+			return ctrl.Result{}, noChange, createErr
 		}
+		audit.Record(ctx, audit.Entry{
+			Action: audit.ActionCreate,
+			Actor:  audit.ActorAccesserator,
+			Object: audit.GetObjectRef(deReferencedDesired, scheme),
+			Reason: fmt.Sprintf("Desired by SecurityConfig %s/%s", scope.SecurityConfig.Namespace, scope.SecurityConfig.Name),
+			After:  audit.GetSpec(deReferencedDesired),
+		})
 		successMessage := fmt.Sprintf(
 			"Successfully created %s %s/%s.",
 			kind,
@@ -195,7 +228,7 @@ func ReconcileControllerResource[T client.Object](
 		)
 		scope.ReplaceDescendant(deReferencedDesired, nil, &successMessage, resourceKind, resourceName)
 
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, Outcome{Operation: OperationCreated, Object: deReferencedDesired}, nil
 	}
 
 	if err != nil {
@@ -206,35 +239,13 @@ func ReconcileControllerResource[T client.Object](
 			deReferencedDesired.GetName(),
 		)
 		scope.ReplaceDescendant(deReferencedDesired, &errorReason, nil, resourceKind, resourceName)
-		return ctrl.Result{}, err
+		return ctrl.Result{}, noChange, err
 	}
 
-	rLog.Debug(fmt.Sprintf("%s %s/%s exists", kind, deReferencedDesired.GetNamespace(), deReferencedDesired.GetName()))
-	rLog.Debug(
-		fmt.Sprintf(
-			"Determine if %s %s/%s should be updated",
-			kind,
-			deReferencedDesired.GetNamespace(),
-			deReferencedDesired.GetName(),
-		),
-	)
+	outcome := noChange
+	rLog.Debug("Resource exists. Determining if it should be updated")
 	if shouldUpdate(current, deReferencedDesired) {
-		rLog.Debug(
-			fmt.Sprintf(
-				"Current %s %s/%s != desired",
-				kind,
-				deReferencedDesired.GetNamespace(),
-				deReferencedDesired.GetName(),
-			),
-		)
-		rLog.Debug(
-			fmt.Sprintf(
-				"Updating current %s %s/%s with desired",
-				kind,
-				deReferencedDesired.GetNamespace(),
-				deReferencedDesired.GetName(),
-			),
-		)
+		rLog.Debug("Current resource != desired. Updating it with desired")
 		before := current.DeepCopyObject().(client.Object)
 		updateFields(current, deReferencedDesired)
 
@@ -246,17 +257,19 @@ func ReconcileControllerResource[T client.Object](
 				current.GetName(),
 			)
 			scope.ReplaceDescendant(current, &errorReason, nil, resourceKind, resourceName)
-			return ctrl.Result{}, patchErr
+			return ctrl.Result{}, noChange, patchErr
 		}
+		audit.Record(ctx, audit.Entry{
+			Action: audit.ActionUpdate,
+			Actor:  audit.ActorAccesserator,
+			Object: audit.GetObjectRef(current, scheme),
+			Reason: fmt.Sprintf("Changed by SecurityConfig %s/%s", scope.SecurityConfig.Namespace, scope.SecurityConfig.Name),
+			Before: audit.GetSpec(before),
+			After:  audit.GetSpec(current),
+		})
+		outcome = Outcome{Operation: OperationUpdated, Object: current, Changes: GetChangedSpecFields(before, current)}
 	} else {
-		rLog.Debug(
-			fmt.Sprintf(
-				"Current %s %s/%s == desired. No update needed.",
-				kind,
-				deReferencedDesired.GetNamespace(),
-				deReferencedDesired.GetName(),
-			),
-		)
+		rLog.Debug("Current resource == desired. No update needed")
 	}
 
 	successMessage := fmt.Sprintf(
@@ -265,8 +278,34 @@ func ReconcileControllerResource[T client.Object](
 		current.GetNamespace(),
 		current.GetName(),
 	)
-	rLog.Info(successMessage)
+	rLog.Info("Successfully generated resource")
 	scope.ReplaceDescendant(current, nil, &successMessage, resourceKind, resourceName)
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, outcome, nil
+}
+
+// GetChangedSpecFields returns the sorted top-level spec fields that differ between the objects.
+func GetChangedSpecFields(before, after client.Object) []string {
+	beforeSpec, _ := audit.GetSpec(before).(map[string]interface{})
+	afterSpec, _ := audit.GetSpec(after).(map[string]interface{})
+
+	var changes []string
+	for field, value := range afterSpec {
+		if !equality.Semantic.DeepEqual(value, beforeSpec[field]) {
+			changes = append(changes, fmt.Sprintf("spec.%s", field))
+		}
+	}
+	for field := range beforeSpec {
+		if _, ok := afterSpec[field]; !ok {
+			changes = append(changes, fmt.Sprintf("spec.%s", field))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func setGroupVersionKind(obj client.Object, gvk schema.GroupVersionKind) {
+	if !gvk.Empty() {
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
 }
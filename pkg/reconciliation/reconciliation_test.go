@@ -0,0 +1,49 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestGetChangedSpecFields(t *testing.T) {
+	before := &v1.NetworkPolicy{
+		Spec: v1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "my-app"}},
+			PolicyTypes: []v1.PolicyType{v1.PolicyTypeEgress},
+		},
+	}
+
+	t.Run("returns no changes for equal specs", func(t *testing.T) {
+		assert.Empty(t, GetChangedSpecFields(before, before.DeepCopy()))
+	})
+
+	t.Run("returns the changed, added and removed fields sorted", func(t *testing.T) {
+		after := before.DeepCopy()
+		after.Spec.PodSelector.MatchLabels["app"] = "other-app"
+		after.Spec.PolicyTypes = nil
+		after.Spec.Egress = []v1.NetworkPolicyEgressRule{{}}
+
+		assert.Equal(t, []string{"spec.egress", "spec.podSelector", "spec.policyTypes"}, GetChangedSpecFields(before, after))
+	})
+}
+
+func TestIsPermanentError(t *testing.T) {
+	groupKind := v1.SchemeGroupVersion.WithKind("NetworkPolicy").GroupKind()
+	groupResource := v1.SchemeGroupVersion.WithResource("networkpolicies").GroupResource()
+	invalid := apierrors.NewInvalid(groupKind, "my-app", field.ErrorList{field.Required(field.NewPath("spec"), "")})
+
+	assert.False(t, IsPermanentError(nil))
+	assert.True(t, IsPermanentError(invalid))
+	assert.True(t, IsPermanentError(fmt.Errorf("failed to create: %w", apierrors.NewBadRequest("bad request"))))
+	assert.False(t, IsPermanentError(apierrors.NewConflict(groupResource, "my-app", errors.New("conflict"))))
+	assert.False(t, IsPermanentError(apierrors.NewServiceUnavailable("unavailable")))
+	assert.False(t, IsPermanentError(context.DeadlineExceeded))
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/resolver"
+	webhookv1 "github.com/kartverket/accesserator/internal/webhook/v1"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// skiperatorAppLabel is the label Skiperator sets on the pods of an application.
+const skiperatorAppLabel = "app"
+
+// PodPatch is the mutation the pod webhook would make to a pod of the application.
+type PodPatch struct {
+	Pod   string
+	Patch []jsonpatch.JsonPatchOperation
+	// Rejection is set when the webhook would reject the pod instead of mutating it.
+	Rejection string
+}
+
+// Result holds the resources Accesserator would produce for a SecurityConfig.
+type Result struct {
+	SecurityConfig types.NamespacedName
+	Objects        []client.Object
+	PodPatches     []PodPatch
+}
+
+// Decode reads the objects in YAML or JSON manifests, with several documents separated by ---. Objects without a
+// namespace are put in namespace.
+func Decode(r io.Reader, scheme *runtime.Scheme, namespace string) ([]client.Object, error) {
+	var objects []client.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		gvk := u.GroupVersionKind()
+		typed, err := scheme.New(gvk)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported kind %s in manifest: %w", gvk, err)
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s: %w", gvk.Kind, u.GetName(), err)
+		}
+		obj, ok := typed.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported kind %s in manifest", gvk)
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		objects = append(objects, obj)
+	}
+}
+
+// Render runs the resolver, the resource generators and the pod webhook on the objects without a cluster, and
+// returns what Accesserator would produce for every SecurityConfig among them. The pods of an application are
+// taken from the objects, or made up from its Application if there are none.
+func Render(ctx context.Context, scheme *runtime.Scheme, objects []client.Object) ([]*Result, error) {
+	k8sClient := utilities.GetMockKubernetesClient(scheme, objects...)
+
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources: %w", err)
+	}
+	if len(securityConfigList.Items) == 0 {
+		return nil, fmt.Errorf("the manifests contain no SecurityConfig")
+	}
+	sort.Slice(securityConfigList.Items, func(i, j int) bool {
+		return client.ObjectKeyFromObject(&securityConfigList.Items[i]).String() <
+			client.ObjectKeyFromObject(&securityConfigList.Items[j]).String()
+	})
+
+	results := make([]*Result, 0, len(securityConfigList.Items))
+	for _, securityConfig := range securityConfigList.Items {
+		result, err := renderSecurityConfig(ctx, k8sClient, scheme, securityConfig)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func renderSecurityConfig(
+	ctx context.Context,
+	k8sClient client.Client,
+	scheme *runtime.Scheme,
+	securityConfig v1alpha.SecurityConfig,
+) (*Result, error) {
+	key := client.ObjectKeyFromObject(&securityConfig)
+	scope, err := resolver.ResolveSecurityConfig(ctx, k8sClient, securityConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SecurityConfig %s: %w", key, err)
+	}
+	if scope.Orphaned {
+		return nil, fmt.Errorf(
+			"SecurityConfig %s references Application %s, which is not in the manifests",
+			key,
+			securityConfig.Spec.ApplicationRef,
+		)
+	}
+	if scope.InvalidConfig {
+		return nil, fmt.Errorf("SecurityConfig %s is invalid: %s", key, *scope.ValidationErrorMessage)
+	}
+
+	result := &Result{SecurityConfig: key}
+	for _, c := range capability.All() {
+		for _, resource := range c.DesiredResources(scope) {
+			// Resources that are not desired, such as the ones of the egress backends that are not selected, are nil.
+			obj := resource.GetDesiredResource()
+			if obj == nil {
+				continue
+			}
+			gvk, err := apiutil.GVKForObject(obj, scheme)
+			if err != nil {
+				return nil, err
+			}
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+			result.Objects = append(result.Objects, obj)
+		}
+	}
+
+	pods, err := getPods(ctx, k8sClient, securityConfig)
+	if err != nil {
+		return nil, err
+	}
+	defaulter := &webhookv1.PodCustomDefaulter{Client: k8sClient}
+	for _, pod := range pods {
+		podPatch, err := getPodPatch(ctx, defaulter, pod)
+		if err != nil {
+			return nil, err
+		}
+		result.PodPatches = append(result.PodPatches, podPatch)
+	}
+	return result, nil
+}
+
+func getPods(ctx context.Context, k8sClient client.Client, securityConfig v1alpha.SecurityConfig) ([]corev1.Pod, error) {
+	appName := securityConfig.Spec.ApplicationRef
+	var podList corev1.PodList
+	if err := k8sClient.List(
+		ctx,
+		&podList,
+		client.InNamespace(securityConfig.Namespace),
+		client.MatchingLabels{utilities.SkiperatorApplicationRefLabel: appName},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list pods of application %s/%s: %w", securityConfig.Namespace, appName, err)
+	}
+	if len(podList.Items) > 0 {
+		return podList.Items, nil
+	}
+
+	application := &v1alpha1.Application{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: appName, Namespace: securityConfig.Namespace}, application); err != nil {
+		return nil, fmt.Errorf("failed to fetch Application %s/%s: %w", securityConfig.Namespace, appName, err)
+	}
+	// Skiperator names the application container after the application and labels the pods with its name.
+	return []corev1.Pod{{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: securityConfig.Namespace,
+			Labels:    map[string]string{utilities.SkiperatorApplicationRefLabel: appName, skiperatorAppLabel: appName},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: appName, Image: application.Spec.Image}}},
+	}}, nil
+}
+
+func getPodPatch(ctx context.Context, defaulter *webhookv1.PodCustomDefaulter, pod corev1.Pod) (PodPatch, error) {
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return PodPatch{}, fmt.Errorf("failed to marshal pod %s: %w", pod.Name, err)
+	}
+	mutated := pod.DeepCopy()
+	if err := defaulter.Default(ctx, mutated); err != nil {
+		return PodPatch{Pod: pod.Name, Rejection: err.Error()}, nil
+	}
+	mutatedJSON, err := json.Marshal(mutated)
+	if err != nil {
+		return PodPatch{}, fmt.Errorf("failed to marshal pod %s: %w", pod.Name, err)
+	}
+	patch, err := jsonpatch.CreatePatch(original, mutatedJSON)
+	if err != nil {
+		return PodPatch{}, fmt.Errorf("failed to create patch for pod %s: %w", pod.Name, err)
+	}
+	sort.Slice(patch, func(i, j int) bool { return patch[i].Path < patch[j].Path })
+	return PodPatch{Pod: pod.Name, Patch: patch}, nil
+}
+
+// Print writes the resources as YAML documents, followed by the JSON patches of the pods.
+func (r *Result) Print(w io.Writer) error {
+	for _, obj := range r.Objects {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n# Desired by SecurityConfig %s\n%s", r.SecurityConfig, out); err != nil {
+			return err
+		}
+	}
+	for _, podPatch := range r.PodPatches {
+		if podPatch.Rejection != "" {
+			if _, err := fmt.Fprintf(w, "---\n# Pod %s would be rejected: %s\n", podPatch.Pod, podPatch.Rejection); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(podPatch.Patch) == 0 {
+			if _, err := fmt.Fprintf(w, "---\n# Pod %s would not be mutated\n", podPatch.Pod); err != nil {
+				return err
+			}
+			continue
+		}
+		out, err := yaml.Marshal(podPatch.Patch)
+		if err != nil {
+			return fmt.Errorf("failed to marshal patch of pod %s: %w", podPatch.Pod, err)
+		}
+		if _, err := fmt.Fprintf(w, "---\n# JSON patch of pod %s by the mutating webhook\n%s", podPatch.Pod, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
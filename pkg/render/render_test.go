@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+const manifests = `apiVersion: skiperator.kartverket.no/v1alpha1
+kind: Application
+metadata:
+  name: app
+  labels:
+    skiperator/security: enabled
+spec:
+  image: image
+  port: 8080
+  accessPolicy:
+    inbound:
+      rules:
+        - application: caller
+---
+apiVersion: accesserator.kartverket.no/v1alpha
+kind: SecurityConfig
+metadata:
+  name: app
+spec:
+  applicationRef: app
+  tokenx:
+    enabled: true
+`
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func loadConfig(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	require.NoError(t, config.Load())
+}
+
+func TestDecode(t *testing.T) {
+	objects, err := Decode(strings.NewReader(manifests), getScheme(t), "ns")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.IsType(t, &v1alpha1.Application{}, objects[0])
+	assert.Equal(t, "ns", objects[1].GetNamespace())
+
+	_, err = Decode(strings.NewReader("apiVersion: v1\nkind: Unknown\nmetadata:\n  name: x\n"), getScheme(t), "ns")
+	assert.ErrorContains(t, err, "unsupported kind")
+}
+
+func TestRender(t *testing.T) {
+	loadConfig(t)
+	scheme := getScheme(t)
+	objects, err := Decode(strings.NewReader(manifests), scheme, "ns")
+	require.NoError(t, err)
+
+	results, err := Render(context.Background(), scheme, objects)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+
+	require.Len(t, result.Objects, 2)
+	jwker, ok := result.Objects[0].(*naisiov1.Jwker)
+	require.True(t, ok)
+	assert.Equal(t, "Jwker", jwker.Kind)
+	assert.Equal(t, "caller", jwker.Spec.AccessPolicy.Inbound.Rules[0].Application)
+	assert.IsType(t, &networkingv1.NetworkPolicy{}, result.Objects[1])
+
+	require.Len(t, result.PodPatches, 1)
+	podPatch := result.PodPatches[0]
+	assert.Empty(t, podPatch.Rejection)
+	var paths []string
+	for _, operation := range podPatch.Patch {
+		paths = append(paths, operation.Path)
+	}
+	assert.Equal(t, []string{"/spec/containers/0/env", "/spec/initContainers"}, paths)
+
+	var out bytes.Buffer
+	require.NoError(t, result.Print(&out))
+	assert.Contains(t, out.String(), "kind: Jwker")
+	assert.Contains(t, out.String(), "# JSON patch of pod app by the mutating webhook")
+	assert.Contains(t, out.String(), "image: ghcr.io/nais/texas:latest")
+}
+
+func TestRender_TokenxDisabled(t *testing.T) {
+	loadConfig(t)
+	scheme := getScheme(t)
+	objects, err := Decode(strings.NewReader(strings.Replace(manifests, "enabled: true", "enabled: false", 1)), scheme, "ns")
+	require.NoError(t, err)
+
+	results, err := Render(context.Background(), scheme, objects)
+	require.NoError(t, err)
+	assert.Empty(t, results[0].Objects)
+	require.Len(t, results[0].PodPatches, 1)
+	assert.NotEmpty(t, results[0].PodPatches[0].Rejection)
+}
+
+func TestRender_NoSecurityConfig(t *testing.T) {
+	_, err := Render(context.Background(), getScheme(t), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package cilium
+
+import (
+	"strconv"
+
+	"github.com/kartverket/accesserator/pkg/config"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CiliumNetworkPolicyGVK is the GroupVersionKind of the CiliumNetworkPolicy. Cilium types are handled as unstructured
+// objects to avoid depending on the Cilium API.
+var CiliumNetworkPolicyGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2", Kind: "CiliumNetworkPolicy"}
+
+// namespaceLabelPrefix is the prefix Cilium adds to the labels of the namespace of an endpoint.
+const namespaceLabelPrefix = "k8s:io.cilium.k8s.namespace.labels."
+
+// GetEndpointSelector returns an endpoint selector matching the pods with podLabels in the namespaces with
+// namespaceLabels.
+func GetEndpointSelector(podLabels, namespaceLabels map[string]string) map[string]interface{} {
+	matchLabels := map[string]interface{}{}
+	for key, value := range podLabels {
+		matchLabels[key] = value
+	}
+	for key, value := range namespaceLabels {
+		matchLabels[namespaceLabelPrefix+key] = value
+	}
+	return map[string]interface{}{"matchLabels": matchLabels}
+}
+
+// GetToPorts returns the toPorts of an egress rule allowing the ports, or nil to allow all ports.
+func GetToPorts(protocol string, ports ...int32) []interface{} {
+	if len(ports) == 0 {
+		return nil
+	}
+	portProtocols := make([]interface{}, 0, len(ports))
+	for _, port := range ports {
+		portProtocols = append(portProtocols, map[string]interface{}{
+			"port":     strconv.Itoa(int(port)),
+			"protocol": protocol,
+		})
+	}
+	return []interface{}{map[string]interface{}{"ports": portProtocols}}
+}
+
+// GetDnsEgressRule returns an egress rule allowing DNS lookups through the Cilium DNS proxy, which is required for
+// toFQDNs rules to match.
+func GetDnsEgressRule() map[string]interface{} {
+	cfg := config.Get()
+	return map[string]interface{}{
+		"toEndpoints": []interface{}{
+			GetEndpointSelector(
+				cfg.DnsPodSelectorLabels,
+				cfg.GetDnsNamespaceSelectorLabels(),
+			),
+		},
+		"toPorts": []interface{}{
+			map[string]interface{}{
+				"ports": []interface{}{
+					map[string]interface{}{"port": strconv.Itoa(int(cfg.DnsPort)), "protocol": "ANY"},
+				},
+				"rules": map[string]interface{}{
+					"dns": []interface{}{map[string]interface{}{"matchPattern": "*"}},
+				},
+			},
+		},
+	}
+}
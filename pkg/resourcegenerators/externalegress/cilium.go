@@ -0,0 +1,43 @@
+package externalegress
+
+import (
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// GetDesiredCiliumNetworkPolicy returns a CiliumNetworkPolicy allowing the application to reach the external
+// endpoints of the capability by host name when the cilium egress backend is selected, or nil otherwise.
+func GetDesiredCiliumNetworkPolicy(objectMeta metav1.ObjectMeta, scope *state.Scope, capabilityName string) *unstructured.Unstructured {
+	cfg := config.Get()
+	endpoints := cfg.EgressEndpoints[capabilityName]
+	if !capability.IsEnabled(capabilityName, scope) || len(endpoints) == 0 || cfg.EgressBackend != config.EgressBackendCilium {
+		return nil
+	}
+
+	egress := make([]interface{}, 0, len(endpoints)+1)
+	for _, endpoint := range endpoints {
+		egress = append(egress, map[string]interface{}{
+			"toFQDNs": []interface{}{map[string]interface{}{"matchName": endpoint.Host}},
+			"toPorts": cilium.GetToPorts("TCP", endpoint.Port),
+		})
+	}
+	// toFQDNs rules only match hosts resolved through the Cilium DNS proxy.
+	egress = append(egress, cilium.GetDnsEgressRule())
+
+	ciliumNetworkPolicy := &unstructured.Unstructured{}
+	ciliumNetworkPolicy.SetGroupVersionKind(cilium.CiliumNetworkPolicyGVK)
+	ciliumNetworkPolicy.SetName(objectMeta.Name)
+	ciliumNetworkPolicy.SetNamespace(objectMeta.Namespace)
+	ciliumNetworkPolicy.Object["spec"] = map[string]interface{}{
+		"endpointSelector": cilium.GetEndpointSelector(
+			map[string]string{cfg.EgressPodSelectorLabel: scope.SecurityConfig.Spec.ApplicationRef},
+			nil,
+		),
+		"egress": egress,
+	}
+	return ciliumNetworkPolicy
+}
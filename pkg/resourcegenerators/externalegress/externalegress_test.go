@@ -0,0 +1,115 @@
+package externalegress
+
+import (
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	_ "github.com/kartverket/accesserator/pkg/capability/tokenx"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getScope(tokenxEnabled bool) *state.Scope {
+	return &state.Scope{
+		SecurityConfig: v1alpha.SecurityConfig{Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: "app",
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: tokenxEnabled},
+		}},
+	}
+}
+
+func loadConfig(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	t.Setenv(
+		"ACCESSERATOR_EGRESS_ENDPOINTS",
+		`{"tokenx":[{"host":"tokenx.example.com","port":443},{"host":"jwks.example.com","port":8443,"cidrs":["10.0.0.0/8","192.168.0.0/16"]}],"maskinporten":[{"host":"maskinporten.no","port":443}]}`,
+	)
+	require.NoError(t, config.Load())
+}
+
+func TestGetDesiredServiceEntry(t *testing.T) {
+	loadConfig(t)
+
+	serviceEntry := GetDesiredServiceEntry(metav1.ObjectMeta{Name: "egress", Namespace: "ns"}, getScope(true), state.CapabilityTokenX)
+	require.NotNil(t, serviceEntry)
+	assert.Equal(t, ServiceEntryGVK, serviceEntry.GroupVersionKind())
+	assert.Equal(t, "egress", serviceEntry.GetName())
+	assert.Equal(t, "ns", serviceEntry.GetNamespace())
+	assert.Equal(t, map[string]interface{}{
+		"exportTo": []interface{}{"."},
+		"hosts":    []interface{}{"jwks.example.com", "tokenx.example.com"},
+		"location": "MESH_EXTERNAL",
+		"ports": []interface{}{
+			map[string]interface{}{"name": "https-443", "number": int64(443), "protocol": "HTTPS"},
+			map[string]interface{}{"name": "tcp-8443", "number": int64(8443), "protocol": "TCP"},
+		},
+		"resolution": "DNS",
+	}, serviceEntry.Object["spec"])
+}
+
+func TestGetDesiredServiceEntry_NotEnabled(t *testing.T) {
+	loadConfig(t)
+
+	assert.Nil(t, GetDesiredServiceEntry(metav1.ObjectMeta{Name: "egress"}, getScope(false), state.CapabilityTokenX))
+	assert.Nil(t, GetDesiredServiceEntry(metav1.ObjectMeta{Name: "egress"}, getScope(true), "maskinporten"))
+	orphaned := getScope(true)
+	orphaned.Orphaned = true
+	assert.Nil(t, GetDesiredServiceEntry(metav1.ObjectMeta{Name: "egress"}, orphaned, state.CapabilityTokenX))
+	assert.Nil(t, GetDesiredServiceEntry(metav1.ObjectMeta{Name: "egress"}, getScope(true), "idporten"))
+}
+
+func TestGetDesiredNetworkPolicy(t *testing.T) {
+	loadConfig(t)
+
+	networkPolicy := GetDesiredNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(true), state.CapabilityTokenX)
+	require.NotNil(t, networkPolicy)
+	assert.Equal(t, map[string]string{"app": "app"}, networkPolicy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []v1.PolicyType{v1.PolicyTypeEgress}, networkPolicy.Spec.PolicyTypes)
+	require.Len(t, networkPolicy.Spec.Egress, 2)
+	assert.Equal(t, []v1.NetworkPolicyPeer{{IPBlock: &v1.IPBlock{CIDR: "0.0.0.0/0"}}}, networkPolicy.Spec.Egress[0].To)
+	assert.Equal(t, int32(443), networkPolicy.Spec.Egress[0].Ports[0].Port.IntVal)
+	assert.Equal(t, []v1.NetworkPolicyPeer{
+		{IPBlock: &v1.IPBlock{CIDR: "10.0.0.0/8"}},
+		{IPBlock: &v1.IPBlock{CIDR: "192.168.0.0/16"}},
+	}, networkPolicy.Spec.Egress[1].To)
+	assert.Equal(t, int32(8443), networkPolicy.Spec.Egress[1].Ports[0].Port.IntVal)
+}
+
+func TestGetDesiredNetworkPolicy_NotEnabled(t *testing.T) {
+	loadConfig(t)
+
+	assert.Nil(t, GetDesiredNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(false), state.CapabilityTokenX))
+	assert.Nil(t, GetDesiredNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(true), "maskinporten"))
+}
+
+func TestGetDesiredCiliumNetworkPolicy(t *testing.T) {
+	loadConfig(t)
+	t.Setenv("ACCESSERATOR_EGRESS_BACKEND", "cilium")
+	require.NoError(t, config.Load())
+
+	assert.Nil(t, GetDesiredNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(true), state.CapabilityTokenX))
+	ciliumNetworkPolicy := GetDesiredCiliumNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(true), state.CapabilityTokenX)
+	require.NotNil(t, ciliumNetworkPolicy)
+	egress, ok := ciliumNetworkPolicy.Object["spec"].(map[string]interface{})["egress"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, egress, 3)
+	assert.Equal(t, map[string]interface{}{
+		"toFQDNs": []interface{}{map[string]interface{}{"matchName": "tokenx.example.com"}},
+		"toPorts": []interface{}{
+			map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": "443", "protocol": "TCP"}}},
+		},
+	}, egress[0])
+	assert.Equal(t, cilium.GetDnsEgressRule(), egress[2])
+}
+
+func TestGetDesiredCiliumNetworkPolicy_OtherBackend(t *testing.T) {
+	loadConfig(t)
+	assert.Nil(t, GetDesiredCiliumNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope(true), state.CapabilityTokenX))
+}
@@ -0,0 +1,58 @@
+package externalegress
+
+import (
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// GetDesiredNetworkPolicy returns a NetworkPolicy allowing the application to reach the external endpoints of the
+// capability when the networkpolicy egress backend is selected, or nil if the capability is not enabled or has no
+// endpoints.
+func GetDesiredNetworkPolicy(objectMeta metav1.ObjectMeta, scope *state.Scope, capabilityName string) *v1.NetworkPolicy {
+	cfg := config.Get()
+	endpoints := cfg.EgressEndpoints[capabilityName]
+	if !capability.IsEnabled(capabilityName, scope) || len(endpoints) == 0 || cfg.EgressBackend != config.EgressBackendNetworkPolicy {
+		return nil
+	}
+
+	egress := make([]v1.NetworkPolicyEgressRule, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		cidrs := endpoint.CIDRs
+		if len(cidrs) == 0 {
+			cidrs = []string{"0.0.0.0/0"}
+		}
+		peers := make([]v1.NetworkPolicyPeer, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			peers = append(peers, v1.NetworkPolicyPeer{IPBlock: &v1.IPBlock{CIDR: cidr}})
+		}
+		egress = append(egress, v1.NetworkPolicyEgressRule{
+			To: peers,
+			Ports: []v1.NetworkPolicyPort{
+				{
+					Protocol: &[]corev1.Protocol{corev1.ProtocolTCP}[0],
+					Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: endpoint.Port},
+				},
+			},
+		})
+	}
+
+	return &v1.NetworkPolicy{
+		ObjectMeta: objectMeta,
+		Spec: v1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					cfg.EgressPodSelectorLabel: scope.SecurityConfig.Spec.ApplicationRef,
+				},
+			},
+			PolicyTypes: []v1.PolicyType{
+				v1.PolicyTypeEgress,
+			},
+			Egress: egress,
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package externalegress
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/capability"
+	"github.com/kartverket/accesserator/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceEntryGVK is the GroupVersionKind of the Istio ServiceEntry. Istio types are handled as unstructured objects
+// to avoid depending on the Istio API.
+var ServiceEntryGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "ServiceEntry"}
+
+// GetDesiredServiceEntry returns a ServiceEntry registering the external endpoints of the capability in the mesh, or
+// nil if the capability is not enabled or has no endpoints.
+func GetDesiredServiceEntry(objectMeta metav1.ObjectMeta, scope *state.Scope, capabilityName string) *unstructured.Unstructured {
+	endpoints := config.Get().EgressEndpoints[capabilityName]
+	if !capability.IsEnabled(capabilityName, scope) || len(endpoints) == 0 {
+		return nil
+	}
+
+	hosts := map[string]bool{}
+	ports := map[int32]bool{}
+	for _, endpoint := range endpoints {
+		hosts[endpoint.Host] = true
+		ports[endpoint.Port] = true
+	}
+
+	sortedHosts := make([]interface{}, 0, len(hosts))
+	for _, host := range sortedKeys(hosts, func(a, b string) bool { return a < b }) {
+		sortedHosts = append(sortedHosts, host)
+	}
+	servicePorts := make([]interface{}, 0, len(ports))
+	for _, port := range sortedKeys(ports, func(a, b int32) bool { return a < b }) {
+		protocol := "TCP"
+		if port == 443 {
+			protocol = "HTTPS"
+		}
+		servicePorts = append(servicePorts, map[string]interface{}{
+			"name":     fmt.Sprintf("%s-%d", strings.ToLower(protocol), port),
+			"number":   int64(port),
+			"protocol": protocol,
+		})
+	}
+
+	serviceEntry := &unstructured.Unstructured{}
+	serviceEntry.SetGroupVersionKind(ServiceEntryGVK)
+	serviceEntry.SetName(objectMeta.Name)
+	serviceEntry.SetNamespace(objectMeta.Namespace)
+	serviceEntry.Object["spec"] = map[string]interface{}{
+		"exportTo":   []interface{}{"."},
+		"hosts":      sortedHosts,
+		"location":   "MESH_EXTERNAL",
+		"ports":      servicePorts,
+		"resolution": "DNS",
+	}
+	return serviceEntry
+}
+
+func sortedKeys[K comparable](m map[K]bool, less func(a, b K) bool) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool { return less(keys[a], keys[b]) })
+	return keys
+}
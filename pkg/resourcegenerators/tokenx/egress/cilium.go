@@ -0,0 +1,44 @@
+package egress
+
+import (
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// GetDesiredCiliumNetworkPolicy returns a CiliumNetworkPolicy allowing the application to reach Tokendings when the
+// cilium egress backend is selected.
+func GetDesiredCiliumNetworkPolicy(objectMeta metav1.ObjectMeta, scope *state.Scope) *unstructured.Unstructured {
+	cfg := config.Get()
+	if !scope.TokenXConfig.Enabled || cfg.EgressBackend != config.EgressBackendCilium {
+		return nil
+	}
+
+	tokenxRule := map[string]interface{}{
+		"toEndpoints": []interface{}{
+			cilium.GetEndpointSelector(cfg.GetTokenxPodSelectorLabels(), cfg.GetTokenxNamespaceSelectorLabels()),
+		},
+	}
+	if toPorts := cilium.GetToPorts("TCP", cfg.TokenxPorts...); toPorts != nil {
+		tokenxRule["toPorts"] = toPorts
+	}
+	egress := []interface{}{tokenxRule}
+	if cfg.EgressDnsEnabled {
+		egress = append(egress, cilium.GetDnsEgressRule())
+	}
+
+	ciliumNetworkPolicy := &unstructured.Unstructured{}
+	ciliumNetworkPolicy.SetGroupVersionKind(cilium.CiliumNetworkPolicyGVK)
+	ciliumNetworkPolicy.SetName(objectMeta.Name)
+	ciliumNetworkPolicy.SetNamespace(objectMeta.Namespace)
+	ciliumNetworkPolicy.Object["spec"] = map[string]interface{}{
+		"endpointSelector": cilium.GetEndpointSelector(
+			map[string]string{cfg.EgressPodSelectorLabel: scope.SecurityConfig.Spec.ApplicationRef},
+			nil,
+		),
+		"egress": egress,
+	}
+	return ciliumNetworkPolicy
+}
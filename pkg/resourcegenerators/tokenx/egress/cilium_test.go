@@ -0,0 +1,51 @@
+package egress
+
+import (
+	"testing"
+
+	"github.com/kartverket/accesserator/pkg/resourcegenerators/cilium"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDesiredCiliumNetworkPolicy(t *testing.T) {
+	loadConfig(t, map[string]string{
+		"ACCESSERATOR_EGRESS_BACKEND":     "cilium",
+		"ACCESSERATOR_TOKENX_PORTS":       "7456",
+		"ACCESSERATOR_EGRESS_DNS_ENABLED": "true",
+	})
+
+	ciliumNetworkPolicy := GetDesiredCiliumNetworkPolicy(metav1.ObjectMeta{Name: "egress", Namespace: "ns"}, getScope())
+	require.NotNil(t, ciliumNetworkPolicy)
+	assert.Equal(t, cilium.CiliumNetworkPolicyGVK, ciliumNetworkPolicy.GroupVersionKind())
+	assert.Equal(t, "ns", ciliumNetworkPolicy.GetNamespace())
+	assert.Equal(t, map[string]interface{}{
+		"endpointSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"app": "app"},
+		},
+		"egress": []interface{}{
+			map[string]interface{}{
+				"toEndpoints": []interface{}{
+					map[string]interface{}{
+						"matchLabels": map[string]interface{}{
+							"app": "tokendings",
+							"k8s:io.cilium.k8s.namespace.labels.kubernetes.io/metadata.name": "obo",
+						},
+					},
+				},
+				"toPorts": []interface{}{
+					map[string]interface{}{
+						"ports": []interface{}{map[string]interface{}{"port": "7456", "protocol": "TCP"}},
+					},
+				},
+			},
+			cilium.GetDnsEgressRule(),
+		},
+	}, ciliumNetworkPolicy.Object["spec"])
+}
+
+func TestGetDesiredCiliumNetworkPolicy_OtherBackend(t *testing.T) {
+	loadConfig(t, nil)
+	assert.Nil(t, GetDesiredCiliumNetworkPolicy(metav1.ObjectMeta{Name: "egress"}, getScope()))
+}
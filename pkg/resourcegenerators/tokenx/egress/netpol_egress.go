@@ -3,12 +3,17 @@ package egress
 import (
 	"github.com/kartverket/accesserator/internal/state"
 	"github.com/kartverket/accesserator/pkg/config"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func GetDesired(objectMeta metav1.ObjectMeta, scope state.Scope) *v1.NetworkPolicy {
-	if !scope.TokenXConfig.Enabled {
+// GetDesired returns a NetworkPolicy allowing the application to reach Tokendings when the networkpolicy egress
+// backend is selected.
+func GetDesired(objectMeta metav1.ObjectMeta, scope *state.Scope) *v1.NetworkPolicy {
+	cfg := config.Get()
+	if !scope.TokenXConfig.Enabled || cfg.EgressBackend != config.EgressBackendNetworkPolicy {
 		return nil
 	}
 
@@ -16,38 +21,63 @@ func GetDesired(objectMeta metav1.ObjectMeta, scope state.Scope) *v1.NetworkPoli
 	// fromApp is the application referenced in SecurityConfig
 	fromApp := scope.SecurityConfig.Spec.ApplicationRef
 
-	toNamespace := config.Get().TokenxNamespace
-	toApp := config.Get().TokenxName
+	egress := []v1.NetworkPolicyEgressRule{
+		{
+			To: []v1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: cfg.GetTokenxNamespaceSelectorLabels(),
+					},
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: cfg.GetTokenxPodSelectorLabels(),
+					},
+				},
+			},
+			Ports: getPorts(corev1.ProtocolTCP, cfg.TokenxPorts...),
+		},
+	}
+	if cfg.EgressDnsEnabled {
+		egress = append(egress, v1.NetworkPolicyEgressRule{
+			To: []v1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: cfg.GetDnsNamespaceSelectorLabels(),
+					},
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: cfg.DnsPodSelectorLabels,
+					},
+				},
+			},
+			Ports: append(getPorts(corev1.ProtocolUDP, cfg.DnsPort), getPorts(corev1.ProtocolTCP, cfg.DnsPort)...),
+		})
+	}
 
 	return &v1.NetworkPolicy{
 		ObjectMeta: objectMeta,
 		Spec: v1.NetworkPolicySpec{
 			PodSelector: metav1.LabelSelector{
 				MatchLabels: map[string]string{
-					"app": fromApp,
+					cfg.EgressPodSelectorLabel: fromApp,
 				},
 			},
 			PolicyTypes: []v1.PolicyType{
 				v1.PolicyTypeEgress,
 			},
-			Egress: []v1.NetworkPolicyEgressRule{
-				{
-					To: []v1.NetworkPolicyPeer{
-						{
-							NamespaceSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{
-									"kubernetes.io/metadata.name": toNamespace,
-								},
-							},
-							PodSelector: &metav1.LabelSelector{
-								MatchLabels: map[string]string{
-									"app": toApp,
-								},
-							},
-						},
-					},
-				},
-			},
+			Egress: egress,
 		},
 	}
 }
+
+func getPorts(protocol corev1.Protocol, ports ...int32) []v1.NetworkPolicyPort {
+	if len(ports) == 0 {
+		return nil
+	}
+	networkPolicyPorts := make([]v1.NetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		networkPolicyPorts = append(networkPolicyPorts, v1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: port},
+		})
+	}
+	return networkPolicyPorts
+}
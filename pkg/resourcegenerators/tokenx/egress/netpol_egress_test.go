@@ -0,0 +1,82 @@
+package egress
+
+import (
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/internal/state"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func getScope() *state.Scope {
+	return &state.Scope{
+		SecurityConfig: v1alpha.SecurityConfig{Spec: v1alpha.SecurityConfigSpec{ApplicationRef: "app"}},
+		TokenXConfig:   state.TokenXConfig{Enabled: true},
+	}
+}
+
+func loadConfig(t *testing.T, env map[string]string) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	require.NoError(t, config.Load())
+}
+
+func TestGetDesired_Defaults(t *testing.T) {
+	loadConfig(t, nil)
+
+	networkPolicy := GetDesired(metav1.ObjectMeta{Name: "egress"}, getScope())
+	require.NotNil(t, networkPolicy)
+	assert.Equal(t, map[string]string{"app": "app"}, networkPolicy.Spec.PodSelector.MatchLabels)
+	require.Len(t, networkPolicy.Spec.Egress, 1)
+	assert.Equal(t, []v1.NetworkPolicyPeer{{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "obo"}},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "tokendings"}},
+	}}, networkPolicy.Spec.Egress[0].To)
+	assert.Nil(t, networkPolicy.Spec.Egress[0].Ports)
+}
+
+func TestGetDesired_PortsAndDns(t *testing.T) {
+	loadConfig(t, map[string]string{
+		"ACCESSERATOR_EGRESS_POD_SELECTOR_LABEL": "app.kubernetes.io/name",
+		"ACCESSERATOR_TOKENX_PORTS":              "7456",
+		"ACCESSERATOR_EGRESS_DNS_ENABLED":        "true",
+	})
+
+	networkPolicy := GetDesired(metav1.ObjectMeta{Name: "egress"}, getScope())
+	require.NotNil(t, networkPolicy)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/name": "app"}, networkPolicy.Spec.PodSelector.MatchLabels)
+	require.Len(t, networkPolicy.Spec.Egress, 2)
+
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	assert.Equal(t, []v1.NetworkPolicyPort{
+		{Protocol: &tcp, Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 7456}},
+	}, networkPolicy.Spec.Egress[0].Ports)
+	assert.Equal(t, []v1.NetworkPolicyPeer{{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "kube-system"}},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+	}}, networkPolicy.Spec.Egress[1].To)
+	assert.Equal(t, []v1.NetworkPolicyPort{
+		{Protocol: &udp, Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 53}},
+		{Protocol: &tcp, Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 53}},
+	}, networkPolicy.Spec.Egress[1].Ports)
+}
+
+func TestGetDesired_TokenXDisabled(t *testing.T) {
+	loadConfig(t, nil)
+	assert.Nil(t, GetDesired(metav1.ObjectMeta{Name: "egress"}, &state.Scope{}))
+}
+
+func TestGetDesired_OtherBackend(t *testing.T) {
+	loadConfig(t, map[string]string{"ACCESSERATOR_EGRESS_BACKEND": "cilium"})
+	assert.Nil(t, GetDesired(metav1.ObjectMeta{Name: "egress"}, getScope()))
+}
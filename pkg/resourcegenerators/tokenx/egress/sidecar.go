@@ -0,0 +1,10 @@
+package egress
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SidecarGVK is the GroupVersionKind of the Istio Sidecar. Earlier versions of the istio egress backend generated a
+// Sidecar per application, which replaced the whole egress configuration of the workload. The backend now uses an
+// AuthorizationPolicy on Tokendings instead, and Sidecars left behind are only deleted.
+var SidecarGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Sidecar"}
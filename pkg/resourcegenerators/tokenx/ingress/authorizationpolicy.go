@@ -0,0 +1,74 @@
+package ingress
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AuthorizationPolicyGVK is the GroupVersionKind of the Istio AuthorizationPolicy. Istio types are handled as
+// unstructured objects to avoid depending on the Istio API.
+var AuthorizationPolicyGVK = schema.GroupVersionKind{Group: "security.istio.io", Version: "v1", Kind: "AuthorizationPolicy"}
+
+// GetDesiredAuthorizationPolicy returns the AuthorizationPolicy in the Tokendings namespace allowing the applications
+// of all SecurityConfigs with TokenX enabled to reach Tokendings when the istio egress backend is selected, or nil if
+// there are none. The applications are identified by the mTLS principal of their service account, which Skiperator
+// names after the application.
+func GetDesiredAuthorizationPolicy(objectMeta metav1.ObjectMeta, securityConfigs []v1alpha.SecurityConfig) *unstructured.Unstructured {
+	cfg := config.Get()
+	if cfg.EgressBackend != config.EgressBackendIstio {
+		return nil
+	}
+	namespaces, applicationsByNamespace := getTokenxApplications(securityConfigs)
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	var principals []interface{}
+	for _, namespace := range namespaces {
+		for _, application := range applicationsByNamespace[namespace] {
+			principals = append(principals, fmt.Sprintf("%s/ns/%s/sa/%s", cfg.IstioTrustDomain, namespace, application))
+		}
+	}
+	rule := map[string]interface{}{
+		"from": []interface{}{
+			map[string]interface{}{"source": map[string]interface{}{"principals": principals}},
+		},
+	}
+	if len(cfg.TokenxPorts) > 0 {
+		ports := make([]interface{}, 0, len(cfg.TokenxPorts))
+		for _, port := range cfg.TokenxPorts {
+			ports = append(ports, strconv.Itoa(int(port)))
+		}
+		rule["to"] = []interface{}{
+			map[string]interface{}{"operation": map[string]interface{}{"ports": ports}},
+		}
+	}
+
+	selectorLabels := map[string]interface{}{}
+	for key, value := range cfg.GetTokenxPodSelectorLabels() {
+		selectorLabels[key] = value
+	}
+
+	authorizationPolicy := &unstructured.Unstructured{}
+	authorizationPolicy.SetGroupVersionKind(AuthorizationPolicyGVK)
+	authorizationPolicy.SetName(objectMeta.Name)
+	authorizationPolicy.SetNamespace(objectMeta.Namespace)
+	// The AuthorizationPolicy cannot be owned by the SecurityConfigs across namespaces, so the label is used to only
+	// update or delete an AuthorizationPolicy created by Accesserator.
+	authorizationPolicy.SetLabels(map[string]string{
+		utilities.ManagedByLabelKey: utilities.ManagedByLabelValue,
+	})
+	authorizationPolicy.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": selectorLabels},
+		"action":   "ALLOW",
+		"rules":    []interface{}{rule},
+	}
+	return authorizationPolicy
+}
@@ -0,0 +1,66 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDesiredAuthorizationPolicy(t *testing.T) {
+	t.Setenv("ACCESSERATOR_EGRESS_BACKEND", "istio")
+	loadConfig(t)
+
+	authorizationPolicy := GetDesiredAuthorizationPolicy(
+		metav1.ObjectMeta{Name: "tokenx-ingress", Namespace: "obo"},
+		[]v1alpha.SecurityConfig{
+			getSecurityConfig("team-b", "app-c", true),
+			getSecurityConfig("team-a", "app-b", true),
+			getSecurityConfig("team-a", "app-a", true),
+			getSecurityConfig("team-a", "disabled", false),
+		},
+	)
+	require.NotNil(t, authorizationPolicy)
+	assert.Equal(t, AuthorizationPolicyGVK, authorizationPolicy.GroupVersionKind())
+	assert.Equal(t, "obo", authorizationPolicy.GetNamespace())
+	assert.Equal(t, map[string]string{"app.kubernetes.io/managed-by": "accesserator"}, authorizationPolicy.GetLabels())
+	assert.Equal(t, map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "tokendings"}},
+		"action":   "ALLOW",
+		"rules": []interface{}{
+			map[string]interface{}{
+				"from": []interface{}{
+					map[string]interface{}{"source": map[string]interface{}{"principals": []interface{}{
+						"cluster.local/ns/team-a/sa/app-a",
+						"cluster.local/ns/team-a/sa/app-b",
+						"cluster.local/ns/team-b/sa/app-c",
+					}}},
+				},
+				"to": []interface{}{
+					map[string]interface{}{"operation": map[string]interface{}{"ports": []interface{}{"7456"}}},
+				},
+			},
+		},
+	}, authorizationPolicy.Object["spec"])
+}
+
+func TestGetDesiredAuthorizationPolicy_OtherBackend(t *testing.T) {
+	loadConfig(t)
+
+	assert.Nil(t, GetDesiredAuthorizationPolicy(
+		metav1.ObjectMeta{Name: "tokenx-ingress", Namespace: "obo"},
+		[]v1alpha.SecurityConfig{getSecurityConfig("team-a", "app-a", true)},
+	))
+}
+
+func TestGetDesiredAuthorizationPolicy_NoTokenxApplications(t *testing.T) {
+	t.Setenv("ACCESSERATOR_EGRESS_BACKEND", "istio")
+	loadConfig(t)
+
+	assert.Nil(t, GetDesiredAuthorizationPolicy(
+		metav1.ObjectMeta{Name: "tokenx-ingress", Namespace: "obo"},
+		[]v1alpha.SecurityConfig{getSecurityConfig("team-a", "app-a", false)},
+	))
+}
@@ -0,0 +1,114 @@
+package ingress
+
+import (
+	"sort"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// GetDesired returns the NetworkPolicy in the Tokendings namespace allowing ingress to Tokendings from the
+// applications of all SecurityConfigs with TokenX enabled, or nil if there are none.
+func GetDesired(objectMeta metav1.ObjectMeta, securityConfigs []v1alpha.SecurityConfig) *v1.NetworkPolicy {
+	cfg := config.Get()
+
+	namespaces, applicationsByNamespace := getTokenxApplications(securityConfigs)
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	from := make([]v1.NetworkPolicyPeer, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		applications := applicationsByNamespace[namespace]
+		from = append(from, v1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					cfg.TokenxIngressNamespaceSelectorLabel: namespace,
+				},
+			},
+			PodSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      cfg.EgressPodSelectorLabel,
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   applications,
+					},
+				},
+			},
+		})
+	}
+
+	// The NetworkPolicy cannot be owned by the SecurityConfigs across namespaces, so the label is used to only
+	// update or delete a NetworkPolicy created by Accesserator.
+	objectMeta.Labels = map[string]string{
+		utilities.ManagedByLabelKey: utilities.ManagedByLabelValue,
+	}
+	return &v1.NetworkPolicy{
+		ObjectMeta: objectMeta,
+		Spec: v1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: cfg.GetTokenxPodSelectorLabels(),
+			},
+			PolicyTypes: []v1.PolicyType{
+				v1.PolicyTypeIngress,
+			},
+			Ingress: []v1.NetworkPolicyIngressRule{
+				{
+					From:  from,
+					Ports: getPorts(cfg.TokenxPorts...),
+				},
+			},
+		},
+	}
+}
+
+func getPorts(ports ...int32) []v1.NetworkPolicyPort {
+	if len(ports) == 0 {
+		return nil
+	}
+	protocol := corev1.ProtocolTCP
+	networkPolicyPorts := make([]v1.NetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		networkPolicyPorts = append(networkPolicyPorts, v1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: port},
+		})
+	}
+	return networkPolicyPorts
+}
+
+// getTokenxApplications returns the sorted namespaces of the SecurityConfigs with TokenX enabled that are not being
+// deleted, and the sorted names of their applications by namespace.
+func getTokenxApplications(securityConfigs []v1alpha.SecurityConfig) ([]string, map[string][]string) {
+	applicationSets := map[string]map[string]bool{}
+	for _, securityConfig := range securityConfigs {
+		if !securityConfig.DeletionTimestamp.IsZero() ||
+			securityConfig.Spec.Tokenx == nil ||
+			!securityConfig.Spec.Tokenx.Enabled {
+			continue
+		}
+		if applicationSets[securityConfig.Namespace] == nil {
+			applicationSets[securityConfig.Namespace] = map[string]bool{}
+		}
+		applicationSets[securityConfig.Namespace][securityConfig.Spec.ApplicationRef] = true
+	}
+
+	namespaces := make([]string, 0, len(applicationSets))
+	applicationsByNamespace := make(map[string][]string, len(applicationSets))
+	for namespace, applicationSet := range applicationSets {
+		namespaces = append(namespaces, namespace)
+		applications := make([]string, 0, len(applicationSet))
+		for application := range applicationSet {
+			applications = append(applications, application)
+		}
+		sort.Strings(applications)
+		applicationsByNamespace[namespace] = applications
+	}
+	sort.Strings(namespaces)
+	return namespaces, applicationsByNamespace
+}
@@ -0,0 +1,93 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func loadConfig(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	t.Setenv("ACCESSERATOR_TOKENX_PORTS", "7456")
+	require.NoError(t, config.Load())
+}
+
+func getSecurityConfig(namespace, applicationRef string, tokenxEnabled bool) v1alpha.SecurityConfig {
+	return v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: applicationRef, Namespace: namespace},
+		Spec: v1alpha.SecurityConfigSpec{
+			ApplicationRef: applicationRef,
+			Tokenx:         &v1alpha.TokenXSpec{Enabled: tokenxEnabled},
+		},
+	}
+}
+
+func TestGetDesired(t *testing.T) {
+	loadConfig(t)
+	deleted := getSecurityConfig("team-a", "deleted", true)
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	networkPolicy := GetDesired(
+		metav1.ObjectMeta{Name: "tokenx-ingress", Namespace: "obo"},
+		[]v1alpha.SecurityConfig{
+			getSecurityConfig("team-b", "app-c", true),
+			getSecurityConfig("team-a", "app-b", true),
+			getSecurityConfig("team-a", "app-a", true),
+			getSecurityConfig("team-a", "disabled", false),
+			deleted,
+		},
+	)
+	require.NotNil(t, networkPolicy)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/managed-by": "accesserator"}, networkPolicy.Labels)
+	assert.Equal(t, map[string]string{"app": "tokendings"}, networkPolicy.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []v1.PolicyType{v1.PolicyTypeIngress}, networkPolicy.Spec.PolicyTypes)
+	require.Len(t, networkPolicy.Spec.Ingress, 1)
+	assert.Equal(t, []v1.NetworkPolicyPeer{
+		{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "team-a"}},
+			PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"app-a", "app-b"}},
+			}},
+		},
+		{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "team-b"}},
+			PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"app-c"}},
+			}},
+		},
+	}, networkPolicy.Spec.Ingress[0].From)
+	require.Len(t, networkPolicy.Spec.Ingress[0].Ports, 1)
+	assert.Equal(t, int32(7456), networkPolicy.Spec.Ingress[0].Ports[0].Port.IntVal)
+}
+
+func TestGetDesired_NamespaceSelectorLabel(t *testing.T) {
+	t.Setenv("ACCESSERATOR_TOKENX_INGRESS_NAMESPACE_SELECTOR_LABEL", "name")
+	loadConfig(t)
+
+	networkPolicy := GetDesired(
+		metav1.ObjectMeta{Name: "tokenx-ingress", Namespace: "obo"},
+		[]v1alpha.SecurityConfig{getSecurityConfig("team-a", "app-a", true)},
+	)
+	require.NotNil(t, networkPolicy)
+	require.Len(t, networkPolicy.Spec.Ingress, 1)
+	require.Len(t, networkPolicy.Spec.Ingress[0].From, 1)
+	assert.Equal(t, map[string]string{"name": "team-a"}, networkPolicy.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels)
+}
+
+func TestGetDesired_NoTokenxApplications(t *testing.T) {
+	loadConfig(t)
+
+	assert.Nil(t, GetDesired(metav1.ObjectMeta{Name: "tokenx-ingress"}, nil))
+	assert.Nil(t, GetDesired(
+		metav1.ObjectMeta{Name: "tokenx-ingress"},
+		[]v1alpha.SecurityConfig{getSecurityConfig("team-a", "app-a", false)},
+	))
+}
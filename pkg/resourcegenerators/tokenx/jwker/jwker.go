@@ -3,20 +3,20 @@ package jwker
 import (
 	"github.com/kartverket/accesserator/internal/state"
 	"github.com/kartverket/accesserator/pkg/config"
-	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/accesserator/pkg/naming"
 	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
 	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func GetDesired(objectMeta v1.ObjectMeta, scope state.Scope) *naisiov1.Jwker {
+func GetDesired(objectMeta v1.ObjectMeta, scope *state.Scope) *naisiov1.Jwker {
 	if !scope.TokenXConfig.Enabled {
 		return nil
 	}
 	return &naisiov1.Jwker{
 		ObjectMeta: objectMeta,
 		Spec: naisiov1.JwkerSpec{
-			SecretName:   utilities.GetJwkerSecretName(objectMeta.Name),
+			SecretName:   naming.GetJwkerSecretName(objectMeta.Name),
 			AccessPolicy: getNaisIoV1AccessPolicy(scope.TokenXConfig.AccessPolicy, scope.SecurityConfig.Namespace),
 		},
 	}
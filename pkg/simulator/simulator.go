@@ -0,0 +1,288 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Check is a single step in the chain of reasons deciding whether a token exchange succeeds.
+type Check struct {
+	Subject string
+	Passed  bool
+	Message string
+}
+
+// Result is the outcome of simulating a token exchange where From is the caller and To is the intended audience.
+type Result struct {
+	From    accesspolicy.Workload
+	To      accesspolicy.Workload
+	Checks  []Check
+	Allowed bool
+}
+
+func (r *Result) add(subject string, passed bool, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Passed: passed, Message: fmt.Sprintf(format, args...)})
+	if !passed {
+		r.Allowed = false
+	}
+}
+
+// workloadState holds the objects of a workload that are relevant for token exchange.
+type workloadState struct {
+	application    *v1alpha1.Application
+	namespace      *corev1.Namespace
+	securityConfig *v1alpha.SecurityConfig
+	jwker          *naisiov1.Jwker
+	networkPolicy  *networkingv1.NetworkPolicy
+	// lookupErr is set when the securityConfig could not be chosen, e.g. because there are none or several.
+	lookupErr string
+}
+
+// Simulate evaluates whether a TokenX token exchange from the caller to the target would succeed given the current
+// SecurityConfigs, Applications, Jwkers and NetworkPolicies in the cluster.
+func Simulate(ctx context.Context, k8sClient client.Client, from, to accesspolicy.Workload) (*Result, error) {
+	caller, err := getWorkloadState(ctx, k8sClient, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := getWorkloadState(ctx, k8sClient, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{From: from, To: to, Allowed: true}
+
+	// The caller needs the Texas sidecar with TokenX credentials, and network access to Tokendings.
+	checkLabels(result, "caller", from, caller)
+	checkWebhookNamespaceLabel(result, from, caller)
+	checkSecurityConfig(result, "caller", from, caller)
+	checkJwker(result, "caller", from, caller)
+	checkEgressNetworkPolicy(result, from, caller)
+
+	// The target must be registered as an audience in Tokendings.
+	checkLabels(result, "target", to, target)
+	checkSecurityConfig(result, "target", to, target)
+	checkJwker(result, "target", to, target)
+
+	checkRules(result, caller, target)
+	return result, nil
+}
+
+// Print writes the chain of reasons in a human-readable form.
+func (r *Result) Print(w io.Writer) error {
+	verdict := "ALLOWED"
+	if !r.Allowed {
+		verdict = "DENIED"
+	}
+	if _, err := fmt.Fprintf(w, "Token exchange from %s to %s: %s\n", r.From, r.To, verdict); err != nil {
+		return err
+	}
+	for _, check := range r.Checks {
+		mark := "✔"
+		if !check.Passed {
+			mark = "✘"
+		}
+		if _, err := fmt.Fprintf(w, "  %s [%s] %s\n", mark, check.Subject, check.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getWorkloadState(ctx context.Context, k8sClient client.Client, w accesspolicy.Workload) (*workloadState, error) {
+	s := &workloadState{}
+
+	application := &v1alpha1.Application{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: w.Name, Namespace: w.Namespace}, application); err != nil {
+		return nil, err
+	} else if application.Name != "" {
+		s.application = application
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: w.Namespace}, namespace); err != nil {
+		return nil, err
+	} else if namespace.Name != "" {
+		s.namespace = namespace
+	}
+
+	var securityConfigList v1alpha.SecurityConfigList
+	if err := k8sClient.List(ctx, &securityConfigList, client.InNamespace(w.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list SecurityConfig resources in namespace %s: %w", w.Namespace, err)
+	}
+	var securityConfigs []v1alpha.SecurityConfig
+	for _, securityConfig := range securityConfigList.Items {
+		if securityConfig.Spec.ApplicationRef == w.Name {
+			securityConfigs = append(securityConfigs, securityConfig)
+		}
+	}
+	switch len(securityConfigs) {
+	case 0:
+		s.lookupErr = fmt.Sprintf("no SecurityConfig references application %s", w)
+		return s, nil
+	case 1:
+		s.securityConfig = &securityConfigs[0]
+	default:
+		s.lookupErr = fmt.Sprintf("%d SecurityConfigs reference application %s, expected exactly one", len(securityConfigs), w)
+		return s, nil
+	}
+
+	jwker := &naisiov1.Jwker{}
+	jwkerKey := types.NamespacedName{Name: naming.GetJwkerName(w.Name), Namespace: w.Namespace}
+	if err := getOptional(ctx, k8sClient, jwkerKey, jwker); err != nil {
+		return nil, err
+	} else if jwker.Name != "" {
+		s.jwker = jwker
+	}
+
+	var networkPolicyList networkingv1.NetworkPolicyList
+	if err := k8sClient.List(ctx, &networkPolicyList, client.InNamespace(w.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list NetworkPolicy resources in namespace %s: %w", w.Namespace, err)
+	}
+	for i, networkPolicy := range networkPolicyList.Items {
+		if owner := metav1.GetControllerOf(&networkPolicy); owner != nil && owner.UID == s.securityConfig.UID {
+			s.networkPolicy = &networkPolicyList.Items[i]
+			break
+		}
+	}
+	return s, nil
+}
+
+func getOptional(ctx context.Context, k8sClient client.Client, key types.NamespacedName, obj client.Object) error {
+	if err := k8sClient.Get(ctx, key, obj); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to fetch %T %s: %w", obj, key, err)
+	}
+	return nil
+}
+
+func checkLabels(result *Result, role string, w accesspolicy.Workload, s *workloadState) {
+	if s.application == nil {
+		result.add("labels", false, "%s Application %s does not exist", role, w)
+		return
+	}
+	if s.application.Labels[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue {
+		result.add(
+			"labels", false, "%s Application %s is not labelled %s=%s",
+			role, w, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+		)
+		return
+	}
+	result.add(
+		"labels", true, "%s Application %s is labelled %s=%s",
+		role, w, utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+	)
+}
+
+func checkWebhookNamespaceLabel(result *Result, w accesspolicy.Workload, s *workloadState) {
+	if s.namespace == nil {
+		result.add("webhook", false, "namespace %s does not exist", w.Namespace)
+		return
+	}
+	if s.namespace.Labels[utilities.WebhookNamespaceLabelName] != utilities.WebhookNamespaceLabelValue {
+		result.add(
+			"webhook", false, "namespace %s is not labelled %s=%s, so Texas is not injected into the caller pods",
+			w.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+		)
+		return
+	}
+	result.add(
+		"webhook", true, "namespace %s is labelled %s=%s",
+		w.Namespace, utilities.WebhookNamespaceLabelName, utilities.WebhookNamespaceLabelValue,
+	)
+}
+
+func checkSecurityConfig(result *Result, role string, w accesspolicy.Workload, s *workloadState) {
+	if s.securityConfig == nil {
+		result.add("securityconfig", false, "%s: %s", role, s.lookupErr)
+		return
+	}
+	if s.securityConfig.Spec.Tokenx == nil || !s.securityConfig.Spec.Tokenx.Enabled {
+		result.add("securityconfig", false, "%s SecurityConfig %s/%s does not enable TokenX", role, w.Namespace, s.securityConfig.Name)
+		return
+	}
+	result.add("securityconfig", true, "%s SecurityConfig %s/%s enables TokenX", role, w.Namespace, s.securityConfig.Name)
+}
+
+func checkJwker(result *Result, role string, w accesspolicy.Workload, s *workloadState) {
+	jwkerName := naming.GetJwkerName(w.Name)
+	if s.jwker == nil {
+		result.add("jwker", false, "%s Jwker %s/%s does not exist", role, w.Namespace, jwkerName)
+		return
+	}
+	if s.jwker.Status.SynchronizationState != utilities.JwkerSynchronizationStateReady {
+		result.add(
+			"jwker", false, "%s Jwker %s/%s is in synchronization state %q, expected %q",
+			role, w.Namespace, jwkerName, s.jwker.Status.SynchronizationState, utilities.JwkerSynchronizationStateReady,
+		)
+		return
+	}
+	result.add("jwker", true, "%s Jwker %s/%s is %s", role, w.Namespace, jwkerName, utilities.JwkerSynchronizationStateReady)
+}
+
+func checkEgressNetworkPolicy(result *Result, w accesspolicy.Workload, s *workloadState) {
+	if s.networkPolicy == nil {
+		result.add("netpol", false, "no egress NetworkPolicy to Tokendings is owned by the SecurityConfig of %s", w)
+		return
+	}
+	result.add("netpol", true, "egress NetworkPolicy %s/%s allows the caller to reach Tokendings", w.Namespace, s.networkPolicy.Name)
+}
+
+func checkRules(result *Result, caller, target *workloadState) {
+	from, to := result.From, result.To
+
+	callerRules := getApplicationAccessPolicyRules(from, caller)
+	if hasRule(callerRules, accesspolicy.DirectionOutbound, to) {
+		result.add("outbound", true, "%s has an outbound rule to %s", from, to)
+	} else {
+		result.add("outbound", false, "%s has no outbound rule to %s", from, to)
+	}
+
+	// Tokendings enforces the inbound rules registered through the Jwker of the target. Fall back to the
+	// Application when the Jwker does not exist yet.
+	targetRules := getApplicationAccessPolicyRules(to, target)
+	source := "Application"
+	if target.jwker != nil {
+		targetRules = accesspolicy.GetJwkerRules(to, target.jwker.Spec.AccessPolicy)
+		source = "Jwker"
+	}
+	if hasRule(targetRules, accesspolicy.DirectionInbound, from) {
+		result.add("inbound", true, "%s of %s has an inbound rule from %s", source, to, from)
+	} else {
+		result.add("inbound", false, "%s of %s has no inbound rule from %s", source, to, from)
+	}
+}
+
+func getApplicationAccessPolicyRules(w accesspolicy.Workload, s *workloadState) []accesspolicy.Rule {
+	if s.application == nil {
+		return nil
+	}
+	accessPolicy := s.application.Spec.AccessPolicy
+	if s.securityConfig != nil && s.securityConfig.Spec.Tokenx != nil {
+		accessPolicy = accesspolicy.MergeAccessPolicy(accessPolicy, s.securityConfig.Spec.Tokenx.AccessPolicy, time.Now())
+	}
+	return accesspolicy.GetRules(w, accessPolicy)
+}
+
+func hasRule(rules []accesspolicy.Rule, direction accesspolicy.Direction, peer accesspolicy.Workload) bool {
+	for _, rule := range rules {
+		if rule.Direction == direction && rule.Peer == peer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/accesspolicy"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	"github.com/kartverket/skiperator/api/v1alpha1/podtypes"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const namespace = "ns"
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, networkingv1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func getObjects(name string, accessPolicy *podtypes.AccessPolicy, inbound naisiov1.AccessPolicyInboundRules) []client.Object {
+	uid := types.UID(name + "-uid")
+	return []client.Object{
+		&v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue},
+			},
+			Spec: v1alpha1.ApplicationSpec{AccessPolicy: accessPolicy},
+		},
+		&v1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: uid},
+			Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: name, Tokenx: &v1alpha.TokenXSpec{Enabled: true}},
+		},
+		&naisiov1.Jwker{
+			ObjectMeta: metav1.ObjectMeta{Name: naming.GetJwkerName(name), Namespace: namespace},
+			Spec: naisiov1.JwkerSpec{AccessPolicy: &naisiov1.AccessPolicy{
+				Inbound: &naisiov1.AccessPolicyInbound{Rules: inbound},
+			}},
+			Status: naisiov1.JwkerStatus{SynchronizationState: utilities.JwkerSynchronizationStateReady},
+		},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-egress",
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha.GroupVersion.String(),
+					Kind:       "SecurityConfig",
+					Name:       name,
+					UID:        uid,
+					Controller: utilities.Ptr(true),
+				}},
+			},
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	from := accesspolicy.Workload{Name: "caller", Namespace: namespace}
+	to := accesspolicy.Workload{Name: "target", Namespace: namespace}
+
+	objects := []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   namespace,
+		Labels: map[string]string{utilities.WebhookNamespaceLabelName: utilities.WebhookNamespaceLabelValue},
+	}}}
+	objects = append(objects, getObjects(
+		"caller",
+		&podtypes.AccessPolicy{Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{{Application: "target"}}}},
+		nil,
+	)...)
+	objects = append(objects, getObjects(
+		"target",
+		nil,
+		naisiov1.AccessPolicyInboundRules{{AccessPolicyRule: naisiov1.AccessPolicyRule{Application: "caller", Namespace: namespace}}},
+	)...)
+
+	result, err := Simulate(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), from, to)
+	require.NoError(t, err)
+	for _, check := range result.Checks {
+		assert.True(t, check.Passed, check.Message)
+	}
+	assert.True(t, result.Allowed)
+
+	var out bytes.Buffer
+	require.NoError(t, result.Print(&out))
+	assert.Contains(t, out.String(), "Token exchange from ns/caller to ns/target: ALLOWED")
+}
+
+func TestSimulate_MissingInboundRuleAndWebhookLabel(t *testing.T) {
+	from := accesspolicy.Workload{Name: "caller", Namespace: namespace}
+	to := accesspolicy.Workload{Name: "target", Namespace: namespace}
+
+	objects := []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}}
+	objects = append(objects, getObjects(
+		"caller",
+		&podtypes.AccessPolicy{Outbound: podtypes.OutboundPolicy{Rules: []podtypes.InternalRule{{Application: "target"}}}},
+		nil,
+	)...)
+	objects = append(objects, getObjects("target", nil, nil)...)
+
+	result, err := Simulate(context.Background(), utilities.GetMockKubernetesClient(getScheme(t), objects...), from, to)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	var failed []string
+	for _, check := range result.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Subject)
+		}
+	}
+	assert.Equal(t, []string{"webhook", "inbound"}, failed)
+}
+
+func TestSimulate_MissingTarget(t *testing.T) {
+	result, err := Simulate(
+		context.Background(),
+		utilities.GetMockKubernetesClient(getScheme(t)),
+		accesspolicy.Workload{Name: "caller", Namespace: namespace},
+		accesspolicy.Workload{Name: "target", Namespace: namespace},
+	)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, "caller Application ns/caller does not exist", result.Checks[0].Message)
+}
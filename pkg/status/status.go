@@ -0,0 +1,281 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	webhookv1 "github.com/kartverket/accesserator/internal/webhook/v1"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Health is the health of a node in the status tree.
+type Health string
+
+const (
+	HealthHealthy   Health = "Healthy"
+	HealthPending   Health = "Pending"
+	HealthUnhealthy Health = "Unhealthy"
+)
+
+// severity orders the healths from best to worst.
+var severity = map[Health]int{HealthHealthy: 0, HealthPending: 1, HealthUnhealthy: 2}
+
+// Node is an object in the status tree of a SecurityConfig.
+type Node struct {
+	Kind     string
+	Name     string
+	Health   Health
+	Message  string
+	Children []*Node
+}
+
+func (n *Node) add(kind, name string, health Health, format string, args ...any) *Node {
+	child := &Node{Kind: kind, Name: name, Health: health, Message: fmt.Sprintf(format, args...)}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// OverallHealth returns the worst health of the node and its descendants.
+func (n *Node) OverallHealth() Health {
+	health := n.Health
+	for _, child := range n.Children {
+		if childHealth := child.OverallHealth(); severity[childHealth] > severity[health] {
+			health = childHealth
+		}
+	}
+	return health
+}
+
+// Collect builds the status tree of the SecurityConfig: its Application and the pods of the application with their
+// Texas injection state, and, when TokenX is enabled, its Jwker with the Jwker secret, its NetworkPolicies and its
+// access grants.
+func Collect(ctx context.Context, k8sClient client.Client, key types.NamespacedName) (*Node, error) {
+	securityConfig := &v1alpha.SecurityConfig{}
+	if err := k8sClient.Get(ctx, key, securityConfig); err != nil {
+		return nil, fmt.Errorf("failed to fetch SecurityConfig %s: %w", key, err)
+	}
+
+	root := &Node{Kind: "SecurityConfig", Name: key.String(), Message: string(securityConfig.Status.Phase)}
+	switch securityConfig.Status.Phase {
+	case v1alpha.PhaseReady:
+		root.Health = HealthHealthy
+	case v1alpha.PhaseFailed, v1alpha.PhaseInvalid, v1alpha.PhaseOrphaned:
+		root.Health = HealthUnhealthy
+	default:
+		root.Health = HealthPending
+	}
+	if securityConfig.Status.Message != "" {
+		root.Message = fmt.Sprintf("%s: %s", root.Message, securityConfig.Status.Message)
+	}
+
+	tokenxEnabled := securityConfig.Spec.Tokenx != nil && securityConfig.Spec.Tokenx.Enabled
+	if err := addApplication(ctx, k8sClient, root, securityConfig, tokenxEnabled); err != nil {
+		return nil, err
+	}
+	if !tokenxEnabled {
+		return root, nil
+	}
+	if err := addJwker(ctx, k8sClient, root, securityConfig); err != nil {
+		return nil, err
+	}
+	if err := addNetworkPolicies(ctx, k8sClient, root, securityConfig); err != nil {
+		return nil, err
+	}
+	addAccessGrants(root, securityConfig, time.Now())
+	return root, nil
+}
+
+func addApplication(
+	ctx context.Context,
+	k8sClient client.Client,
+	root *Node,
+	securityConfig *v1alpha.SecurityConfig,
+	tokenxEnabled bool,
+) error {
+	appName := securityConfig.Spec.ApplicationRef
+	application := &v1alpha1.Application{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: appName, Namespace: securityConfig.Namespace}, application); err != nil {
+		return err
+	}
+	var node *Node
+	switch {
+	case application.Name == "":
+		node = root.add("Application", appName, HealthUnhealthy, "not found")
+	case application.Labels[utilities.SecurityEnabledLabelName] != utilities.SecurityEnabledLabelValue:
+		node = root.add(
+			"Application", appName, HealthUnhealthy, "missing label %s=%s",
+			utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+		)
+	default:
+		node = root.add("Application", appName, HealthHealthy, "labeled %s=%s",
+			utilities.SecurityEnabledLabelName, utilities.SecurityEnabledLabelValue,
+		)
+	}
+
+	var podList corev1.PodList
+	if err := k8sClient.List(
+		ctx,
+		&podList,
+		client.InNamespace(securityConfig.Namespace),
+		client.MatchingLabels{utilities.SkiperatorApplicationRefLabel: appName},
+	); err != nil {
+		return fmt.Errorf("failed to list pods of application %s/%s: %w", securityConfig.Namespace, appName, err)
+	}
+	for _, pod := range podList.Items {
+		injected := hasTexas(pod)
+		switch {
+		case !tokenxEnabled:
+			node.add("Pod", pod.Name, HealthHealthy, "%s, TokenX not enabled", pod.Status.Phase)
+		case !injected:
+			node.add("Pod", pod.Name, HealthUnhealthy, "%s, Texas not injected", pod.Status.Phase)
+		case pod.Status.Phase != corev1.PodRunning:
+			node.add("Pod", pod.Name, HealthPending, "%s, Texas injected", pod.Status.Phase)
+		default:
+			node.add("Pod", pod.Name, HealthHealthy, "%s, Texas injected", pod.Status.Phase)
+		}
+	}
+	return nil
+}
+
+func addJwker(ctx context.Context, k8sClient client.Client, root *Node, securityConfig *v1alpha.SecurityConfig) error {
+	jwkerName := naming.GetJwkerName(securityConfig.Spec.ApplicationRef)
+	jwker := &naisiov1.Jwker{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: jwkerName, Namespace: securityConfig.Namespace}, jwker); err != nil {
+		return err
+	}
+	if jwker.Name == "" {
+		root.add("Jwker", jwkerName, HealthUnhealthy, "not found")
+		return nil
+	}
+
+	state := jwker.Status.SynchronizationState
+	jwkerReady := state == utilities.JwkerSynchronizationStateReady
+	if state == "" {
+		state = "not synchronized yet"
+	}
+	jwkerHealth := HealthPending
+	if jwkerReady {
+		jwkerHealth = HealthHealthy
+	}
+	node := root.add("Jwker", jwkerName, jwkerHealth, "%s", state)
+
+	secretName := jwker.Spec.SecretName
+	if secretName == "" {
+		secretName = naming.GetJwkerSecretName(jwkerName)
+	}
+	secret := &corev1.Secret{}
+	if err := getOptional(ctx, k8sClient, types.NamespacedName{Name: secretName, Namespace: securityConfig.Namespace}, secret); err != nil {
+		return err
+	}
+	switch {
+	case secret.Name != "":
+		node.add("Secret", secretName, HealthHealthy, "exists")
+	case jwkerReady:
+		node.add("Secret", secretName, HealthUnhealthy, "not found")
+	default:
+		node.add("Secret", secretName, HealthPending, "waiting for the Jwker to be synchronized")
+	}
+	return nil
+}
+
+func addNetworkPolicies(ctx context.Context, k8sClient client.Client, root *Node, securityConfig *v1alpha.SecurityConfig) error {
+	var networkPolicyList networkingv1.NetworkPolicyList
+	if err := k8sClient.List(ctx, &networkPolicyList, client.InNamespace(securityConfig.Namespace)); err != nil {
+		return fmt.Errorf("failed to list NetworkPolicy resources in namespace %s: %w", securityConfig.Namespace, err)
+	}
+	found := false
+	for _, networkPolicy := range networkPolicyList.Items {
+		if owner := metav1.GetControllerOf(&networkPolicy); owner != nil && owner.UID == securityConfig.UID {
+			root.add("NetworkPolicy", networkPolicy.Name, HealthHealthy, "egress allowed")
+			found = true
+		}
+	}
+	if !found {
+		root.add("NetworkPolicy", "", HealthUnhealthy, "no egress NetworkPolicy owned by the SecurityConfig")
+	}
+	return nil
+}
+
+// addAccessGrants adds the access grants in the status of the SecurityConfig with their remaining lifetime, which is
+// computed here since the status only has their expiry.
+func addAccessGrants(root *Node, securityConfig *v1alpha.SecurityConfig, now time.Time) {
+	for _, grant := range securityConfig.Status.AccessGrants {
+		name := fmt.Sprintf("%s/%s", grant.Namespace, grant.Application)
+		remainingLifetime := grant.ExpiresAt.Sub(now).Round(time.Minute)
+		switch {
+		case remainingLifetime <= 0:
+			root.add("AccessGrant", name, HealthPending, "%s, expired, waiting for the SecurityConfig to be reconciled", grant.Direction)
+		case grant.ExpiringSoon:
+			root.add("AccessGrant", name, HealthHealthy, "%s, expires soon in %s", grant.Direction, remainingLifetime)
+		default:
+			root.add("AccessGrant", name, HealthHealthy, "%s, expires in %s", grant.Direction, remainingLifetime)
+		}
+	}
+}
+
+func hasTexas(pod corev1.Pod) bool {
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == webhookv1.TexasInitContainerName {
+			return true
+		}
+	}
+	return false
+}
+
+func getOptional(ctx context.Context, k8sClient client.Client, key types.NamespacedName, obj client.Object) error {
+	if err := k8sClient.Get(ctx, key, obj); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to fetch %T %s: %w", obj, key, err)
+	}
+	return nil
+}
+
+const (
+	colorReset  = "\033[0m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
+)
+
+var (
+	marks  = map[Health]string{HealthHealthy: "✔", HealthPending: "…", HealthUnhealthy: "✘"}
+	colors = map[Health]string{HealthHealthy: colorGreen, HealthPending: colorYellow, HealthUnhealthy: colorRed}
+)
+
+// Print writes the tree with a mark for the health of every node, colored when color is true.
+func (n *Node) Print(w io.Writer, color bool) error {
+	return n.print(w, color, "", "")
+}
+
+func (n *Node) print(w io.Writer, color bool, prefix, childPrefix string) error {
+	mark := marks[n.Health]
+	if color {
+		mark = colors[n.Health] + mark + colorReset
+	}
+	name := strings.TrimSpace(fmt.Sprintf("%s %s", n.Kind, n.Name))
+	if _, err := fmt.Fprintf(w, "%s%s %s: %s\n", prefix, mark, name, n.Message); err != nil {
+		return err
+	}
+	for i, child := range n.Children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		if err := child.print(w, color, childPrefix+branch, childPrefix+indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
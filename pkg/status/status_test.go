@@ -0,0 +1,167 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	webhookv1 "github.com/kartverket/accesserator/internal/webhook/v1"
+	"github.com/kartverket/accesserator/pkg/naming"
+	"github.com/kartverket/accesserator/pkg/utilities"
+	"github.com/kartverket/skiperator/api/v1alpha1"
+	naisiov1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const namespace = "ns"
+
+func getScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, networkingv1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	require.NoError(t, naisiov1.AddToScheme(scheme))
+	return scheme
+}
+
+func getObjects() []client.Object {
+	uid := types.UID("app-uid")
+	return []client.Object{
+		&v1alpha.SecurityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace, UID: uid},
+			Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: "app", Tokenx: &v1alpha.TokenXSpec{Enabled: true}},
+			Status:     v1alpha.SecurityConfigStatus{Phase: v1alpha.PhaseReady, Message: "SecurityConfig ready."},
+		},
+		&v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: namespace,
+				Labels:    map[string]string{utilities.SecurityEnabledLabelName: utilities.SecurityEnabledLabelValue},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-abc",
+				Namespace: namespace,
+				Labels:    map[string]string{utilities.SkiperatorApplicationRefLabel: "app"},
+			},
+			Spec:   corev1.PodSpec{InitContainers: []corev1.Container{{Name: webhookv1.TexasInitContainerName}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&naisiov1.Jwker{
+			ObjectMeta: metav1.ObjectMeta{Name: naming.GetJwkerName("app"), Namespace: namespace},
+			Spec:       naisiov1.JwkerSpec{SecretName: "app-secret"},
+			Status:     naisiov1.JwkerStatus{SynchronizationState: utilities.JwkerSynchronizationStateReady},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: namespace}},
+		&networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-egress",
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha.GroupVersion.String(),
+					Kind:       "SecurityConfig",
+					Name:       "app",
+					UID:        uid,
+					Controller: utilities.Ptr(true),
+				}},
+			},
+		},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	key := types.NamespacedName{Name: "app", Namespace: namespace}
+
+	t.Run("renders a healthy tree", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), getObjects()...)
+
+		tree, err := Collect(context.Background(), k8sClient, key)
+		require.NoError(t, err)
+		assert.Equal(t, HealthHealthy, tree.OverallHealth())
+
+		var out bytes.Buffer
+		require.NoError(t, tree.Print(&out, false))
+		assert.Equal(t, `✔ SecurityConfig ns/app: Ready: SecurityConfig ready.
+├── ✔ Application app: labeled skiperator/security=enabled
+│   └── ✔ Pod app-abc: Running, Texas injected
+├── ✔ Jwker app: RolloutComplete
+│   └── ✔ Secret app-secret: exists
+└── ✔ NetworkPolicy app-egress: egress allowed
+`, out.String())
+	})
+
+	t.Run("marks missing Texas injection and unsynchronized Jwker", func(t *testing.T) {
+		objects := getObjects()
+		objects[2].(*corev1.Pod).Spec.InitContainers = nil
+		objects[3].(*naisiov1.Jwker).Status.SynchronizationState = ""
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), append(objects[:4], objects[5])...)
+
+		tree, err := Collect(context.Background(), k8sClient, key)
+		require.NoError(t, err)
+		assert.Equal(t, HealthUnhealthy, tree.OverallHealth())
+
+		pod := tree.Children[0].Children[0]
+		assert.Equal(t, HealthUnhealthy, pod.Health)
+		assert.Equal(t, "Running, Texas not injected", pod.Message)
+		jwker := tree.Children[1]
+		assert.Equal(t, HealthPending, jwker.Health)
+		assert.Equal(t, HealthPending, jwker.Children[0].Health)
+	})
+
+	t.Run("only shows the application when TokenX is disabled", func(t *testing.T) {
+		objects := getObjects()
+		objects[0].(*v1alpha.SecurityConfig).Spec.Tokenx = nil
+		objects[2].(*corev1.Pod).Spec.InitContainers = nil
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t), objects...)
+
+		tree, err := Collect(context.Background(), k8sClient, key)
+		require.NoError(t, err)
+		assert.Equal(t, HealthHealthy, tree.OverallHealth())
+		require.Len(t, tree.Children, 1)
+		assert.Equal(t, "Running, TokenX not enabled", tree.Children[0].Children[0].Message)
+	})
+
+	t.Run("returns an error when the SecurityConfig does not exist", func(t *testing.T) {
+		k8sClient := utilities.GetMockKubernetesClient(getScheme(t))
+
+		_, err := Collect(context.Background(), k8sClient, key)
+		assert.Error(t, err)
+	})
+}
+
+func TestPrint_Color(t *testing.T) {
+	tree := &Node{Kind: "SecurityConfig", Name: "ns/app", Health: HealthUnhealthy, Message: "Failed"}
+
+	var out bytes.Buffer
+	require.NoError(t, tree.Print(&out, true))
+	assert.Equal(t, colorRed+"✘"+colorReset+" SecurityConfig ns/app: Failed\n", out.String())
+}
+
+func TestAddAccessGrants(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	securityConfig := &v1alpha.SecurityConfig{Status: v1alpha.SecurityConfigStatus{AccessGrants: []v1alpha.AccessGrantStatus{
+		{Direction: "inbound", Application: "a", Namespace: "team-a", ExpiresAt: metav1.NewTime(now.Add(48 * time.Hour))},
+		{Direction: "outbound", Application: "b", Namespace: "team-b", ExpiresAt: metav1.NewTime(now.Add(90 * time.Minute)), ExpiringSoon: true},
+		{Direction: "inbound", Application: "c", Namespace: "team-c", ExpiresAt: metav1.NewTime(now.Add(-time.Minute))},
+	}}}
+	root := &Node{Kind: "SecurityConfig", Name: "ns/app", Health: HealthHealthy}
+
+	addAccessGrants(root, securityConfig, now)
+
+	assert.Equal(t, []*Node{
+		{Kind: "AccessGrant", Name: "team-a/a", Health: HealthHealthy, Message: "inbound, expires in 48h0m0s"},
+		{Kind: "AccessGrant", Name: "team-b/b", Health: HealthHealthy, Message: "outbound, expires soon in 1h30m0s"},
+		{Kind: "AccessGrant", Name: "team-c/c", Health: HealthPending, Message: "inbound, expired, waiting for the SecurityConfig to be reconciled"},
+	}, root.Children)
+}
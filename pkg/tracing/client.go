@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// NewClient is a client.NewClientFunc creating a client that starts a span around every call, so that slow
+// reconciliations and admissions can be attributed to specific API calls.
+func NewClient(config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.NewWithWatch(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return WrapClient(c), nil
+}
+
+// WrapClient returns a client starting a span around every call to c.
+func WrapClient(c client.WithWatch) client.WithWatch {
+	return interceptor.NewClient(c, interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			ctx, span := startClientSpan(ctx, c, "Get", obj, key.Namespace, key.Name)
+			err := c.Get(ctx, key, obj, opts...)
+			End(span, err)
+			return err
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			listOptions := (&client.ListOptions{}).ApplyOptions(opts)
+			ctx, span := startClientSpan(ctx, c, "List", list, listOptions.Namespace, "")
+			err := c.List(ctx, list, opts...)
+			End(span, err)
+			return err
+		},
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			ctx, span := startClientSpan(ctx, c, "Create", obj, obj.GetNamespace(), obj.GetName())
+			err := c.Create(ctx, obj, opts...)
+			End(span, err)
+			return err
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			ctx, span := startClientSpan(ctx, c, "Update", obj, obj.GetNamespace(), obj.GetName())
+			err := c.Update(ctx, obj, opts...)
+			End(span, err)
+			return err
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			ctx, span := startClientSpan(ctx, c, "Patch", obj, obj.GetNamespace(), obj.GetName())
+			err := c.Patch(ctx, obj, patch, opts...)
+			End(span, err)
+			return err
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			ctx, span := startClientSpan(ctx, c, "Delete", obj, obj.GetNamespace(), obj.GetName())
+			err := c.Delete(ctx, obj, opts...)
+			End(span, err)
+			return err
+		},
+	})
+}
+
+func startClientSpan(
+	ctx context.Context,
+	c client.Client,
+	verb string,
+	obj runtime.Object,
+	namespace, name string,
+) (context.Context, trace.Span) {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	return Start(
+		ctx,
+		fmt.Sprintf("k8s.client.%s %s", verb, kind),
+		attribute.String("k8s.namespace.name", namespace),
+		attribute.String("k8s.object.name", name),
+	)
+}
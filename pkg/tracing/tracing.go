@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/kartverket/accesserator"
+	serviceName = "accesserator"
+
+	ExporterNone = "none"
+	ExporterOtlp = "otlp"
+)
+
+// Setup configures the global tracer provider from the config, and returns a function flushing and stopping it. Spans
+// are not exported with the default none exporter. The otlp exporter is configured with the standard
+// OTEL_EXPORTER_OTLP_* environment variables.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	cfg := config.Get()
+	switch cfg.TracingExporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOtlp:
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q, must be %s or %s", cfg.TracingExporter, ExporterNone, ExporterOtlp)
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("k8s.cluster.name", cfg.ClusterName),
+		)),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tracerProvider.Shutdown, nil
+}
+
+// Start starts a span with the attributes, which is a no-op unless tracing is set up.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SecurityConfigAttributes returns the span attributes identifying the SecurityConfig and its application.
+func SecurityConfigAttributes(securityConfig v1alpha.SecurityConfig) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("accesserator.securityconfig.name", securityConfig.Name),
+		attribute.String("accesserator.application", securityConfig.Spec.ApplicationRef),
+		attribute.String("k8s.namespace.name", securityConfig.Namespace),
+	}
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kartverket/accesserator/api/v1alpha"
+	"github.com/kartverket/accesserator/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup_None(t *testing.T) {
+	t.Setenv("ACCESSERATOR_CLUSTER_NAME", "cluster")
+	t.Setenv("ACCESSERATOR_TOKENX_NAMESPACE", "obo")
+	t.Setenv("ACCESSERATOR_TEXAS_IMAGE_TAG", "latest")
+	require.NoError(t, config.Load())
+
+	shutdown, err := Setup(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	t.Setenv("ACCESSERATOR_TRACING_EXPORTER", "zipkin")
+	require.NoError(t, config.Load())
+	_, err = Setup(context.Background())
+	assert.Error(t, err)
+}
+
+func TestStartAndEnd(t *testing.T) {
+	recorder := setupRecorder(t)
+	securityConfig := v1alpha.SecurityConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "sc", Namespace: "ns"},
+		Spec:       v1alpha.SecurityConfigSpec{ApplicationRef: "app"},
+	}
+
+	_, span := Start(context.Background(), "test", SecurityConfigAttributes(securityConfig)...)
+	End(span, errors.New("failed"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "test", spans[0].Name())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("accesserator.securityconfig.name", "sc"),
+		attribute.String("accesserator.application", "app"),
+		attribute.String("k8s.namespace.name", "ns"),
+	}, spans[0].Attributes())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "failed", spans[0].Status().Description)
+}
+
+func TestWrapClient(t *testing.T) {
+	recorder := setupRecorder(t)
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha.AddToScheme(scheme))
+	c := WrapClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+	ctx, parent := Start(context.Background(), "parent")
+	err := c.Get(ctx, types.NamespacedName{Name: "sc", Namespace: "ns"}, &v1alpha.SecurityConfig{})
+	assert.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "k8s.client.Get SecurityConfig", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("k8s.object.name", "sc"))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -1,6 +1,22 @@
 package utilities
 
 const (
-	JwkerSecretNameSuffix = "jwker-secret"
-	EgressNameSuffix      = "egress"
+	// SkiperatorApplicationRefLabel is the pod label Skiperator sets to the name of the Application of the pod.
+	SkiperatorApplicationRefLabel = "application.skiperator.no/app-name"
+	// SecurityEnabledLabelName and SecurityEnabledLabelValue mark the Applications whose pods are mutated by the pod
+	// webhook.
+	SecurityEnabledLabelName  = "skiperator/security"
+	SecurityEnabledLabelValue = "enabled"
+	// WebhookNamespaceLabelName and WebhookNamespaceLabelValue mark the namespaces the pod webhook is called for.
+	WebhookNamespaceLabelName  = "accesserator-webhooks"
+	WebhookNamespaceLabelValue = "enabled"
+
+	// ManagedByLabelKey and ManagedByLabelValue mark resources created by Accesserator that cannot have an owner
+	// reference, such as resources in another namespace than the SecurityConfig.
+	ManagedByLabelKey   = "app.kubernetes.io/managed-by"
+	ManagedByLabelValue = "accesserator"
+
+	// JwkerSynchronizationStateReady is the synchronization state of a Jwker once its OAuth client is registered
+	// with Tokendings and the secret is created.
+	JwkerSynchronizationStateReady = "RolloutComplete"
 )
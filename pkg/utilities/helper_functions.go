@@ -1,12 +1,13 @@
 package utilities
 
 import (
-	"fmt"
 	"time"
 
+	"github.com/kartverket/accesserator/pkg/fieldindex"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -36,21 +37,22 @@ func LowestNonZeroResult(i, j ctrl.Result) ctrl.Result {
 	}
 }
 
-func GetJwkerName(applicationRef string) string {
-	return applicationRef
-}
-
-func GetJwkerSecretName(jwkerName string) string {
-	return fmt.Sprintf("%s-%s", jwkerName, JwkerSecretNameSuffix)
-}
-
-func GetTokenxEgressName(securityConfigName string, tokenxConfigName string) string {
-	return fmt.Sprintf("%s-%s-%s", securityConfigName, tokenxConfigName, EgressNameSuffix)
+// IsManagedByAccesserator reports whether the object carries the label marking it as created by Accesserator.
+func IsManagedByAccesserator(obj client.Object) bool {
+	return obj.GetLabels()[ManagedByLabelKey] == ManagedByLabelValue
 }
 
+// GetMockKubernetesClient returns a fake client with the objects and the field indexes of the manager, so lookups by
+// field index behave like they do against the cache of the manager.
 func GetMockKubernetesClient(scheme *runtime.Scheme, objects ...client.Object) client.Client {
-	return fake.NewClientBuilder().
+	builder := fake.NewClientBuilder().
 		WithScheme(scheme).
-		WithObjects(objects...).
-		Build()
+		WithObjects(objects...)
+	for _, index := range fieldindex.All() {
+		// Only the types of the scheme can be indexed.
+		if _, err := apiutil.GVKForObject(index.Object, scheme); err == nil {
+			builder = builder.WithIndex(index.Object, index.Field, index.ExtractValue)
+		}
+	}
+	return builder.Build()
 }
@@ -1,7 +1,6 @@
 package utilities
 
 import (
-	"fmt"
 	"testing"
 	"time"
 
@@ -30,24 +29,6 @@ func TestLowestNonZeroResult(t *testing.T) {
 	assert.Equal(t, one, LowestNonZeroResult(two, one))
 }
 
-func TestGetJwkerName(t *testing.T) {
-	appRef := "my-app"
-	assert.Equal(t, appRef, GetJwkerName(appRef))
-}
-
-func TestGetJwkerSecretName(t *testing.T) {
-	jwkerName := "foo"
-	want := fmt.Sprintf("%s-%s", jwkerName, JwkerSecretNameSuffix)
-	assert.Equal(t, want, GetJwkerSecretName(jwkerName))
-}
-
-func TestGetTokenxEgressName(t *testing.T) {
-	secName := "sec"
-	tokenx := "tok"
-	want := fmt.Sprintf("%s-%s-%s", secName, tokenx, EgressNameSuffix)
-	assert.Equal(t, want, GetTokenxEgressName(secName, tokenx))
-}
-
 func TestGetMockKubernetesClient(t *testing.T) {
 	scheme := runtime.NewScheme()
 	obj := &unstructured.Unstructured{}
@@ -57,3 +38,14 @@ func TestGetMockKubernetesClient(t *testing.T) {
 	client := GetMockKubernetesClient(scheme, obj)
 	assert.NotNil(t, client)
 }
+
+func TestIsManagedByAccesserator(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	assert.False(t, IsManagedByAccesserator(obj))
+
+	obj.SetLabels(map[string]string{ManagedByLabelKey: "someone-else"})
+	assert.False(t, IsManagedByAccesserator(obj))
+
+	obj.SetLabels(map[string]string{ManagedByLabelKey: ManagedByLabelValue})
+	assert.True(t, IsManagedByAccesserator(obj))
+}